@@ -0,0 +1,66 @@
+package index
+
+import (
+	"os"
+	"strings"
+
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 查询执行产物列表
+// @Description 按任务ID与exec_id列出该次执行保存的产物（大输出、收集的文件）
+// @Tags 日志管理
+// @Accept json
+// @Produce json
+// @Param id query int true "任务ID"
+// @Param exec_id query string true "执行ID"
+// @Success 200 {object} function.JsonData "查询成功"
+// @Failure 400 {object} function.JsonData "参数错误"
+// @Router /jobs/artifacts [get]
+func (*Index) JobArtifacts(c *gin.Context) {
+	jobID := funcs.GetQueryInt(c, "id", 0)
+	execID := funcs.GetQueryString(c, "exec_id", "")
+	if jobID <= 0 || strings.TrimSpace(execID) == "" {
+		funcs.No(c, "参数错误：id 和 exec_id 必填", nil)
+		return
+	}
+	refs, err := global.ListArtifacts(uint(jobID), execID)
+	if err != nil {
+		funcs.No(c, "查询产物失败："+err.Error(), nil)
+		return
+	}
+	funcs.Ok(c, "查询成功", refs)
+}
+
+// @Summary 下载执行产物
+// @Description 下载某次执行的指定产物文件
+// @Tags 日志管理
+// @Produce octet-stream
+// @Param id query int true "任务ID"
+// @Param exec_id query string true "执行ID"
+// @Param name query string true "产物文件名"
+// @Success 200 {file} file "产物文件"
+// @Failure 400 {object} function.JsonData "参数错误"
+// @Router /jobs/artifacts/download [get]
+func (*Index) DownloadArtifact(c *gin.Context) {
+	jobID := funcs.GetQueryInt(c, "id", 0)
+	execID := funcs.GetQueryString(c, "exec_id", "")
+	name := funcs.GetQueryString(c, "name", "")
+	if jobID <= 0 || strings.TrimSpace(execID) == "" || strings.TrimSpace(name) == "" {
+		funcs.No(c, "参数错误：id、exec_id 和 name 必填", nil)
+		return
+	}
+	path, err := global.ArtifactPath(uint(jobID), execID, name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			funcs.No(c, "产物不存在", nil)
+		} else {
+			funcs.No(c, "获取产物失败："+err.Error(), nil)
+		}
+		return
+	}
+	c.FileAttachment(path, name)
+}
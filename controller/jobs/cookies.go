@@ -0,0 +1,84 @@
+package index
+
+import (
+	"strings"
+
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CookieJarClearRequest 清空Cookie罐结构体
+// 示例：{"jar":"portal"} 或 {"id":3,"name":"SESSIONID"}
+type CookieJarClearRequest struct {
+	Jar  string `form:"jar" json:"jar"`   // 共享罐名称
+	ID   uint   `form:"id" json:"id"`     // 任务ID（任务独立的Cookie罐）
+	Name string `form:"name" json:"name"` // 只删除该名称的Cookie，为空清空整个罐
+}
+
+// cookieJarParam 按 jar 或任务 id 确定罐名
+func cookieJarParam(jar string, id uint) string {
+	if jar = strings.TrimSpace(jar); jar != "" {
+		return jar
+	}
+	if id > 0 {
+		return global.CookieJarForJob(id)
+	}
+	return ""
+}
+
+// @Summary 查看Cookie罐
+// @Description 未指定 jar/id 时列出所有Cookie罐及Cookie数量；指定时返回罐内Cookie（值只显示前4个字符）
+// @Tags 任务管理
+// @Produce json
+// @Param jar query string false "共享罐名称"
+// @Param id query int false "任务ID（任务独立的Cookie罐）"
+// @Success 200 {object} function.JsonData "查询成功"
+// @Router /jobs/cookies [get]
+func (*Index) CookieJars(c *gin.Context) {
+	jar := cookieJarParam(funcs.GetQueryString(c, "jar", ""), uint(funcs.GetQueryInt(c, "id", 0)))
+	if jar == "" {
+		list, err := global.ListCookieJars()
+		if err != nil {
+			funcs.No(c, "查询失败："+err.Error(), nil)
+			return
+		}
+		funcs.Ok(c, "查询成功", list)
+		return
+	}
+	cookies, err := global.GetCookieJar(jar)
+	if err != nil {
+		funcs.No(c, "查询失败："+err.Error(), nil)
+		return
+	}
+	funcs.Ok(c, "查询成功", gin.H{"jar": jar, "cookies": cookies})
+}
+
+// @Summary 清空Cookie罐
+// @Description 清空指定Cookie罐，或只删除其中某个名称的Cookie
+// @Tags 任务管理
+// @Accept json
+// @Produce json
+// @Param data body index.CookieJarClearRequest true "罐名称或任务ID"
+// @Success 200 {object} function.JsonData "操作成功"
+// @Failure 400 {object} function.JsonData "参数错误"
+// @Router /jobs/cookies/clear [post]
+func (*Index) ClearCookieJar(c *gin.Context) {
+	var req CookieJarClearRequest
+	if err := c.ShouldBind(&req); err != nil {
+		funcs.No(c, "参数错误："+err.Error(), nil)
+		return
+	}
+	jar := cookieJarParam(req.Jar, req.ID)
+	if jar == "" {
+		funcs.No(c, "参数错误：jar 或 id 必填", nil)
+		return
+	}
+	n, err := global.ClearCookieJar(jar, strings.TrimSpace(req.Name))
+	if err != nil {
+		funcs.No(c, "清空失败："+err.Error(), nil)
+		return
+	}
+	funcs.Ok(c, "清空成功", gin.H{"jar": jar, "deleted": n})
+}
@@ -0,0 +1,18 @@
+package index
+
+import (
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 查询HTTP任务连接池
+// @Description 返回HTTP任务共享连接池的数量、命中/新建次数、打开的连接数、连接复用与TLS握手次数
+// @Tags 系统管理
+// @Produce json
+// @Success 200 {object} function.JsonData "查询成功"
+// @Router /jobs/http/pool [get]
+func (*Index) HTTPPoolStats(c *gin.Context) {
+	funcs.Ok(c, "查询成功", global.GetHTTPPoolStats())
+}
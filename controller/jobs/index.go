@@ -3,6 +3,7 @@ package index
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -42,12 +43,17 @@ type JobRequest struct {
 	ID          *uint  `form:"id" json:"id,omitempty"`
 	Name        string `form:"name" json:"name"`
 	Desc        string `form:"desc,omitempty" json:"desc,omitempty"`
+	Tags        string `form:"tags,omitempty" json:"tags,omitempty"`
 	CronExpr    string `form:"cron_expr" json:"cron_expr"`
 	Mode        string `form:"mode" json:"mode"`
 	Command     string `form:"command" json:"command"`
+	Config      string `form:"config,omitempty" json:"config,omitempty"` // 结构化执行配置（JSON字符串），优先于 command
 	State       int    `form:"state,omitempty" json:"state,omitempty"`
 	AllowMode   int    `form:"allow_mode,omitempty" json:"allow_mode,omitempty"`
 	MaxRunCount int    `form:"max_run_count,omitempty" json:"max_run_count,omitempty"`
+	Notify      string `form:"notify,omitempty" json:"notify,omitempty"`
+	Breaker     string `form:"breaker,omitempty" json:"breaker,omitempty"`
+	PingToken   string `form:"ping_token,omitempty" json:"ping_token,omitempty"`
 	Page        int    `form:"page" json:"page"`
 	Size        int    `form:"size" json:"size"`
 }
@@ -59,12 +65,17 @@ type JobEditRequest struct {
 	ID          uint    `form:"id" json:"id" binding:"required"`
 	Name        *string `form:"name" json:"name"`
 	Desc        *string `form:"desc" json:"desc"`
+	Tags        *string `form:"tags" json:"tags"`
 	CronExpr    *string `form:"cron_expr" json:"cron_expr"`
 	Mode        *string `form:"mode" json:"mode"`
 	Command     *string `form:"command" json:"command"`
+	Config      *string `form:"config" json:"config"`
 	State       *int    `form:"state" json:"state"`
 	AllowMode   *int    `form:"allow_mode" json:"allow_mode"`
 	MaxRunCount *uint   `form:"max_run_count" json:"max_run_count"`
+	Notify      *string `form:"notify" json:"notify"`
+	Breaker     *string `form:"breaker" json:"breaker"`
+	PingToken   *string `form:"ping_token" json:"ping_token"`
 }
 
 // JobRunRequest 任务运行结构体
@@ -154,14 +165,24 @@ func (*Index) AddJob(c *gin.Context) {
 	job := jobs.Jobs{
 		Name:        jobReq.Name,
 		Desc:        jobReq.Desc,
+		Tags:        global.JoinJobTags([]string{jobReq.Tags}),
 		CronExpr:    jobReq.CronExpr,
 		Mode:        jobReq.Mode,
 		Command:     jobReq.Command,
+		Config:      jobReq.Config,
 		State:       jobReq.State,
 		MaxRunCount: uint(jobReq.MaxRunCount),
 		AllowMode:   jobReq.AllowMode,
+		Notify:      jobReq.Notify,
+		Breaker:     jobReq.Breaker,
+		PingToken:   jobReq.PingToken,
 	}
 	if err := global.CreateJob(&job); err != nil {
+		var cfgErr *global.JobConfigError
+		if errors.As(err, &cfgErr) {
+			funcs.No(c, err.Error(), cfgErr.Report)
+			return
+		}
 		global.ZapLog.Error("任务添加失败1",
 			global.LogField("name", job.Name),
 			global.LogError(err))
@@ -193,17 +214,13 @@ func (*Index) DeleteJob(c *gin.Context) {
 		funcs.No(c, "任务未找到："+err.Error(), nil)
 		return
 	}
-	if err := global.DB.Delete(&job).Error; err != nil {
-		funcs.No(c, "任务删除失败："+err.Error(), nil)
+	if job.ManagedBy != "" {
+		funcs.No(c, "任务由定义文件 "+job.ManagedBy+" 管理，请删除定义后同步", nil)
 		return
 	}
-	if job.State == 1 || job.State == 0 {
-		if err := global.RemoveJob(job.ID); err != nil {
-			// 记录错误但不影响删除操作的成功
-			if global.ZapLog != nil {
-				global.ZapLog.Error("从调度器移除任务失败", global.LogError(err))
-			}
-		}
+	if err := global.DeleteJob(&job); err != nil {
+		funcs.No(c, "任务删除失败："+err.Error(), nil)
+		return
 	}
 	funcs.Ok(c, "任务删除成功", nil)
 }
@@ -228,8 +245,13 @@ func (*Index) StopJob(c *gin.Context) {
 		funcs.No(c, "任务未找到："+err.Error(), nil)
 		return
 	}
-	if job.State == 1 || job.State == 0 {
+	if job.ManagedBy != "" {
+		funcs.No(c, "任务由定义文件 "+job.ManagedBy+" 管理，请在定义文件中设置 enabled: false 后同步", nil)
+		return
+	}
+	if job.State == 1 || job.State == 0 || job.State == global.JobStateSuspended {
 		job.State = 2
+		global.ClearSuspension(&job)
 		if err := global.DB.Save(&job).Error; err != nil {
 			funcs.No(c, "任务停止失败："+err.Error(), nil)
 			return
@@ -265,6 +287,7 @@ func (*Index) JobInfo(c *gin.Context) {
 		funcs.No(c, "任务未找到："+err.Error(), nil)
 		return
 	}
+	job.Running = global.RunningCount(job.ID)
 	funcs.Ok(c, "任务信息", job)
 }
 
@@ -276,8 +299,8 @@ func (*Index) JobInfo(c *gin.Context) {
 // @Param page query int false "页码" default(1)
 // @Param size query int false "每页数量" default(10)
 // @Param name query string false "任务名称"
-// @Param state query int false "任务状态: 0等待 1运行中 2已停止"
-// @Param mode query string false "执行模式: http command func"
+// @Param state query int false "任务状态: 0启用 2已停止 3熔断暂停（运行中的实例见 running 字段）"
+// @Param mode query string false "执行模式: http command script ssh func workflow heartbeat"
 // @Success 200 {object} function.PageData "分页数据"
 // @Failure 400 {object} function.JsonData "参数错误"
 // @Router /jobs/list [get]
@@ -305,7 +328,7 @@ func (*Index) JobList(c *gin.Context) {
 	}
 
 	// 按任务状态筛选
-	if jobReq.State >= 0 && jobReq.State <= 2 {
+	if jobReq.State >= 0 && jobReq.State <= global.JobStateSuspended {
 		query = query.Where("state = ?", jobReq.State)
 	}
 
@@ -327,6 +350,9 @@ func (*Index) JobList(c *gin.Context) {
 		return
 	}
 	totalPages := (total + int64(jobReq.Size) - 1) / int64(jobReq.Size)
+	for i := range jobList {
+		jobList[i].Running = global.RunningCount(jobList[i].ID)
+	}
 	funcs.JsonPage(c, "查询任务列表成功", jobList, total, totalPages, jobReq.Page, jobReq.Size)
 }
 
@@ -349,13 +375,20 @@ func (*Index) EditJob(c *gin.Context) {
 		funcs.No(c, "任务未找到："+err.Error(), nil)
 		return
 	}
-	needRestart := jobReq.CronExpr != nil || jobReq.Mode != nil || jobReq.Command != nil || jobReq.State != nil || jobReq.AllowMode != nil || jobReq.MaxRunCount != nil
+	if oldJob.ManagedBy != "" {
+		funcs.No(c, "任务由定义文件 "+oldJob.ManagedBy+" 管理，请修改定义文件后同步", nil)
+		return
+	}
+	needRestart := jobReq.CronExpr != nil || jobReq.Mode != nil || jobReq.Command != nil || jobReq.Config != nil || jobReq.State != nil || jobReq.AllowMode != nil || jobReq.MaxRunCount != nil || jobReq.Notify != nil || jobReq.Breaker != nil
 	if jobReq.Name != nil {
 		oldJob.Name = *jobReq.Name
 	}
 	if jobReq.Desc != nil {
 		oldJob.Desc = *jobReq.Desc
 	}
+	if jobReq.Tags != nil {
+		oldJob.Tags = global.JoinJobTags([]string{*jobReq.Tags})
+	}
 	if jobReq.CronExpr != nil {
 		oldJob.CronExpr = *jobReq.CronExpr
 	}
@@ -365,8 +398,17 @@ func (*Index) EditJob(c *gin.Context) {
 	if jobReq.Command != nil {
 		oldJob.Command = *jobReq.Command
 	}
+	// 只修改了 command 或 mode 时，结构化配置按新的【】格式重新生成
+	if jobReq.Config != nil {
+		oldJob.Config = *jobReq.Config
+	} else if jobReq.Command != nil || jobReq.Mode != nil {
+		oldJob.Config = ""
+	}
 	if jobReq.State != nil {
 		oldJob.State = *jobReq.State
+		if oldJob.State != global.JobStateSuspended {
+			global.ClearSuspension(&oldJob)
+		}
 	}
 	if jobReq.AllowMode != nil {
 		oldJob.AllowMode = *jobReq.AllowMode
@@ -374,6 +416,40 @@ func (*Index) EditJob(c *gin.Context) {
 	if jobReq.MaxRunCount != nil {
 		oldJob.MaxRunCount = *jobReq.MaxRunCount
 	}
+	if jobReq.Notify != nil {
+		if err := global.ValidateNotifyRules(*jobReq.Notify); err != nil {
+			funcs.No(c, "通知规则验证失败："+err.Error(), nil)
+			return
+		}
+		oldJob.Notify = *jobReq.Notify
+	}
+	if jobReq.Breaker != nil {
+		if err := global.ValidateBreakerConfig(*jobReq.Breaker); err != nil {
+			funcs.No(c, "熔断配置验证失败："+err.Error(), nil)
+			return
+		}
+		oldJob.Breaker = *jobReq.Breaker
+		global.ResetBreaker(oldJob.ID)
+	}
+	if jobReq.PingToken != nil {
+		oldJob.PingToken = *jobReq.PingToken
+	}
+	global.EnsurePingToken(&oldJob)
+	if needRestart {
+		if err := global.ValidateJob(&oldJob); err != nil {
+			var cfgErr *global.JobConfigError
+			if errors.As(err, &cfgErr) {
+				funcs.No(c, err.Error(), cfgErr.Report)
+			} else {
+				funcs.No(c, err.Error(), nil)
+			}
+			return
+		}
+		if err := global.PrepareJobConfig(&oldJob); err != nil {
+			funcs.No(c, "执行配置转换失败："+err.Error(), nil)
+			return
+		}
+	}
 	if err := global.DB.Save(&oldJob).Error; err != nil {
 		funcs.No(c, "任务更新失败："+err.Error(), nil)
 		return
@@ -406,13 +482,19 @@ func (*Index) JobRun(c *gin.Context) {
 		funcs.No(c, "任务未找到", nil)
 		return
 	}
-	// 根据配置决定是否允许手动并发
-	if global.GetJobsConfigBool("jobs.manual_allow_concurrent", true) {
-		execID := global.RunJobManually(&job)
-		funcs.Ok(c, "任务已手动执行", gin.H{"exec_id": execID, "skipped": false})
+	if job.Mode == "heartbeat" {
+		funcs.No(c, "心跳监控任务无需手动执行，请通过 /ping/"+job.PingToken+" 上报", nil)
+		return
+	}
+	if job.ManagedBy != "" && job.State == 2 {
+		funcs.No(c, "任务由定义文件 "+job.ManagedBy+" 管理且已停止，请在定义文件中启用后同步", nil)
 		return
 	}
-	// 不允许并发时，按 AllowMode 执行策略
+	if global.IsDraining() {
+		funcs.No(c, "服务正在停止，不接受新的执行", nil)
+		return
+	}
+	// 按 jobs.manual_allow_concurrent 与 AllowMode 执行；返回前已完成执行登记
 	execID, skipped, reason := global.RunJobManuallyWithPolicy(&job)
 	if skipped {
 		funcs.Ok(c, "任务已按策略跳过", gin.H{"skipped": true, "reason": reason})
@@ -432,9 +514,9 @@ func (i *Index) JobRunAll(c *gin.Context) {
 
 	global.Timer.Start()
 	global.TimerRunning = true
-	// 查询所有状态为0或1的任务
+	// 查询所有需要调度的任务（等待、执行中、熔断暂停）
 	var dbJobs []jobs.Jobs
-	if err := global.DB.Where("state IN (?)", []int{0, 1}).Find(&dbJobs).Error; err != nil {
+	if err := global.DB.Where("state IN (?)", global.SchedulableJobStates).Find(&dbJobs).Error; err != nil {
 		funcs.No(c, "查询任务失败: "+err.Error(), nil)
 		return
 	}
@@ -532,6 +614,11 @@ func (*Index) JobRestart(c *gin.Context) {
 		funcs.No(c, "任务未找到："+err.Error(), nil)
 		return
 	}
+	// 托管任务的启停由定义文件决定；熔断暂停与等待中的任务可以重启（同步不会回写）
+	if job.ManagedBy != "" && job.State == 2 {
+		funcs.No(c, "任务由定义文件 "+job.ManagedBy+" 管理且已停止，请在定义文件中启用后同步", nil)
+		return
+	}
 
 	// 先停止任务（从调度器中移除）
 	if err := global.RemoveJob(job.ID); err != nil {
@@ -541,6 +628,7 @@ func (*Index) JobRestart(c *gin.Context) {
 
 	// 无论任务之前是什么状态，都将状态设置为等待（0）并重新添加到调度器
 	job.State = 0 // 将状态改为等待
+	global.ClearSuspension(&job)
 	if err := global.DB.Save(&job).Error; err != nil {
 		funcs.No(c, "更新任务状态失败："+err.Error(), nil)
 		return
@@ -711,9 +799,9 @@ func (*Index) LogSwitchState(c *gin.Context) {
 // @Router /jobs/checkJob [post]
 func (i *Index) CalibrateJobList(c *gin.Context) {
 
-	// 查询所有状态为0或1的任务
+	// 查询所有需要调度的任务（等待、执行中、熔断暂停）
 	var dbJobs []jobs.Jobs
-	if err := global.DB.Where("state IN (?)", []int{0, 1}).Find(&dbJobs).Error; err != nil {
+	if err := global.DB.Where("state IN (?)", global.SchedulableJobStates).Find(&dbJobs).Error; err != nil {
 		funcs.No(c, "查询任务失败: "+err.Error(), nil)
 		return
 	}
@@ -1217,12 +1305,32 @@ func (*Index) GetJobsConfig(c *gin.Context) {
 		return
 	}
 	data := gin.H{
-		"default_allow_mode":      cfg.Jobs.DefaultAllowMode,
-		"manual_allow_concurrent": cfg.Jobs.ManualAllowConcurrent,
-		"default_timeout_seconds": cfg.Jobs.DefaultTimeoutSeconds,
-		"http_response_max_bytes": cfg.Jobs.HTTPResponseMaxBytes,
-		"log_summary_enabled":     cfg.Jobs.LogSummaryEnabled,
-		"log_line_truncate":       cfg.Jobs.LogLineTruncate,
+		"default_allow_mode":          cfg.Jobs.DefaultAllowMode,
+		"manual_allow_concurrent":     cfg.Jobs.ManualAllowConcurrent,
+		"default_timeout_seconds":     cfg.Jobs.DefaultTimeoutSeconds,
+		"http_response_max_bytes":     cfg.Jobs.HTTPResponseMaxBytes,
+		"log_summary_enabled":         cfg.Jobs.LogSummaryEnabled,
+		"log_line_truncate":           cfg.Jobs.LogLineTruncate,
+		"artifact_dir":                cfg.Jobs.ArtifactDir,
+		"artifact_threshold_bytes":    cfg.Jobs.ArtifactThresholdBytes,
+		"artifact_max_file_bytes":     cfg.Jobs.ArtifactMaxFileBytes,
+		"artifact_keep_days":          cfg.Jobs.ArtifactKeepDays,
+		"heartbeat_grace_seconds":     cfg.Jobs.HeartbeatGraceSeconds,
+		"exec_record_keep_days":       cfg.Jobs.ExecRecordKeepDays,
+		"drain_timeout_seconds":       cfg.Jobs.DrainTimeoutSeconds,
+		"drain_kill_grace_seconds":    cfg.Jobs.DrainKillGraceSeconds,
+		"drain_requeue":               cfg.Jobs.DrainRequeue,
+		"definitions_dir":             cfg.Jobs.DefinitionsDir,
+		"import_timeout_seconds":      cfg.Jobs.ImportTimeoutSeconds,
+		"http_body_max_bytes":         cfg.Jobs.HTTPBodyMaxBytes,
+		"http_transport_idle_seconds": cfg.Jobs.HTTPTransportIdleSecs,
+		"http_proxy":                  global.RedactProxy(cfg.Jobs.HTTPProxy),
+		"http_no_proxy":               cfg.Jobs.HTTPNoProxy,
+		"http_max_retry_wait_seconds": cfg.Jobs.HTTPMaxRetryWaitSecs,
+		"http_host_rate_limit":        cfg.Jobs.HTTPHostRateLimit,
+		"http_host_rate_limits":       cfg.Jobs.HTTPHostRateLimits,
+		"ssh_known_hosts":             cfg.Jobs.SSHKnownHosts,
+		"ssh_concurrency":             cfg.Jobs.SSHConcurrency,
 	}
 	funcs.Ok(c, "获取配置成功", data)
 }
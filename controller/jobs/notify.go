@@ -0,0 +1,74 @@
+package index
+
+import (
+	"strings"
+	"time"
+
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NotifyTestRequest 通知测试结构体
+// 示例：{"channel":"ops","event":"failure"}
+type NotifyTestRequest struct {
+	Channel string `form:"channel" json:"channel" binding:"required"`
+	Event   string `form:"event" json:"event"`
+}
+
+// @Summary 查询通知渠道
+// @Description 列出配置文件中的通知渠道（仅返回名称、类型与目标，不含密钥）
+// @Tags 系统管理
+// @Produce json
+// @Success 200 {object} function.JsonData "查询成功"
+// @Router /jobs/notify/channels [get]
+func (*Index) NotifyChannels(c *gin.Context) {
+	list := make([]gin.H, 0)
+	for name, ch := range global.ListNotifyChannels() {
+		target := ch.URL
+		if target == "" {
+			target = ch.Host
+		}
+		// 去掉URL中的 access_token 等查询参数，避免泄露
+		if i := strings.Index(target, "?"); i >= 0 {
+			target = target[:i]
+		}
+		list = append(list, gin.H{"name": name, "type": ch.Type, "target": target})
+	}
+	funcs.Ok(c, "查询成功", list)
+}
+
+// @Summary 发送测试通知
+// @Description 向指定渠道发送一条测试通知，用于验证渠道配置
+// @Tags 系统管理
+// @Accept json
+// @Produce json
+// @Param data body index.NotifyTestRequest true "渠道名与事件" 例：{"channel":"ops","event":"failure"}
+// @Success 200 {object} function.JsonData "发送成功"
+// @Failure 400 {object} function.JsonData "发送失败"
+// @Router /jobs/notify/test [post]
+func (*Index) NotifyTest(c *gin.Context) {
+	var req NotifyTestRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if req.Event == "" {
+		req.Event = global.NotifyOnFailure
+	}
+	ev := &global.NotifyEvent{
+		Event:   req.Event,
+		JobName: "通知测试",
+		Mode:    "test",
+		ExecID:  "test",
+		Source:  "manual",
+		Status:  "测试",
+		Time:    time.Now().Format("2006-01-02 15:04:05.000"),
+		Output:  "这是一条测试通知",
+	}
+	if err := global.SendNotify(req.Channel, ev); err != nil {
+		funcs.No(c, "发送失败："+err.Error(), nil)
+		return
+	}
+	funcs.Ok(c, "发送成功", nil)
+}
@@ -0,0 +1,48 @@
+package index
+
+import (
+	"strings"
+	"time"
+
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 查询任务输出时间序列
+// @Description 按执行时间正序返回HTTP任务【extract】提取的输出；指定 name 时返回该输出的原始值与数值
+// @Tags 日志管理
+// @Produce json
+// @Param id query int true "任务ID"
+// @Param name query string false "输出名称"
+// @Param since query string false "起始时间，如 2025-06-25 00:00:00 或 RFC3339"
+// @Param limit query int false "最多返回的执行数，默认500"
+// @Success 200 {object} function.JsonData "查询成功"
+// @Failure 400 {object} function.JsonData "参数错误"
+// @Router /jobs/outputs [get]
+func (*Index) JobOutputs(c *gin.Context) {
+	jobID := funcs.GetQueryInt(c, "id", 0)
+	if jobID <= 0 {
+		funcs.No(c, "参数错误：id 必填", nil)
+		return
+	}
+	var since time.Time
+	if s := strings.TrimSpace(funcs.GetQueryString(c, "since", "")); s != "" {
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+		if err != nil {
+			if t, err = time.Parse(time.RFC3339, s); err != nil {
+				funcs.No(c, "参数错误：since 格式应为 2006-01-02 15:04:05 或 RFC3339", nil)
+				return
+			}
+		}
+		since = t
+	}
+	name := strings.TrimSpace(funcs.GetQueryString(c, "name", ""))
+	points, err := global.JobOutputSeries(uint(jobID), name, since, funcs.GetQueryInt(c, "limit", 500))
+	if err != nil {
+		funcs.No(c, "查询失败："+err.Error(), nil)
+		return
+	}
+	funcs.Ok(c, "查询成功", gin.H{"job_id": jobID, "name": name, "points": points})
+}
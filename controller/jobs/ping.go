@@ -0,0 +1,31 @@
+package index
+
+import (
+	"io"
+
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 心跳上报
+// @Description 外部任务向心跳监控任务上报状态，kind 可选 start/success/fail，省略时为 success；请求体会作为输出记录
+// @Tags 任务管理
+// @Accept plain
+// @Produce json
+// @Param token path string true "心跳token"
+// @Param kind path string false "上报类型: start success fail"
+// @Success 200 {object} function.JsonData "上报成功"
+// @Failure 400 {object} function.JsonData "token无效"
+// @Router /ping/{token}/{kind} [post]
+func (*Index) Ping(c *gin.Context) {
+	maxBytes := int64(global.GetJobsConfigInt("jobs.log_line_truncate", 1000))
+	body, _ := io.ReadAll(io.LimitReader(c.Request.Body, maxBytes))
+	execID, err := global.RecordPing(c.Param("token"), c.Param("kind"), string(body), c.ClientIP())
+	if err != nil {
+		funcs.No(c, "上报失败："+err.Error(), nil)
+		return
+	}
+	funcs.Ok(c, "上报成功", gin.H{"exec_id": execID})
+}
@@ -0,0 +1,23 @@
+package index
+
+import (
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary 查询正在执行的任务
+// @Description 列出当前正在执行的任务实例（exec_id、任务、来源、开始时间、进程ID、尝试次数）
+// @Tags 任务管理
+// @Produce json
+// @Param id query int false "任务ID，不传则返回全部"
+// @Success 200 {object} function.JsonData "查询成功"
+// @Router /jobs/running [get]
+func (*Index) JobRunning(c *gin.Context) {
+	jobID := funcs.GetQueryInt(c, "id", 0)
+	if jobID < 0 {
+		jobID = 0
+	}
+	funcs.Ok(c, "查询成功", global.ListRunningExecs(uint(jobID)))
+}
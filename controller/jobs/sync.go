@@ -0,0 +1,39 @@
+package index
+
+import (
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobSyncRequest 任务定义同步结构体
+// 示例：{"dry_run":true}
+type JobSyncRequest struct {
+	DryRun bool `form:"dry_run" json:"dry_run"`
+}
+
+// @Summary 同步任务定义文件
+// @Description 从 jobs.definitions_dir 读取YAML任务定义并与数据库对账；dry_run=true 时只返回差异
+// @Tags 任务管理
+// @Accept json
+// @Produce json
+// @Param data body index.JobSyncRequest false "是否仅预览" 例：{"dry_run":true}
+// @Success 200 {object} function.JsonData "同步结果"
+// @Failure 400 {object} function.JsonData "同步失败"
+// @Router /jobs/sync [post]
+func (*Index) JobSync(c *gin.Context) {
+	var req JobSyncRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	result, err := global.SyncJobDefinitions(req.DryRun)
+	if err != nil {
+		funcs.No(c, "同步失败："+err.Error(), nil)
+		return
+	}
+	if !req.DryRun {
+		global.ZapLog.Info("手动触发任务定义同步", global.LogField("changes", len(result.Changes)))
+	}
+	funcs.Ok(c, "同步完成", result)
+}
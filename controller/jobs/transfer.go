@@ -0,0 +1,168 @@
+package index
+
+import (
+	"io"
+	"strconv"
+	"strings"
+
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// JobExportRequest 任务导出参数
+type JobExportRequest struct {
+	IDs    string `form:"ids" json:"ids"`       // 任务ID，逗号分隔
+	Tags   string `form:"tags" json:"tags"`     // 标签，逗号分隔，匹配任一
+	Mode   string `form:"mode" json:"mode"`     // 执行模式
+	Format string `form:"format" json:"format"` // json（默认）/yaml
+}
+
+// JobImportRequest 任务导入参数（任务数据放在请求体中，支持JSON或YAML）
+type JobImportRequest struct {
+	Strategy string `form:"strategy" json:"strategy"` // skip（默认）/overwrite/rename
+	DryRun   bool   `form:"dry_run" json:"dry_run"`
+}
+
+// @Summary 导出任务
+// @Description 按ID、标签、执行模式筛选导出任务定义，format=yaml 时下载 jobs.yaml
+// @Tags 任务管理
+// @Produce json
+// @Param ids query string false "任务ID，逗号分隔"
+// @Param tags query string false "标签，逗号分隔"
+// @Param mode query string false "执行模式"
+// @Param format query string false "json/yaml"
+// @Success 200 {object} function.JsonData "导出成功"
+// @Failure 400 {object} function.JsonData "导出失败"
+// @Router /jobs/export [get]
+func (*Index) JobExport(c *gin.Context) {
+	var req JobExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		funcs.No(c, "参数错误："+err.Error(), nil)
+		return
+	}
+	var ids []uint
+	for _, s := range strings.Split(req.IDs, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || id == 0 {
+			funcs.No(c, "任务ID格式错误："+s, nil)
+			return
+		}
+		ids = append(ids, uint(id))
+	}
+	specs, err := global.ExportJobs(ids, global.SplitJobTags(req.Tags), strings.TrimSpace(req.Mode))
+	if err != nil {
+		funcs.No(c, "导出失败："+err.Error(), nil)
+		return
+	}
+	switch strings.ToLower(req.Format) {
+	case "", "json":
+		funcs.Ok(c, "导出成功", gin.H{"jobs": specs, "total": len(specs)})
+	case "yaml", "yml":
+		data, err := yaml.Marshal(map[string]interface{}{"jobs": specs})
+		if err != nil {
+			funcs.No(c, "导出失败："+err.Error(), nil)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="jobs.yaml"`)
+		c.Data(200, "application/x-yaml; charset=utf-8", data)
+	default:
+		funcs.No(c, "不支持的导出格式："+req.Format, nil)
+	}
+}
+
+// @Summary 导入任务
+// @Description 请求体为JSON（{"jobs":[...]} 或数组）或YAML；先整体校验，全部通过后在单个事务中写入
+// @Tags 任务管理
+// @Accept json
+// @Produce json
+// @Param strategy query string false "同名冲突策略：skip/overwrite/rename"
+// @Param dry_run query bool false "仅预览"
+// @Success 200 {object} function.JsonData "导入结果"
+// @Failure 400 {object} function.JsonData "导入失败"
+// @Router /jobs/import [post]
+func (*Index) JobImport(c *gin.Context) {
+	var req JobImportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		funcs.No(c, "参数错误："+err.Error(), nil)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 10<<20))
+	if err != nil {
+		funcs.No(c, "读取请求体失败："+err.Error(), nil)
+		return
+	}
+	// JSON 是 YAML 的子集，统一按 YAML 解析
+	specs, err := global.ParseJobSpecsYAML(body)
+	if err != nil {
+		funcs.No(c, "导入数据不是合法的JSON/YAML："+err.Error(), nil)
+		return
+	}
+	if len(specs) == 0 {
+		funcs.No(c, "导入数据为空", nil)
+		return
+	}
+	if global.IsDraining() && !req.DryRun {
+		funcs.No(c, "服务正在停止，暂不接受导入", nil)
+		return
+	}
+	result, err := global.ImportJobs(specs, strings.ToLower(strings.TrimSpace(req.Strategy)), req.DryRun)
+	if err != nil {
+		funcs.No(c, err.Error(), result)
+		return
+	}
+	if !req.DryRun {
+		global.ZapLog.Info("批量导入任务",
+			global.LogField("strategy", result.Strategy),
+			global.LogField("created", result.Created),
+			global.LogField("updated", result.Updated),
+			global.LogField("skipped", result.Skipped))
+	}
+	funcs.Ok(c, "导入完成", result)
+}
+
+// JobImportLegacyRequest 遗留调度导入参数
+// 示例：{"files":[{"name":"/etc/crontab","content":"0 2 * * * root /opt/backup.sh"}],"strategy":"rename","dry_run":true}
+type JobImportLegacyRequest struct {
+	Files    []global.LegacySource `json:"files" binding:"required,min=1"`
+	Strategy string                `json:"strategy"` // skip（默认）/overwrite/rename
+	DryRun   bool                  `json:"dry_run"`
+	Timeout  int                   `json:"timeout" binding:"min=0"` // 导入任务的执行超时（秒），0使用 jobs.import_timeout_seconds
+}
+
+// @Summary 导入 crontab / systemd timer
+// @Description 将 crontab（含 /etc/crontab、cron.d）与 systemd timer+service 转换为 command 任务，无法转换的条目在 issues 中列出
+// @Tags 任务管理
+// @Accept json
+// @Produce json
+// @Param data body index.JobImportLegacyRequest true "待导入文件"
+// @Success 200 {object} function.JsonData "导入结果"
+// @Failure 400 {object} function.JsonData "导入失败"
+// @Router /jobs/import/legacy [post]
+func (*Index) JobImportLegacy(c *gin.Context) {
+	var req JobImportLegacyRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if global.IsDraining() && !req.DryRun {
+		funcs.No(c, "服务正在停止，暂不接受导入", nil)
+		return
+	}
+	result, err := global.ImportLegacySchedules(req.Files, strings.ToLower(strings.TrimSpace(req.Strategy)), req.DryRun, req.Timeout)
+	if err != nil {
+		funcs.No(c, err.Error(), result)
+		return
+	}
+	if !req.DryRun {
+		global.ZapLog.Info("导入遗留调度",
+			global.LogField("entries", len(result.Entries)),
+			global.LogField("issues", len(result.Issues)),
+			global.LogField("created", result.Import.Created))
+	}
+	funcs.Ok(c, "导入完成", result)
+}
@@ -0,0 +1,50 @@
+package index
+
+import (
+	funcs "xiaohuAdmin/function"
+	"xiaohuAdmin/global"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobValidateRequest 任务配置校验参数
+// 示例：{"mode":"http","command":"【url】https://example.com\n【timout】10","cron_expr":"0 */5 * * * *"}
+type JobValidateRequest struct {
+	Mode     string `json:"mode" binding:"required"`
+	Command  string `json:"command"`
+	Config   string `json:"config"`    // 结构化执行配置，非空时校验该配置而不是 command
+	CronExpr string `json:"cron_expr"` // 可选
+}
+
+// @Summary 校验任务配置
+// @Description 保存前校验执行配置，返回带行号的错误与警告（valid=false 表示存在错误，保存会被拒绝）
+// @Tags 任务管理
+// @Accept json
+// @Produce json
+// @Param data body index.JobValidateRequest true "任务配置"
+// @Success 200 {object} function.JsonData "校验结果"
+// @Failure 400 {object} function.JsonData "参数错误"
+// @Router /jobs/validate [post]
+func (*Index) JobValidate(c *gin.Context) {
+	var req JobValidateRequest
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	var report *global.JobConfigReport
+	if req.Config != "" {
+		report = global.ValidateJobConfigJSON(req.Mode, req.Config)
+	} else {
+		report = global.ValidateJobConfig(req.Mode, req.Command)
+	}
+	if req.CronExpr != "" {
+		if err := global.CronExprCheck(req.CronExpr); err != nil {
+			report.Errors = append([]global.JobConfigIssue{{Message: "cron表达式验证失败: " + err.Error()}}, report.Errors...)
+			report.Valid = false
+		}
+	}
+	if !report.Valid {
+		funcs.Ok(c, "校验未通过", report)
+		return
+	}
+	funcs.Ok(c, "校验通过", report)
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"xiaohuAdmin/global"
+)
+
+// RunImportLegacy 命令行导入 crontab / systemd timer
+// 用法：import-cron [-strategy skip|overwrite|rename] [-dry-run] [-format crontab|system|systemd] [-timeout 秒] 文件或目录...
+// 直接写入数据库；服务运行中时需调用 /jobs/checkJob 校准或重启服务后生效
+func RunImportLegacy(args []string) int {
+	fs := flag.NewFlagSet("import-cron", flag.ContinueOnError)
+	strategy := fs.String("strategy", global.ImportSkip, "同名冲突策略：skip/overwrite/rename")
+	dryRun := fs.Bool("dry-run", false, "只解析并校验，不写入数据库")
+	format := fs.String("format", "", "文件格式：crontab/system/systemd，默认按文件名识别")
+	timeout := fs.Int("timeout", 0, "导入任务的执行超时（秒），默认使用 jobs.import_timeout_seconds")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fmt.Println("用法: import-cron [-strategy skip|overwrite|rename] [-dry-run] [-format crontab|system|systemd] [-timeout 秒] 文件或目录...")
+		return 2
+	}
+
+	sources, err := readLegacySources(fs.Args(), *format)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if err := global.InitConfig(); err != nil {
+		fmt.Printf("配置初始化失败: %v\n", err)
+		return 1
+	}
+	global.InitLogger()
+	if err := global.InitDB(); err != nil {
+		fmt.Printf("数据库初始化失败: %v\n", err)
+		return 1
+	}
+
+	result, err := global.ImportLegacySchedules(sources, *strategy, *dryRun, *timeout)
+	for _, e := range result.Entries {
+		fmt.Printf("[转换] %s  %s  =>  %s\n", e.Name, e.Schedule, e.CronExpr)
+	}
+	for _, is := range result.Issues {
+		loc := is.Source
+		if is.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", is.Source, is.Line)
+		}
+		fmt.Printf("[%s] %s  %s  %s\n", is.Level, loc, is.Entry, is.Reason)
+	}
+	if result.Import != nil {
+		for _, item := range result.Import.Items {
+			if item.Error != "" {
+				fmt.Printf("[失败] %s: %s\n", item.Name, item.Error)
+			}
+		}
+		fmt.Printf("共 %d 条，新建 %d，覆盖 %d，跳过 %d，失败 %d\n",
+			result.Import.Total, result.Import.Created, result.Import.Updated, result.Import.Skipped, result.Import.Failed)
+	}
+	if err != nil {
+		fmt.Printf("导入失败: %v\n", err)
+		return 1
+	}
+	if *dryRun {
+		fmt.Println("预览模式，未写入数据库")
+	} else if IsRunning() {
+		fmt.Println("服务正在运行，请调用 /jobs/checkJob 校准调度器或重启服务使新任务生效")
+	}
+	return 0
+}
+
+// readLegacySources 读取文件；目录下读取所有普通文件（systemd 目录只取 .timer/.service）
+func readLegacySources(paths []string, format string) ([]global.LegacySource, error) {
+	var sources []global.LegacySource
+	add := func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取 %s 失败: %v", path, err)
+		}
+		sources = append(sources, global.LegacySource{Name: path, Content: string(data), Format: format})
+		return nil
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %v", path, err)
+		}
+		if !info.IsDir() {
+			if err := add(path); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取目录 %s 失败: %v", path, err)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			name := entry.Name()
+			ext := filepath.Ext(name)
+			if format == global.LegacyFormatSystemd && ext != ".timer" && ext != ".service" {
+				continue
+			}
+			// 跳过 cron.d 中的说明与备份文件
+			if name[0] == '.' || ext == ".dpkg-old" || ext == ".dpkg-dist" || name[len(name)-1] == '~' {
+				continue
+			}
+			if err := add(filepath.Join(path, name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sources, nil
+}
@@ -103,6 +103,8 @@ func (p *program) StartServer() error {
 		promhttp.Handler().ServeHTTP(c.Writer, c.Request)
 	})
 	global.InitJobs()
+	global.StartArtifactJanitor()
+	global.StartJobDefinitionSync()
 	config := global.GetGlobalConfig()
 	port := config.Server.Port
 	if port == "" {
@@ -133,13 +135,14 @@ func (p *program) StartServer() error {
 // StopServer 优雅关闭 HTTP 服务，释放资源
 func (p *program) StopServer() error {
 	global.ZapLog.Warn("正在优雅停止服务...")
+	// 先排空任务：HTTP 服务在排空期间仍可查询执行状态、接收心跳，但拒绝手动执行
+	global.DrainJobs()
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	if err := p.Srv.Shutdown(ctx); err != nil {
 		global.ZapLog.Error("服务关闭失败", global.LogError(err))
 		return err
 	}
-	global.StopTimer()
 	global.CloseDB()
 	global.CloseAllFileHandles()
 	global.ZapLog.Warn("服务已优雅停止")
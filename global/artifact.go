@@ -0,0 +1,325 @@
+package global
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ArtifactRef 执行产物引用
+// 大输出或任务声明的文件会写入 runtime/artifacts/任务ID/执行ID/ 目录，日志中只保留引用
+type ArtifactRef struct {
+	Name   string `json:"name"`             // 产物文件名
+	Size   int64  `json:"size"`             // 文件大小（字节）
+	Source string `json:"source,omitempty"` // 来源：stdout/stderr/http_resp/func_result/file
+}
+
+// artifactSink 单次执行的产物收集器（并发安全）
+type artifactSink struct {
+	jobID  uint
+	execID string
+
+	mu   sync.Mutex
+	refs []ArtifactRef
+}
+
+// newArtifactSink 创建单次执行的产物收集器
+func newArtifactSink(jobID uint, execID string) *artifactSink {
+	return &artifactSink{jobID: jobID, execID: execID}
+}
+
+// Save 保存一个产物文件，失败时只记录日志
+func (s *artifactSink) Save(name, source string, data []byte) *ArtifactRef {
+	if s == nil {
+		return nil
+	}
+	ref, err := SaveArtifact(s.jobID, s.execID, name, source, data)
+	if err != nil {
+		if ZapLog != nil {
+			ZapLog.Error("保存执行产物失败",
+				LogField("job_id", s.jobID),
+				LogField("exec_id", s.execID),
+				LogField("name", name),
+				LogError(err))
+		}
+		return nil
+	}
+	s.mu.Lock()
+	s.refs = append(s.refs, *ref)
+	s.mu.Unlock()
+	return ref
+}
+
+// Refs 返回已收集的产物列表
+func (s *artifactSink) Refs() []ArtifactRef {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ArtifactRef(nil), s.refs...)
+}
+
+// getArtifactBaseDir 获取产物根目录
+func getArtifactBaseDir() string {
+	dir := GetConfigString("jobs.artifact_dir")
+	if dir == "" {
+		dir = filepath.Join("runtime", "artifacts")
+	}
+	return dir
+}
+
+// artifactExecDir 获取单次执行的产物目录
+func artifactExecDir(jobID uint, execID string) string {
+	return filepath.Join(getArtifactBaseDir(), fmt.Sprintf("%d", jobID), execID)
+}
+
+// validArtifactName 校验产物名/执行ID，禁止路径穿越
+func validArtifactName(name string) bool {
+	if name == "" || name == "." || name == ".." {
+		return false
+	}
+	return filepath.Base(name) == name && !strings.ContainsAny(name, `/\`)
+}
+
+// SaveArtifact 写入产物文件
+func SaveArtifact(jobID uint, execID, name, source string, data []byte) (*ArtifactRef, error) {
+	if !validArtifactName(execID) || !validArtifactName(name) {
+		return nil, fmt.Errorf("非法的产物名称: %s/%s", execID, name)
+	}
+	dir := artifactExecDir(jobID, execID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建产物目录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+		return nil, fmt.Errorf("写入产物文件失败: %v", err)
+	}
+	return &ArtifactRef{Name: name, Size: int64(len(data)), Source: source}, nil
+}
+
+// collectFiles 按任务声明的路径/通配符收集文件产物
+// 相对路径基于 baseDir（命令模式为工作目录）解析，返回收集过程中的错误说明
+func (s *artifactSink) collectFiles(patterns []string, baseDir string) []string {
+	var problems []string
+	maxBytes := int64(GetJobsConfigInt("jobs.artifact_max_file_bytes", 50*1024*1024))
+	for _, pattern := range patterns {
+		p := pattern
+		if !filepath.IsAbs(p) && baseDir != "" {
+			p = filepath.Join(baseDir, p)
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("产物路径无效 %s: %v", pattern, err))
+			continue
+		}
+		if len(matches) == 0 {
+			problems = append(problems, fmt.Sprintf("未找到产物文件: %s", pattern))
+			continue
+		}
+		for _, m := range matches {
+			info, err := os.Stat(m)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			if info.Size() > maxBytes {
+				problems = append(problems, fmt.Sprintf("产物文件过大已跳过: %s (%d 字节)", m, info.Size()))
+				continue
+			}
+			data, err := os.ReadFile(m)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("读取产物文件失败 %s: %v", m, err))
+				continue
+			}
+			s.Save(s.uniqueName(filepath.Base(m)), "file", data)
+		}
+	}
+	return problems
+}
+
+// uniqueName 同名产物自动追加序号，避免覆盖
+func (s *artifactSink) uniqueName(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exists := func(n string) bool {
+		for _, r := range s.refs {
+			if r.Name == n {
+				return true
+			}
+		}
+		return false
+	}
+	if !exists(name) {
+		return name
+	}
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", stem, i, ext)
+		if !exists(candidate) {
+			return candidate
+		}
+	}
+}
+
+// parseArtifactPatterns 解析任务声明的产物文件（【artifacts】路径1|||路径2，支持通配符）
+func parseArtifactPatterns(command string) []string {
+	var patterns []string
+	for _, line := range strings.Split(command, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "【artifacts】") {
+			continue
+		}
+		for _, p := range strings.Split(strings.TrimPrefix(line, "【artifacts】"), "|||") {
+			if p = strings.TrimSpace(p); p != "" {
+				patterns = append(patterns, p)
+			}
+		}
+	}
+	return patterns
+}
+
+// previewText 按字节截断文本（保证UTF-8完整）
+func previewText(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
+
+// offloadLargeOutputs 将超过阈值的输出转存为产物，日志中仅保留预览与引用
+func offloadLargeOutputs(log *JobExecLog, sink *artifactSink) {
+	if sink == nil {
+		return
+	}
+	threshold := GetJobsConfigInt("jobs.artifact_threshold_bytes", 64*1024)
+	preview := GetJobsConfigInt("jobs.log_line_truncate", 1000)
+
+	offload := func(field *string, name, source string) {
+		if len(*field) <= threshold {
+			return
+		}
+		ref := sink.Save(name, source, []byte(*field))
+		if ref == nil {
+			// 转存失败时退化为截断，避免写出超大日志行
+			*field = previewText(*field, preview) + "\n... (内容已截断)"
+			return
+		}
+		*field = previewText(*field, preview) + fmt.Sprintf("\n... (内容已截断，完整内容见产物 %s，共 %d 字节)", ref.Name, ref.Size)
+	}
+
+	offload(&log.Stdout, "stdout.txt", "stdout")
+	offload(&log.Stderr, "stderr.txt", "stderr")
+	offload(&log.HttpResp, "http_resp.txt", "http_resp")
+	offload(&log.FuncResult, "func_result.txt", "func_result")
+	log.Artifacts = sink.Refs()
+}
+
+// ListArtifacts 列出某次执行的产物
+func ListArtifacts(jobID uint, execID string) ([]ArtifactRef, error) {
+	if !validArtifactName(execID) {
+		return nil, fmt.Errorf("非法的执行ID")
+	}
+	entries, err := os.ReadDir(artifactExecDir(jobID, execID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []ArtifactRef{}, nil
+		}
+		return nil, err
+	}
+	refs := make([]ArtifactRef, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		refs = append(refs, ArtifactRef{Name: e.Name(), Size: info.Size()})
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs, nil
+}
+
+// ArtifactPath 获取产物文件路径（用于下载），文件不存在时返回错误
+func ArtifactPath(jobID uint, execID, name string) (string, error) {
+	if !validArtifactName(execID) || !validArtifactName(name) {
+		return "", fmt.Errorf("非法的产物名称")
+	}
+	path := filepath.Join(artifactExecDir(jobID, execID), name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("产物不是文件")
+	}
+	return path, nil
+}
+
+// CleanupArtifacts 按保留天数清理过期产物目录
+// 返回删除的执行目录数量
+func CleanupArtifacts() int {
+	keepDays := GetJobsConfigInt("jobs.artifact_keep_days", 7)
+	cutoff := time.Now().AddDate(0, 0, -keepDays)
+	base := getArtifactBaseDir()
+
+	jobDirs, err := os.ReadDir(base)
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, jd := range jobDirs {
+		if !jd.IsDir() {
+			continue
+		}
+		jobPath := filepath.Join(base, jd.Name())
+		execDirs, err := os.ReadDir(jobPath)
+		if err != nil {
+			continue
+		}
+		for _, ed := range execDirs {
+			info, err := ed.Info()
+			if err != nil || !ed.IsDir() {
+				continue
+			}
+			if info.ModTime().Before(cutoff) {
+				if err := os.RemoveAll(filepath.Join(jobPath, ed.Name())); err == nil {
+					removed++
+				}
+			}
+		}
+		// 任务目录为空时一并删除
+		if rest, err := os.ReadDir(jobPath); err == nil && len(rest) == 0 {
+			os.Remove(jobPath)
+		}
+	}
+	return removed
+}
+
+var artifactJanitorOnce sync.Once
+
+// StartArtifactJanitor 启动产物定期清理（每小时一次）
+func StartArtifactJanitor() {
+	artifactJanitorOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			for {
+				if n := CleanupArtifacts(); n > 0 && ZapLog != nil {
+					ZapLog.Info("已清理过期执行产物", LogField("count", n))
+				}
+				<-ticker.C
+			}
+		}()
+	})
+}
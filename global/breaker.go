@@ -0,0 +1,226 @@
+package global
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"xiaohuAdmin/models/jobs"
+)
+
+// JobStateSuspended 熔断暂停状态（区别于手动停止 State=2）
+// 熔断中的任务仍保留在调度器中，仅在到达半开探测时间时才会真正执行
+const JobStateSuspended = 3
+
+// SchedulableJobStates 需要加载到调度器的任务状态：等待、执行中、熔断暂停
+var SchedulableJobStates = []int{0, 1, JobStateSuspended}
+
+// BreakerConfig 任务熔断配置（Jobs.Breaker 字段，JSON）
+// 示例：{"failures":5} 或 {"failure_rate":0.5,"window_seconds":300,"min_runs":10,"probe_seconds":600}
+type BreakerConfig struct {
+	Failures      int     `json:"failures"`       // 连续失败次数阈值，0表示不启用
+	FailureRate   float64 `json:"failure_rate"`   // 窗口内失败率阈值（0-1），0表示不启用
+	WindowSeconds int     `json:"window_seconds"` // 失败率统计窗口（秒）
+	MinRuns       int     `json:"min_runs"`       // 窗口内最少执行次数，达到后才计算失败率
+	ProbeSeconds  int     `json:"probe_seconds"`  // 半开探测间隔（秒），0表示不自动恢复
+}
+
+// breakerResult 单次执行结果
+type breakerResult struct {
+	at      time.Time
+	success bool
+}
+
+// breakerState 任务熔断统计（仅内存，重启后重新计数）
+type breakerState struct {
+	consecutive int
+	results     []breakerResult
+}
+
+var (
+	breakerMu     sync.Mutex
+	breakerStates = make(map[uint]*breakerState)
+)
+
+// ParseBreakerConfig 解析熔断配置，空字符串返回 nil 表示不启用
+func ParseBreakerConfig(raw string) (*BreakerConfig, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var cfg BreakerConfig
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, fmt.Errorf("熔断配置不是合法JSON: %v", err)
+	}
+	if cfg.FailureRate > 0 {
+		if cfg.WindowSeconds <= 0 {
+			cfg.WindowSeconds = 300
+		}
+		if cfg.MinRuns <= 0 {
+			cfg.MinRuns = 5
+		}
+	}
+	return &cfg, nil
+}
+
+// ValidateBreakerConfig 校验熔断配置
+func ValidateBreakerConfig(raw string) error {
+	cfg, err := ParseBreakerConfig(raw)
+	if err != nil || cfg == nil {
+		return err
+	}
+	if cfg.Failures < 0 || cfg.WindowSeconds < 0 || cfg.MinRuns < 0 || cfg.ProbeSeconds < 0 {
+		return fmt.Errorf("熔断配置数值不能为负数")
+	}
+	if cfg.FailureRate < 0 || cfg.FailureRate > 1 {
+		return fmt.Errorf("failure_rate 取值范围为 0-1")
+	}
+	if cfg.Failures == 0 && cfg.FailureRate == 0 {
+		return fmt.Errorf("熔断配置需要设置 failures 或 failure_rate")
+	}
+	return nil
+}
+
+// recordBreakerResult 记录一次调度执行结果，达到熔断条件时返回原因
+func recordBreakerResult(jobID uint, cfg *BreakerConfig, success bool) string {
+	if cfg == nil {
+		return ""
+	}
+	now := time.Now()
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	st := breakerStates[jobID]
+	if st == nil {
+		st = &breakerState{}
+		breakerStates[jobID] = st
+	}
+	if success {
+		st.consecutive = 0
+	} else {
+		st.consecutive++
+	}
+	if cfg.FailureRate > 0 {
+		cutoff := now.Add(-time.Duration(cfg.WindowSeconds) * time.Second)
+		kept := st.results[:0]
+		for _, r := range st.results {
+			if r.at.After(cutoff) {
+				kept = append(kept, r)
+			}
+		}
+		st.results = append(kept, breakerResult{at: now, success: success})
+	}
+
+	if cfg.Failures > 0 && st.consecutive >= cfg.Failures {
+		return fmt.Sprintf("连续失败 %d 次（阈值 %d）", st.consecutive, cfg.Failures)
+	}
+	if cfg.FailureRate > 0 && len(st.results) >= cfg.MinRuns {
+		failed := 0
+		for _, r := range st.results {
+			if !r.success {
+				failed++
+			}
+		}
+		rate := float64(failed) / float64(len(st.results))
+		if rate >= cfg.FailureRate {
+			return fmt.Sprintf("%d 秒内失败率 %.0f%%（%d/%d，阈值 %.0f%%）",
+				cfg.WindowSeconds, rate*100, failed, len(st.results), cfg.FailureRate*100)
+		}
+	}
+	return ""
+}
+
+// ResetBreaker 清空任务的熔断统计
+func ResetBreaker(jobID uint) {
+	breakerMu.Lock()
+	delete(breakerStates, jobID)
+	breakerMu.Unlock()
+}
+
+// ClearSuspension 清除任务的熔断标记（手动恢复/编辑状态时调用，不写库）
+func ClearSuspension(job *Jobs) {
+	job.SuspendReason = ""
+	job.SuspendedAt = nil
+	ResetBreaker(job.ID)
+}
+
+// suspendJob 熔断：任务置为暂停状态，记录原因并发送通知
+func suspendJob(job *Jobs, reason string) {
+	now := time.Now()
+	err := DB.Model(&jobs.Jobs{}).Where("id=? AND state<>?", job.ID, 2).Updates(map[string]interface{}{
+		"state":          JobStateSuspended,
+		"suspend_reason": reason,
+		"suspended_at":   now,
+	}).Error
+	if err != nil {
+		if ZapLog != nil {
+			ZapLog.Error("任务熔断状态写入失败", LogField("job_id", job.ID), LogError(err))
+		}
+		return
+	}
+	ResetBreaker(job.ID)
+
+	cfg, _ := ParseBreakerConfig(job.Breaker)
+	msg := "任务已熔断暂停：" + reason
+	if cfg != nil && cfg.ProbeSeconds > 0 {
+		msg += fmt.Sprintf("，%d 秒后进行探测", cfg.ProbeSeconds)
+	}
+	NewJobLogger(job.ID, job.Name).Warning(msg)
+	if ZapLog != nil {
+		ZapLog.Warn("任务熔断暂停",
+			LogField("job_id", job.ID),
+			LogField("name", job.Name),
+			LogField("reason", reason))
+	}
+	NotifyJobEvent(job, NotifyOnSuspended, &JobExecLog{
+		Time:     now.Format("2006-01-02 15:04:05.000"),
+		JobID:    job.ID,
+		JobName:  job.Name,
+		Mode:     job.Mode,
+		Source:   "breaker",
+		Status:   "熔断暂停",
+		ErrorMsg: reason,
+	})
+}
+
+// acquireBreakerProbe 判断熔断任务是否到达探测时间，并抢占本次探测
+// 通过条件更新 suspended_at 保证同一时刻只有一次探测
+func acquireBreakerProbe(job *Jobs, current *Jobs) bool {
+	cfg, _ := ParseBreakerConfig(job.Breaker)
+	if cfg == nil || cfg.ProbeSeconds <= 0 || current.SuspendedAt == nil {
+		return false
+	}
+	if time.Since(*current.SuspendedAt) < time.Duration(cfg.ProbeSeconds)*time.Second {
+		return false
+	}
+	res := DB.Model(&jobs.Jobs{}).
+		Where("id=? AND state=? AND suspended_at=?", job.ID, JobStateSuspended, *current.SuspendedAt).
+		Update("suspended_at", time.Now())
+	return res.Error == nil && res.RowsAffected == 1
+}
+
+// finishBreakerProbe 处理探测结果：成功则恢复任务，失败则继续暂停等待下次探测
+func finishBreakerProbe(job *Jobs, success bool) {
+	jobLogger := NewJobLogger(job.ID, job.Name)
+	if !success {
+		jobLogger.Warning("熔断探测失败，任务继续暂停")
+		return
+	}
+	err := DB.Model(&jobs.Jobs{}).Where("id=? AND state=?", job.ID, JobStateSuspended).Updates(map[string]interface{}{
+		"state":          0,
+		"suspend_reason": "",
+		"suspended_at":   nil,
+	}).Error
+	if err != nil {
+		if ZapLog != nil {
+			ZapLog.Error("熔断任务恢复失败", LogField("job_id", job.ID), LogError(err))
+		}
+		return
+	}
+	ResetBreaker(job.ID)
+	jobLogger.Success("熔断探测成功，任务已自动恢复")
+	if ZapLog != nil {
+		ZapLog.Info("熔断任务已自动恢复", LogField("job_id", job.ID), LogField("name", job.Name))
+	}
+}
@@ -0,0 +1,226 @@
+package global
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"xiaohuAdmin/models/jobs"
+)
+
+func TestParseBreakerConfig(t *testing.T) {
+	for _, tc := range []struct {
+		raw     string
+		wantErr bool
+		want    *BreakerConfig
+	}{
+		{raw: "", want: nil},
+		{raw: `{"failures":3}`, want: &BreakerConfig{Failures: 3}},
+		{raw: `{"failure_rate":0.5}`, want: &BreakerConfig{FailureRate: 0.5, WindowSeconds: 300, MinRuns: 5}},
+		{raw: `{"failure_rate":0.5,"window_seconds":60,"min_runs":2,"probe_seconds":30}`, want: &BreakerConfig{FailureRate: 0.5, WindowSeconds: 60, MinRuns: 2, ProbeSeconds: 30}},
+		{raw: `{"failures":`, wantErr: true},
+	} {
+		cfg, err := ParseBreakerConfig(tc.raw)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%s: 错误不符: %v", tc.raw, err)
+		}
+		if tc.wantErr {
+			continue
+		}
+		if (cfg == nil) != (tc.want == nil) || (cfg != nil && *cfg != *tc.want) {
+			t.Fatalf("%s: 解析结果不符: %+v", tc.raw, cfg)
+		}
+	}
+
+	for raw, ok := range map[string]bool{
+		`{"failures":3}`:                    true,
+		`{"failure_rate":1}`:                true,
+		`{"probe_seconds":60}`:              false,
+		`{"failures":-1}`:                   false,
+		`{"failure_rate":1.5}`:              false,
+		`{"failures":3,"probe_seconds":-5}`: false,
+	} {
+		if err := ValidateBreakerConfig(raw); (err == nil) != ok {
+			t.Fatalf("%s: 校验结果不符: %v", raw, err)
+		}
+	}
+}
+
+func TestBreakerConsecutiveFailures(t *testing.T) {
+	const jobID = 9201
+	defer ResetBreaker(jobID)
+	cfg := &BreakerConfig{Failures: 3}
+
+	for _, success := range []bool{false, false, true, false, false} {
+		if reason := recordBreakerResult(jobID, cfg, success); reason != "" {
+			t.Fatalf("成功会清零连续失败，不应熔断: %s", reason)
+		}
+	}
+	reason := recordBreakerResult(jobID, cfg, false)
+	if !strings.Contains(reason, "连续失败 3 次") {
+		t.Fatalf("连续失败 3 次应熔断，实际: %q", reason)
+	}
+	if reason := recordBreakerResult(jobID, nil, false); reason != "" {
+		t.Fatalf("未配置熔断不应熔断: %s", reason)
+	}
+}
+
+func TestBreakerFailureRateWindow(t *testing.T) {
+	const jobID = 9202
+	defer ResetBreaker(jobID)
+	cfg := &BreakerConfig{FailureRate: 0.5, WindowSeconds: 60, MinRuns: 4}
+
+	// 执行次数未达到 min_runs 时不计算失败率
+	for _, success := range []bool{false, false, true} {
+		if reason := recordBreakerResult(jobID, cfg, success); reason != "" {
+			t.Fatalf("未达到 min_runs 不应熔断: %s", reason)
+		}
+	}
+	// 窗口外的结果不计入：把已有结果移到窗口之前
+	breakerMu.Lock()
+	for i := range breakerStates[jobID].results {
+		breakerStates[jobID].results[i].at = time.Now().Add(-2 * time.Minute)
+	}
+	breakerMu.Unlock()
+	for _, success := range []bool{true, false, true} {
+		if reason := recordBreakerResult(jobID, cfg, success); reason != "" {
+			t.Fatalf("窗口外的失败不应计入: %s", reason)
+		}
+	}
+	// 窗口内 2/4 失败，达到 50%
+	reason := recordBreakerResult(jobID, cfg, false)
+	if !strings.Contains(reason, "60 秒内失败率 50%（2/4") {
+		t.Fatalf("失败率达到阈值应熔断，实际: %q", reason)
+	}
+}
+
+// testBreakerJob 熔断测试用的命令任务
+func testBreakerJob(t *testing.T, command string) *Jobs {
+	return createTestJob(t, &Jobs{
+		Name:     "breaker-" + t.Name(),
+		CronExpr: "0 0 1 1 *",
+		Mode:     "command",
+		Command:  "【command】" + command,
+		Breaker:  `{"failures":2,"probe_seconds":60}`,
+	})
+}
+
+// setTestJobCommand 修改任务命令（探测时读取的是调度器持有的任务定义）
+func setTestJobCommand(job *Jobs, command string) {
+	job.Command = "【command】" + command
+	job.Config = ""
+}
+
+// backdateSuspension 将熔断时间提前，使任务到达探测时间
+func backdateSuspension(t *testing.T, id uint, d time.Duration) time.Time {
+	t.Helper()
+	at := time.Now().Add(-d)
+	if err := DB.Model(&jobs.Jobs{}).Where("id=?", id).Update("suspended_at", at).Error; err != nil {
+		t.Fatal(err)
+	}
+	return at
+}
+
+func TestBreakerSuspendProbeResume(t *testing.T) {
+	newTestDB(t)
+	job := testBreakerJob(t, "exit 1")
+
+	// 连续失败 2 次：熔断暂停
+	runJobWithPolicy(job, "cron")
+	if cur := reloadTestJob(t, job.ID); cur.State != 0 {
+		t.Fatalf("失败 1 次不应熔断，状态 %d", cur.State)
+	}
+	runJobWithPolicy(job, "cron")
+	cur := reloadTestJob(t, job.ID)
+	if cur.State != JobStateSuspended || !strings.Contains(cur.SuspendReason, "连续失败 2 次") || cur.SuspendedAt == nil {
+		t.Fatalf("应熔断暂停: state=%d reason=%q", cur.State, cur.SuspendReason)
+	}
+	if cur.RunCount != 2 {
+		t.Fatalf("运行次数应为 2，实际 %d", cur.RunCount)
+	}
+
+	// 未到探测时间：不执行
+	runJobWithPolicy(job, "cron")
+	if cur := reloadTestJob(t, job.ID); cur.RunCount != 2 || cur.State != JobStateSuspended {
+		t.Fatalf("未到探测时间不应执行: run_count=%d state=%d", cur.RunCount, cur.State)
+	}
+
+	// 探测失败：继续暂停，探测时间顺延
+	backdateSuspension(t, job.ID, 2*time.Minute)
+	runJobWithPolicy(job, "cron")
+	cur = reloadTestJob(t, job.ID)
+	if cur.State != JobStateSuspended || cur.RunCount != 3 {
+		t.Fatalf("探测失败应继续暂停: state=%d run_count=%d", cur.State, cur.RunCount)
+	}
+	if time.Since(*cur.SuspendedAt) > 10*time.Second {
+		t.Fatalf("探测后应从当前时间重新计算探测间隔: %s", cur.SuspendedAt)
+	}
+	var probes int64
+	DB.Model(&jobs.JobExec{}).Where("job_id=? AND source=?", job.ID, "probe").Count(&probes)
+	if probes != 1 {
+		t.Fatalf("应记录 1 次探测执行，实际 %d", probes)
+	}
+
+	// 探测成功：恢复并清空熔断信息
+	setTestJobCommand(job, "exit 0")
+	backdateSuspension(t, job.ID, 2*time.Minute)
+	runJobWithPolicy(job, "cron")
+	cur = reloadTestJob(t, job.ID)
+	if cur.State != 0 || cur.SuspendReason != "" || cur.SuspendedAt != nil {
+		t.Fatalf("探测成功应恢复: state=%d reason=%q", cur.State, cur.SuspendReason)
+	}
+
+	// 恢复后重新计数：再次连续失败 2 次才熔断
+	setTestJobCommand(job, "exit 1")
+	runJobWithPolicy(job, "cron")
+	if cur := reloadTestJob(t, job.ID); cur.State != 0 {
+		t.Fatalf("恢复后统计应清零，失败 1 次不应熔断")
+	}
+	runJobWithPolicy(job, "cron")
+	if cur := reloadTestJob(t, job.ID); cur.State != JobStateSuspended {
+		t.Fatalf("恢复后连续失败 2 次应再次熔断，状态 %d", cur.State)
+	}
+}
+
+func TestBreakerProbeWithoutInterval(t *testing.T) {
+	newTestDB(t)
+	job := testBreakerJob(t, "exit 0")
+	job.Breaker = `{"failures":2}`
+	at := time.Now().Add(-time.Hour)
+	DB.Model(&jobs.Jobs{}).Where("id=?", job.ID).Updates(map[string]any{"state": JobStateSuspended, "suspended_at": at})
+
+	// 未配置 probe_seconds：保持暂停，只能手动恢复
+	runJobWithPolicy(job, "cron")
+	if cur := reloadTestJob(t, job.ID); cur.State != JobStateSuspended || cur.RunCount != 0 {
+		t.Fatalf("未配置探测间隔不应执行: state=%d run_count=%d", cur.State, cur.RunCount)
+	}
+}
+
+func TestClearSuspensionOnEdit(t *testing.T) {
+	newTestDB(t)
+	job := testBreakerJob(t, "exit 1")
+	cfg, _ := ParseBreakerConfig(job.Breaker)
+	runJobWithPolicy(job, "cron")
+	runJobWithPolicy(job, "cron")
+	cur := reloadTestJob(t, job.ID)
+	if cur.State != JobStateSuspended {
+		t.Fatalf("应熔断暂停，状态 %d", cur.State)
+	}
+
+	// 编辑为启用状态：清除熔断标记与内存统计
+	recordBreakerResult(job.ID, cfg, false)
+	cur.State = 0
+	ClearSuspension(cur)
+	if cur.SuspendReason != "" || cur.SuspendedAt != nil {
+		t.Fatalf("熔断标记未清除: %q %v", cur.SuspendReason, cur.SuspendedAt)
+	}
+	if err := DB.Save(cur).Error; err != nil {
+		t.Fatal(err)
+	}
+	if cur := reloadTestJob(t, job.ID); cur.State != 0 || cur.SuspendReason != "" || cur.SuspendedAt != nil {
+		t.Fatalf("编辑后数据库中仍有熔断标记: state=%d reason=%q", cur.State, cur.SuspendReason)
+	}
+	if reason := recordBreakerResult(job.ID, cfg, false); reason != "" {
+		t.Fatalf("清除后统计应重新开始，失败 1 次不应熔断: %s", reason)
+	}
+}
@@ -0,0 +1,146 @@
+package global
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"regexp"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// 字符集识别与转换（所有平台）
+//
+// HTTP响应按 BOM、Content-Type 的 charset、响应体前 1024 字节中的 HTML meta / XML 声明的顺序确定编码；
+// 命令输出按【encoding】、BOM 确定。未声明编码时合法的UTF-8原样保留，Windows 下其他内容按 GB18030 尝试解码。
+// 编码名称采用 WHATWG 标签（gbk、gb18030、big5、shift_jis、euc-kr、utf-16le 等），发生转换时原始字节另存为产物。
+
+var (
+	metaCharsetRe = regexp.MustCompile(`(?i)<meta[^>]+charset\s*=\s*["']?\s*([A-Za-z0-9_:.\-]+)`)
+	xmlEncodingRe = regexp.MustCompile(`(?i)^\s*<\?xml[^>]+encoding\s*=\s*["']([A-Za-z0-9_:.\-]+)["']`)
+)
+
+// sniffBOM 根据字节顺序标记判断编码，返回编码名与BOM长度
+func sniffBOM(body []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		return "utf-8", 3
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return "utf-16le", 2
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return "utf-16be", 2
+	}
+	return "", 0
+}
+
+// detectEncoding 检测响应编码，未声明时返回空字符串
+func detectEncoding(body []byte, contentType string) string {
+	// BOM 优先
+	if enc, _ := sniffBOM(body); enc != "" {
+		return enc
+	}
+
+	// 从Content-Type中检测编码
+	mediaType := ""
+	if contentType != "" {
+		if mt, params, err := mime.ParseMediaType(contentType); err == nil {
+			mediaType = mt
+			if cs := strings.TrimSpace(params["charset"]); cs != "" {
+				return strings.ToLower(cs)
+			}
+		}
+	}
+
+	// 从HTML meta或XML声明中检测编码
+	if mediaType == "" || strings.Contains(mediaType, "html") || strings.Contains(mediaType, "xml") {
+		head := body
+		if len(head) > 1024 {
+			head = head[:1024]
+		}
+		if m := xmlEncodingRe.FindSubmatch(head); m != nil {
+			return strings.ToLower(string(m[1]))
+		}
+		if m := metaCharsetRe.FindSubmatch(head); m != nil {
+			return strings.ToLower(string(m[1]))
+		}
+	}
+	return ""
+}
+
+// lookupEncoding 校验编码名称，返回规范化后的名称
+func lookupEncoding(label string) (string, error) {
+	enc, err := htmlindex.Get(strings.TrimSpace(label))
+	if err != nil {
+		return "", fmt.Errorf("不支持的编码: %s", label)
+	}
+	name, err := htmlindex.Name(enc)
+	if err != nil {
+		return "", fmt.Errorf("不支持的编码: %s", label)
+	}
+	return name, nil
+}
+
+// convertToUTF8 按编码转换为UTF-8，返回转换结果与实际使用的编码（未转换时为空）
+// encoding 为空时：合法UTF-8原样返回，Windows下尝试GB18030
+func convertToUTF8(body []byte, encoding string) ([]byte, string, error) {
+	if len(body) == 0 {
+		return body, "", nil
+	}
+	if encoding == "" {
+		if utf8.Valid(body) {
+			return body, "", nil
+		}
+		if runtime.GOOS != "windows" {
+			return body, "", nil
+		}
+		encoding = "gb18030"
+	}
+	enc, err := htmlindex.Get(strings.TrimSpace(encoding))
+	if err != nil {
+		return body, "", fmt.Errorf("不支持的编码: %s", encoding)
+	}
+	name, _ := htmlindex.Name(enc)
+	if name == "utf-8" {
+		return body, "", nil
+	}
+	// 去掉与编码一致的BOM，避免解码为多余字符
+	if bom, n := sniffBOM(body); bom == name {
+		body = body[n:]
+	}
+	out, _, err := transform.Bytes(enc.NewDecoder(), body)
+	if err != nil {
+		return body, "", fmt.Errorf("按 %s 解码失败: %v", name, err)
+	}
+	return out, name, nil
+}
+
+// keepRawOutput 转换过编码时将原始字节另存为产物，返回产物名
+func keepRawOutput(sink *artifactSink, name, source string, raw []byte) string {
+	if sink == nil {
+		return ""
+	}
+	if ref := sink.Save(sink.uniqueName(name), source, raw); ref != nil {
+		return ref.Name
+	}
+	return ""
+}
+
+// decodeCommandOutput 转换命令输出编码（【encoding】优先，其次BOM），发生转换时原始输出另存为产物
+func decodeCommandOutput(raw []byte, encoding, source string, sink *artifactSink) string {
+	if encoding == "" {
+		encoding, _ = sniffBOM(raw)
+	}
+	out, used, err := convertToUTF8(raw, encoding)
+	if err != nil {
+		LogWarn("命令输出编码转换失败", LogField("encoding", encoding), LogError(err))
+		return string(raw)
+	}
+	if used != "" {
+		keepRawOutput(sink, source+".raw", source, raw)
+	}
+	return string(out)
+}
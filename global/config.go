@@ -31,8 +31,44 @@ type GlobalConfig struct {
 		HTTPResponseMaxBytes  int  `mapstructure:"http_response_max_bytes"`
 		LogSummaryEnabled     bool `mapstructure:"log_summary_enabled"`
 		LogLineTruncate       int  `mapstructure:"log_line_truncate"`
+
+		ArtifactDir            string `mapstructure:"artifact_dir"`                // 产物存储目录
+		ArtifactThresholdBytes int    `mapstructure:"artifact_threshold_bytes"`    // 输出超过该字节数转存为产物
+		ArtifactMaxFileBytes   int    `mapstructure:"artifact_max_file_bytes"`     // 单个收集文件大小上限
+		ArtifactKeepDays       int    `mapstructure:"artifact_keep_days"`          // 产物保留天数
+		HeartbeatGraceSeconds  int    `mapstructure:"heartbeat_grace_seconds"`     // 心跳任务默认宽限期（秒）
+		ExecRecordKeepDays     int    `mapstructure:"exec_record_keep_days"`       // 执行登记保留天数
+		DrainTimeoutSeconds    int    `mapstructure:"drain_timeout_seconds"`       // 停止服务时等待在途执行的时长（秒）
+		DrainKillGraceSeconds  int    `mapstructure:"drain_kill_grace_seconds"`    // 排空超时取消执行后，等待其写完日志的时长（秒）
+		DrainRequeue           bool   `mapstructure:"drain_requeue"`               // 启动时是否补跑上次被中断的执行
+		DefinitionsDir         string `mapstructure:"definitions_dir"`             // YAML任务定义目录，为空不启用
+		ImportTimeoutSeconds   int    `mapstructure:"import_timeout_seconds"`      // 从 crontab/systemd 导入的任务执行超时（秒）
+		HTTPBodyMaxBytes       int    `mapstructure:"http_body_max_bytes"`         // HTTP任务请求体（含上传文件）大小上限
+		HTTPTransportIdleSecs  int    `mapstructure:"http_transport_idle_seconds"` // HTTP任务连接池空闲多久后关闭（秒）
+		HTTPProxy              string `mapstructure:"http_proxy"`                  // HTTP任务默认代理，任务未配置【proxy】时使用
+		HTTPNoProxy            string `mapstructure:"http_no_proxy"`               // 默认不代理列表，逗号分隔
+
+		HTTPMaxRetryWaitSecs int             `mapstructure:"http_max_retry_wait_seconds"` // 服务端限流（Retry-After）时默认最长等待（秒）
+		HTTPHostRateLimit    float64         `mapstructure:"http_host_rate_limit"`        // 每个主机默认每秒请求数，0 不限制
+		HTTPHostRateLimits   []HostRateLimit `mapstructure:"http_host_rate_limits"`       // 按主机配置每秒请求数
+
+		SSHKnownHosts  string `mapstructure:"ssh_known_hosts"` // SSH任务默认 known_hosts 文件，为空时使用 ~/.ssh/known_hosts
+		SSHConcurrency int    `mapstructure:"ssh_concurrency"` // SSH任务默认同时连接的主机数
 	} `mapstructure:"jobs"`
 
+	// Notify 通知配置
+	Notify struct {
+		Channels           map[string]NotifyChannelConfig `mapstructure:"channels"`             // 通知渠道，键为渠道名
+		TimeoutSeconds     int                            `mapstructure:"timeout_seconds"`      // 发送超时（秒）
+		OutputExcerptBytes int                            `mapstructure:"output_excerpt_bytes"` // 消息中输出摘要长度
+		TitleTemplate      string                         `mapstructure:"title_template"`       // 全局标题模板
+		BodyTemplate       string                         `mapstructure:"body_template"`        // 全局正文模板
+		SMSChannel         string                         `mapstructure:"sms_channel"`          // SMS函数使用的Webhook渠道
+	} `mapstructure:"notify"`
+
+	// Secrets 密钥（任务配置中以 secret:名称 引用，名称不区分大小写）
+	Secrets map[string]string `mapstructure:"secrets"`
+
 	// Database 数据库配置
 	Database struct {
 		Type string `mapstructure:"type"` // 数据库类型: mysql 或 sqlite
@@ -230,6 +266,25 @@ daemon:
 
 job_log_keep_count: 3
 
+# 通知渠道（任务通过 notify 字段订阅，如 [{"channel":"ops","on":["failure","recovery"]}]）
+# notify:
+#     channels:
+#         ops:
+#             type: smtp          # smtp/webhook/dingtalk/wecom/feishu/slack
+#             host: smtp.example.com
+#             port: 587
+#             username: bot@example.com
+#             password: xxx
+#             to: ["ops@example.com"]
+#         ding:
+#             type: dingtalk
+#             url: https://oapi.dingtalk.com/robot/send?access_token=xxx
+#             secret: SECxxx
+
+# 密钥（HTTP任务认证等配置中以 secret:名称 引用；也可用 env:变量名、file:路径）
+# secrets:
+#     api_token: xxx
+
 # 生产环境建议用环境变量覆盖敏感配置，如：
 #   DATABASE_TYPE=mysql
 #   DATABASE_MYSQL_HOST=mysql
@@ -266,6 +321,28 @@ func setDefaultValues() {
 	Viper.SetDefault("jobs.http_response_max_bytes", 1000)
 	Viper.SetDefault("jobs.log_summary_enabled", true)
 	Viper.SetDefault("jobs.log_line_truncate", 1000)
+	Viper.SetDefault("jobs.artifact_dir", "runtime/artifacts")
+	Viper.SetDefault("jobs.artifact_threshold_bytes", 64*1024)
+	Viper.SetDefault("jobs.artifact_max_file_bytes", 50*1024*1024)
+	Viper.SetDefault("jobs.artifact_keep_days", 7)
+	Viper.SetDefault("jobs.heartbeat_grace_seconds", 300)
+	Viper.SetDefault("jobs.exec_record_keep_days", 7)
+	Viper.SetDefault("jobs.drain_timeout_seconds", 30)
+	Viper.SetDefault("jobs.drain_kill_grace_seconds", 5)
+	Viper.SetDefault("jobs.drain_requeue", false)
+	Viper.SetDefault("jobs.import_timeout_seconds", 3600)
+	Viper.SetDefault("jobs.http_body_max_bytes", 10*1024*1024)
+	Viper.SetDefault("jobs.http_transport_idle_seconds", 300)
+	Viper.SetDefault("jobs.http_proxy", "")
+	Viper.SetDefault("jobs.http_no_proxy", "")
+	Viper.SetDefault("jobs.http_max_retry_wait_seconds", 60)
+	Viper.SetDefault("jobs.http_host_rate_limit", 0)
+	Viper.SetDefault("jobs.ssh_known_hosts", "")
+	Viper.SetDefault("jobs.ssh_concurrency", 5)
+
+	// 通知默认值
+	Viper.SetDefault("notify.timeout_seconds", 10)
+	Viper.SetDefault("notify.output_excerpt_bytes", 500)
 
 	// 兼容旧配置的默认值
 	Viper.SetDefault("db_mysql.charset", "utf8mb4")
@@ -0,0 +1,367 @@
+package global
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"xiaohuAdmin/models/jobs"
+
+	"golang.org/x/net/publicsuffix"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// HTTP任务Cookie罐
+//
+//	【cookie_jar】job      任务独立的Cookie罐（罐名 job:任务ID）
+//	【cookie_jar】portal   多个任务共享的命名Cookie罐
+//
+// 执行前从数据库载入，响应中的 Set-Cookie（含重定向）自动更新罐内容并按 Expires/Max-Age 过期，
+// 执行结束后只保存本次变更的Cookie，并发执行同一罐时互不覆盖其他Cookie。
+
+// CookieJarPerJob 【cookie_jar】job 表示任务独立的Cookie罐
+const CookieJarPerJob = "job"
+
+// cookieJarName 计算实际罐名，job/true 为任务独立罐
+func cookieJarName(value string, jobID uint) string {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, CookieJarPerJob) || strings.EqualFold(value, "true") {
+		return fmt.Sprintf("job:%d", jobID)
+	}
+	return value
+}
+
+// persistentCookieJar 实现 http.CookieJar，记录变更以便执行结束后写回数据库
+type persistentCookieJar struct {
+	name    string
+	mu      sync.Mutex
+	entries map[string]*jobs.CookieJarEntry
+	changed map[string]bool // 已变更的键，entries 中不存在表示删除
+}
+
+func cookieKey(domain, path, name string) string {
+	return domain + ";" + path + ";" + name
+}
+
+// loadCookieJar 载入Cookie罐，同时删除已过期的Cookie
+func loadCookieJar(name string) (*persistentCookieJar, error) {
+	if DB == nil {
+		return nil, fmt.Errorf("数据库未初始化")
+	}
+	now := time.Now()
+	DB.Where("jar = ? AND expires IS NOT NULL AND expires <= ?", name, now).Delete(&jobs.CookieJarEntry{})
+	var rows []jobs.CookieJarEntry
+	if err := DB.Where("jar = ?", name).Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("读取Cookie罐失败: %v", err)
+	}
+	jar := &persistentCookieJar{
+		name:    name,
+		entries: make(map[string]*jobs.CookieJarEntry, len(rows)),
+		changed: make(map[string]bool),
+	}
+	for i := range rows {
+		e := &rows[i]
+		jar.entries[cookieKey(e.Domain, e.Path, e.Name)] = e
+	}
+	return jar, nil
+}
+
+// Len 罐内Cookie数量
+func (j *persistentCookieJar) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.entries)
+}
+
+// SetCookies 处理响应中的 Set-Cookie
+func (j *persistentCookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	host := canonicalCookieHost(u.Hostname())
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, c := range cookies {
+		if c.Name == "" {
+			continue
+		}
+		domain, hostOnly, ok := cookieDomain(host, c.Domain)
+		if !ok {
+			continue
+		}
+		// 非HTTPS响应不能设置 Secure Cookie
+		if c.Secure && u.Scheme != "https" {
+			continue
+		}
+		path := c.Path
+		if path == "" || path[0] != '/' {
+			path = defaultCookiePath(u.Path)
+		}
+		key := cookieKey(domain, path, c.Name)
+
+		var expires *time.Time
+		switch {
+		case c.MaxAge < 0:
+			// Max-Age=0 或负数：删除
+			if _, exists := j.entries[key]; exists {
+				delete(j.entries, key)
+				j.changed[key] = true
+			}
+			continue
+		case c.MaxAge > 0:
+			t := now.Add(time.Duration(c.MaxAge) * time.Second)
+			expires = &t
+		case !c.Expires.IsZero():
+			if !c.Expires.After(now) {
+				if _, exists := j.entries[key]; exists {
+					delete(j.entries, key)
+					j.changed[key] = true
+				}
+				continue
+			}
+			t := c.Expires
+			expires = &t
+		}
+
+		e, exists := j.entries[key]
+		if !exists {
+			e = &jobs.CookieJarEntry{Jar: j.name, Domain: domain, Path: path, Name: c.Name, CreatedAt: now}
+			j.entries[key] = e
+		}
+		e.Value = c.Value
+		e.HostOnly = hostOnly
+		e.Secure = c.Secure
+		e.HttpOnly = c.HttpOnly
+		e.Expires = expires
+		e.UpdatedAt = now
+		j.changed[key] = true
+	}
+}
+
+// Cookies 返回发送到该地址的Cookie，路径长的在前
+func (j *persistentCookieJar) Cookies(u *url.URL) []*http.Cookie {
+	host := canonicalCookieHost(u.Hostname())
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now()
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	var selected []*jobs.CookieJarEntry
+	for key, e := range j.entries {
+		if e.Expires != nil && !e.Expires.After(now) {
+			delete(j.entries, key)
+			j.changed[key] = true
+			continue
+		}
+		if e.Secure && u.Scheme != "https" {
+			continue
+		}
+		if e.HostOnly && host != e.Domain || !e.HostOnly && !domainMatch(host, e.Domain) {
+			continue
+		}
+		if !pathMatch(path, e.Path) {
+			continue
+		}
+		selected = append(selected, e)
+	}
+	sort.Slice(selected, func(a, b int) bool {
+		if len(selected[a].Path) != len(selected[b].Path) {
+			return len(selected[a].Path) > len(selected[b].Path)
+		}
+		return selected[a].CreatedAt.Before(selected[b].CreatedAt)
+	})
+	cookies := make([]*http.Cookie, 0, len(selected))
+	for _, e := range selected {
+		cookies = append(cookies, &http.Cookie{Name: e.Name, Value: e.Value})
+	}
+	return cookies
+}
+
+// save 写回本次变更，返回变更数量
+func (j *persistentCookieJar) save() (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if len(j.changed) == 0 {
+		return 0, nil
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for key := range j.changed {
+			if e, ok := j.entries[key]; ok && e.ID != 0 {
+				err := tx.Model(&jobs.CookieJarEntry{}).Where("id = ?", e.ID).Updates(map[string]interface{}{
+					"value": e.Value, "host_only": e.HostOnly, "secure": e.Secure, "http_only": e.HttpOnly,
+					"expires": e.Expires, "updated_at": e.UpdatedAt,
+				}).Error
+				if err != nil {
+					return err
+				}
+				continue
+			} else if ok {
+				// 新Cookie：并发执行可能已写入同名Cookie，冲突时更新
+				err := tx.Clauses(clause.OnConflict{
+					Columns:   []clause.Column{{Name: "jar"}, {Name: "domain"}, {Name: "path"}, {Name: "name"}},
+					DoUpdates: clause.AssignmentColumns([]string{"value", "host_only", "secure", "http_only", "expires", "updated_at"}),
+				}).Create(e).Error
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			parts := strings.SplitN(key, ";", 3)
+			if err := tx.Where("jar = ? AND domain = ? AND path = ? AND name = ?", j.name, parts[0], parts[1], parts[2]).
+				Delete(&jobs.CookieJarEntry{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("保存Cookie罐失败: %v", err)
+	}
+	n := len(j.changed)
+	j.changed = make(map[string]bool)
+	return n, nil
+}
+
+func canonicalCookieHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// cookieDomain 校验 Domain 属性，返回存储的域名及是否仅限主机
+func cookieDomain(host, domain string) (string, bool, bool) {
+	domain = strings.TrimPrefix(canonicalCookieHost(domain), ".")
+	if domain == "" || domain == host {
+		return host, domain == "", true
+	}
+	// IP 地址只能设置为主机本身
+	if net.ParseIP(host) != nil {
+		return "", false, false
+	}
+	if !strings.HasSuffix(host, "."+domain) {
+		return "", false, false
+	}
+	// 不允许为公共后缀（如 com、co.uk）设置Cookie
+	if ps, _ := publicsuffix.PublicSuffix(domain); ps == domain {
+		return "", false, false
+	}
+	return domain, false, true
+}
+
+func domainMatch(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil
+}
+
+func pathMatch(reqPath, cookiePath string) bool {
+	if reqPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(reqPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || reqPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath 未指定 Path 时取请求路径的目录部分
+func defaultCookiePath(p string) string {
+	if p == "" || p[0] != '/' {
+		return "/"
+	}
+	i := strings.LastIndex(p, "/")
+	if i == 0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+// CookieJarSummary Cookie罐概览
+type CookieJarSummary struct {
+	Jar       string    `json:"jar"`
+	Count     int64     `json:"count"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CookieView Cookie罐内容（值已脱敏）
+type CookieView struct {
+	Domain    string     `json:"domain"`
+	Path      string     `json:"path"`
+	Name      string     `json:"name"`
+	Value     string     `json:"value"`
+	HostOnly  bool       `json:"host_only"`
+	Secure    bool       `json:"secure"`
+	HttpOnly  bool       `json:"http_only"`
+	Expires   *time.Time `json:"expires"` // 为空表示会话Cookie
+	Expired   bool       `json:"expired"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// ListCookieJars 列出所有Cookie罐
+func ListCookieJars() ([]CookieJarSummary, error) {
+	var rows []jobs.CookieJarEntry
+	if err := DB.Select("jar", "updated_at").Order("jar").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	var list []CookieJarSummary
+	for _, e := range rows {
+		if n := len(list); n > 0 && list[n-1].Jar == e.Jar {
+			list[n-1].Count++
+			if e.UpdatedAt.After(list[n-1].UpdatedAt) {
+				list[n-1].UpdatedAt = e.UpdatedAt
+			}
+			continue
+		}
+		list = append(list, CookieJarSummary{Jar: e.Jar, Count: 1, UpdatedAt: e.UpdatedAt})
+	}
+	return list, nil
+}
+
+// GetCookieJar 查看Cookie罐内容，值只保留前4个字符
+func GetCookieJar(name string) ([]CookieView, error) {
+	var rows []jobs.CookieJarEntry
+	if err := DB.Where("jar = ?", name).Order("domain, path, name").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	views := make([]CookieView, 0, len(rows))
+	for _, e := range rows {
+		views = append(views, CookieView{
+			Domain:    e.Domain,
+			Path:      e.Path,
+			Name:      e.Name,
+			Value:     maskCookieValue(e.Value),
+			HostOnly:  e.HostOnly,
+			Secure:    e.Secure,
+			HttpOnly:  e.HttpOnly,
+			Expires:   e.Expires,
+			Expired:   e.Expires != nil && !e.Expires.After(now),
+			UpdatedAt: e.UpdatedAt,
+		})
+	}
+	return views, nil
+}
+
+// ClearCookieJar 清空Cookie罐，cookieName 非空时只删除该名称的Cookie
+func ClearCookieJar(name, cookieName string) (int64, error) {
+	q := DB.Where("jar = ?", name)
+	if cookieName != "" {
+		q = q.Where("name = ?", cookieName)
+	}
+	res := q.Delete(&jobs.CookieJarEntry{})
+	return res.RowsAffected, res.Error
+}
+
+// CookieJarForJob 任务独立Cookie罐的名称
+func CookieJarForJob(jobID uint) string {
+	return cookieJarName(CookieJarPerJob, jobID)
+}
+
+func maskCookieValue(v string) string {
+	if len(v) <= 4 {
+		return strings.Repeat("*", len(v))
+	}
+	return v[:4] + "****"
+}
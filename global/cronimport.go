@@ -0,0 +1,636 @@
+package global
+
+import (
+	"fmt"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 遗留调度导入：将系统 crontab 与 systemd timer 转换为 command 任务
+// 时间字段统一转换为带秒的6段 cron 表达式，无法转换的条目在结果中逐条列出。
+
+// 遗留调度格式
+const (
+	LegacyFormatCrontab = "crontab" // 用户crontab：5个时间字段 + 命令
+	LegacyFormatSystem  = "system"  // /etc/crontab、/etc/cron.d：时间字段后多一个用户字段
+	LegacyFormatSystemd = "systemd" // *.timer 及其对应的 *.service
+)
+
+// LegacySource 待导入的文件
+type LegacySource struct {
+	Name    string `json:"name"`             // 文件名或路径，用于识别格式与生成任务名
+	Content string `json:"content"`          // 文件内容
+	Format  string `json:"format,omitempty"` // crontab/system/systemd，为空时按文件名识别
+}
+
+// LegacyEntry 已转换的条目
+type LegacyEntry struct {
+	Source   string `json:"source"`
+	Line     int    `json:"line,omitempty"`
+	Schedule string `json:"schedule"` // 原始时间定义
+	CronExpr string `json:"cron_expr"`
+	Name     string `json:"name"`
+	Command  string `json:"command"`
+}
+
+// LegacyIssue 无法转换或需要人工确认的条目
+type LegacyIssue struct {
+	Source string `json:"source"`
+	Line   int    `json:"line,omitempty"`
+	Entry  string `json:"entry"`
+	Level  string `json:"level"` // error 未导入；warning 已导入但行为可能不同
+	Reason string `json:"reason"`
+}
+
+// LegacyImportResult 遗留调度导入结果
+type LegacyImportResult struct {
+	Entries []LegacyEntry    `json:"entries"`
+	Issues  []LegacyIssue    `json:"issues"`
+	Import  *JobImportResult `json:"import,omitempty"`
+}
+
+// crontab 宏与等价的6段表达式
+var crontabMacros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// systemd OnCalendar 简写与等价的6段表达式
+var systemdCalendarShorthands = map[string]string{
+	"minutely":     "0 * * * * *",
+	"hourly":       "0 0 * * * *",
+	"daily":        "0 0 0 * * *",
+	"weekly":       "0 0 0 * * 1",
+	"monthly":      "0 0 0 1 * *",
+	"yearly":       "0 0 0 1 1 *",
+	"annually":     "0 0 0 1 1 *",
+	"quarterly":    "0 0 0 1 1,4,7,10 *",
+	"semiannually": "0 0 0 1 1,7 *",
+}
+
+var (
+	crontabEnvRe     = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*)\s*=\s*(.*)$`)
+	calendarFieldRe  = regexp.MustCompile(`^[0-9*,/\-]+$`)
+	crontabPercentRe = regexp.MustCompile(`(^|[^\\])%`)
+	leadingZeroRe    = regexp.MustCompile(`(^|[^0-9])0+([0-9])`)
+)
+
+// DetectLegacyFormat 按文件名识别格式
+func DetectLegacyFormat(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".timer"), strings.HasSuffix(lower, ".service"):
+		return LegacyFormatSystemd
+	case lower == "/etc/crontab", strings.Contains(filepath.ToSlash(lower), "/cron.d/"):
+		return LegacyFormatSystem
+	default:
+		return LegacyFormatCrontab
+	}
+}
+
+// getLegacyImportTimeout 导入任务的执行超时，crontab/systemd 本身没有超时
+func getLegacyImportTimeout(timeout int) int {
+	if timeout > 0 {
+		return timeout
+	}
+	return GetJobsConfigInt("jobs.import_timeout_seconds", 3600)
+}
+
+// ParseLegacySchedules 解析 crontab 与 systemd timer，返回转换后的条目、任务定义与问题列表
+func ParseLegacySchedules(sources []LegacySource, timeout int) ([]LegacyEntry, []JobSpec, []LegacyIssue) {
+	p := &legacyParser{timeout: getLegacyImportTimeout(timeout), names: make(map[string]bool)}
+	services := make(map[string]LegacySource)
+	var timers []LegacySource
+	for _, src := range sources {
+		format := strings.ToLower(strings.TrimSpace(src.Format))
+		if format == "" {
+			format = DetectLegacyFormat(src.Name)
+		}
+		switch format {
+		case LegacyFormatCrontab, LegacyFormatSystem:
+			p.parseCrontab(src, format == LegacyFormatSystem)
+		case LegacyFormatSystemd:
+			if strings.HasSuffix(strings.ToLower(src.Name), ".service") {
+				services[filepath.Base(src.Name)] = src
+			} else {
+				timers = append(timers, src)
+			}
+		default:
+			p.issue(src.Name, 0, "", "error", "不支持的格式: "+src.Format)
+		}
+	}
+	for _, timer := range timers {
+		p.parseSystemdTimer(timer, services)
+	}
+	return p.entries, p.specs, p.issues
+}
+
+// ImportLegacySchedules 解析并导入遗留调度，导入规则与批量导入相同
+func ImportLegacySchedules(sources []LegacySource, strategy string, dryRun bool, timeout int) (*LegacyImportResult, error) {
+	entries, specs, issues := ParseLegacySchedules(sources, timeout)
+	result := &LegacyImportResult{Entries: entries, Issues: issues}
+	if result.Entries == nil {
+		result.Entries = []LegacyEntry{}
+	}
+	if result.Issues == nil {
+		result.Issues = []LegacyIssue{}
+	}
+	if len(specs) == 0 {
+		return result, fmt.Errorf("没有可导入的条目")
+	}
+	imported, err := ImportJobs(specs, strategy, dryRun)
+	result.Import = imported
+	return result, err
+}
+
+// legacyParser 解析过程中的累积状态
+type legacyParser struct {
+	timeout int
+	names   map[string]bool
+	entries []LegacyEntry
+	specs   []JobSpec
+	issues  []LegacyIssue
+}
+
+func (p *legacyParser) issue(source string, line int, entry, level, reason string) {
+	p.issues = append(p.issues, LegacyIssue{Source: source, Line: line, Entry: entry, Level: level, Reason: reason})
+}
+
+// add 生成任务定义，同名时追加序号
+func (p *legacyParser) add(entry LegacyEntry, desc, format string, env []string, workdir string) {
+	name := entry.Name
+	for n := 2; p.names[name]; n++ {
+		name = fmt.Sprintf("%s#%d", entry.Name, n)
+	}
+	p.names[name] = true
+	entry.Name = name
+
+	lines := []string{"【command】" + entry.Command}
+	if workdir != "" {
+		lines = append(lines, "【workdir】"+workdir)
+	}
+	if len(env) > 0 {
+		lines = append(lines, "【env】"+strings.Join(env, "|||"))
+	}
+	lines = append(lines, fmt.Sprintf("【timeout】%d", p.timeout))
+	p.entries = append(p.entries, entry)
+	p.specs = append(p.specs, JobSpec{
+		Name:     name,
+		Desc:     desc,
+		Tags:     []string{"imported", format},
+		CronExpr: entry.CronExpr,
+		Mode:     "command",
+		Command:  strings.Join(lines, "\n"),
+	})
+}
+
+// legacyJobName 由来源与命令生成任务名
+func legacyJobName(source string, line int, command string) string {
+	short := []rune(strings.Join(strings.Fields(command), " "))
+	if len(short) > 40 {
+		short = append(short[:40], '…')
+	}
+	if line > 0 {
+		return fmt.Sprintf("%s:%d %s", filepath.Base(source), line, string(short))
+	}
+	return fmt.Sprintf("%s %s", filepath.Base(source), string(short))
+}
+
+// cutFields 切出前n个空白分隔的字段，返回剩余部分（保留原始空白）
+func cutFields(s string, n int) ([]string, string) {
+	fields := make([]string, 0, n)
+	rest := strings.TrimLeft(s, " \t")
+	for len(fields) < n && rest != "" {
+		end := strings.IndexAny(rest, " \t")
+		if end < 0 {
+			fields = append(fields, rest)
+			rest = ""
+			break
+		}
+		fields = append(fields, rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t")
+	}
+	return fields, rest
+}
+
+// unquoteEnvValue 去掉环境变量值两侧的引号
+func unquoteEnvValue(v string) string {
+	v = strings.TrimSpace(v)
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+		return v[1 : len(v)-1]
+	}
+	return v
+}
+
+// parseCrontab 解析 crontab，system 为 true 时时间字段后为用户名
+func (p *legacyParser) parseCrontab(src LegacySource, system bool) {
+	format := LegacyFormatCrontab
+	if system {
+		format = LegacyFormatSystem
+	}
+	currentUser := ""
+	if u, err := user.Current(); err == nil {
+		currentUser = u.Username
+	}
+	var env []string
+	mailto, tz := "", ""
+	mailtoReported := false
+	for i, rawLine := range strings.Split(src.Content, "\n") {
+		lineNo := i + 1
+		line := strings.TrimSpace(rawLine)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := crontabEnvRe.FindStringSubmatch(line); m != nil && !strings.HasPrefix(line, "@") {
+			name, value := m[1], unquoteEnvValue(m[2])
+			switch name {
+			case "MAILTO":
+				mailto = value
+				mailtoReported = false
+			case "CRON_TZ", "TZ":
+				if _, err := time.LoadLocation(value); err != nil {
+					p.issue(src.Name, lineNo, line, "error", "未知时区，后续条目按服务时区调度: "+value)
+					tz = ""
+				} else {
+					tz = value
+				}
+			case "SHELL":
+				if base := filepath.Base(value); base != "sh" && base != "bash" {
+					p.issue(src.Name, lineNo, line, "warning", "命令固定由 bash 执行，SHELL 设置不生效")
+				}
+			default:
+				env = append(env, name+"="+value)
+			}
+			continue
+		}
+
+		var schedule, expr string
+		var rest string
+		if strings.HasPrefix(line, "@") {
+			fields, r := cutFields(line, 1)
+			schedule, rest = fields[0], r
+			if strings.EqualFold(schedule, "@reboot") {
+				p.issue(src.Name, lineNo, line, "error", "@reboot 在系统启动时执行，无法转换为定时计划")
+				continue
+			}
+			e, ok := crontabMacros[strings.ToLower(schedule)]
+			if !ok {
+				p.issue(src.Name, lineNo, line, "error", "未知的宏: "+schedule)
+				continue
+			}
+			expr = e
+		} else {
+			fields, r := cutFields(line, 5)
+			if len(fields) < 5 || r == "" {
+				p.issue(src.Name, lineNo, line, "error", "字段不足，应为5个时间字段加命令")
+				continue
+			}
+			schedule, rest = strings.Join(fields, " "), r
+			fields[4] = normalizeCrontabDow(fields[4])
+			expr = "0 " + strings.Join(fields, " ")
+		}
+
+		runAs := ""
+		if system {
+			fields, r := cutFields(rest, 1)
+			if len(fields) == 0 || r == "" {
+				p.issue(src.Name, lineNo, line, "error", "缺少用户字段或命令")
+				continue
+			}
+			runAs, rest = fields[0], r
+		}
+		command := strings.TrimSpace(rest)
+		if crontabPercentRe.MatchString(command) {
+			p.issue(src.Name, lineNo, line, "error", "命令包含未转义的 %（crontab 中表示换行与标准输入），请手工转换")
+			continue
+		}
+		command = strings.ReplaceAll(command, `\%`, "%")
+		if tz != "" {
+			expr = "CRON_TZ=" + tz + " " + expr
+		}
+		if err := CronExprCheck(expr); err != nil {
+			p.issue(src.Name, lineNo, line, "error", "时间字段无法转换: "+err.Error())
+			continue
+		}
+
+		desc := fmt.Sprintf("从 %s 第%d行导入，原计划：%s", src.Name, lineNo, schedule)
+		if runAs != "" {
+			desc += "，原执行用户：" + runAs
+			if runAs != currentUser {
+				p.issue(src.Name, lineNo, line, "warning", "原以用户 "+runAs+" 执行，导入后以服务进程用户执行")
+			}
+		}
+		if mailto != "" {
+			desc += "，MAILTO：" + mailto
+			if !mailtoReported {
+				p.issue(src.Name, lineNo, "MAILTO="+mailto, "warning", "MAILTO 未转换，请为导入的任务配置通知规则（notify）")
+				mailtoReported = true
+			}
+		}
+		p.add(LegacyEntry{
+			Source:   src.Name,
+			Line:     lineNo,
+			Schedule: schedule,
+			CronExpr: expr,
+			Name:     legacyJobName(src.Name, lineNo, command),
+			Command:  command,
+		}, desc, format, append([]string(nil), env...), "")
+	}
+}
+
+// normalizeCrontabDow crontab 中周日可写作7，cron 表达式只接受0
+func normalizeCrontabDow(field string) string {
+	parts := strings.Split(field, ",")
+	for i, part := range parts {
+		switch {
+		case part == "7":
+			parts[i] = "0"
+		case strings.HasSuffix(part, "-7"):
+			parts[i] = strings.TrimSuffix(part, "-7") + "-6,0"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// unitFile systemd 单元文件中的键值（按节区分，同名键保留多个值）
+type unitFile map[string]map[string][]string
+
+// parseUnitFile 解析 systemd 单元文件，支持行尾反斜杠续行
+func parseUnitFile(content string) unitFile {
+	unit := make(unitFile)
+	section := ""
+	var pending string
+	for _, raw := range strings.Split(content, "\n") {
+		line := strings.TrimSpace(raw)
+		if pending != "" {
+			line = pending + " " + line
+			pending = ""
+		}
+		if strings.HasSuffix(line, "\\") {
+			pending = strings.TrimSuffix(line, "\\")
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[]")
+			if unit[section] == nil {
+				unit[section] = make(map[string][]string)
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section == "" {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if value == "" {
+			// 空值表示重置之前的设置
+			delete(unit[section], key)
+			continue
+		}
+		unit[section][key] = append(unit[section][key], value)
+	}
+	return unit
+}
+
+// parseSystemdTimer 解析 timer 单元及其触发的 service 单元
+func (p *legacyParser) parseSystemdTimer(src LegacySource, services map[string]LegacySource) {
+	unit := parseUnitFile(src.Content)
+	timer := unit["Timer"]
+	if timer == nil {
+		p.issue(src.Name, 0, "", "error", "缺少 [Timer] 节")
+		return
+	}
+	for _, key := range []string{"OnActiveSec", "OnBootSec", "OnStartupSec", "OnUnitActiveSec", "OnUnitInactiveSec"} {
+		for _, v := range timer[key] {
+			p.issue(src.Name, 0, key+"="+v, "error", "单调定时器（相对启动/上次运行的时间）无法转换为 cron 计划")
+		}
+	}
+	for _, key := range []string{"RandomizedDelaySec", "AccuracySec", "Persistent", "WakeSystem"} {
+		for _, v := range timer[key] {
+			p.issue(src.Name, 0, key+"="+v, "warning", key+" 不支持，已忽略")
+		}
+	}
+	calendars := timer["OnCalendar"]
+	if len(calendars) == 0 {
+		return
+	}
+
+	base := strings.TrimSuffix(filepath.Base(src.Name), filepath.Ext(src.Name))
+	serviceName := base + ".service"
+	if v := timer["Unit"]; len(v) > 0 {
+		serviceName = v[len(v)-1]
+	}
+	svcSrc, ok := services[serviceName]
+	if !ok {
+		p.issue(src.Name, 0, serviceName, "error", "缺少对应的 service 单元文件，请一并提供")
+		return
+	}
+	svc := parseUnitFile(svcSrc.Content)["Service"]
+	command, env, workdir, ok := p.systemdServiceCommand(svcSrc.Name, svc)
+	if !ok {
+		return
+	}
+
+	for _, cal := range calendars {
+		expr, err := convertOnCalendar(cal)
+		if err == nil {
+			err = CronExprCheck(expr)
+		}
+		if err != nil {
+			p.issue(src.Name, 0, "OnCalendar="+cal, "error", "时间定义无法转换: "+err.Error())
+			continue
+		}
+		p.add(LegacyEntry{
+			Source:   src.Name,
+			Schedule: cal,
+			CronExpr: expr,
+			Name:     base + ".timer",
+			Command:  command,
+		}, fmt.Sprintf("从 %s 导入，原计划：OnCalendar=%s，执行单元：%s", src.Name, cal, serviceName),
+			LegacyFormatSystemd, env, workdir)
+	}
+}
+
+// systemdServiceCommand 由 [Service] 生成命令、环境变量与工作目录
+func (p *legacyParser) systemdServiceCommand(source string, svc map[string][]string) (string, []string, string, bool) {
+	if svc == nil || len(svc["ExecStart"]) == 0 {
+		p.issue(source, 0, "", "error", "缺少 ExecStart")
+		return "", nil, "", false
+	}
+	var cmds []string
+	for _, exec := range svc["ExecStart"] {
+		ignoreFailure := false
+		// 去掉 systemd 的命令前缀：- 忽略失败，@ + ! !! : 与权限/参数相关
+		for len(exec) > 0 && strings.ContainsRune("-@+!:", rune(exec[0])) {
+			if exec[0] == '-' {
+				ignoreFailure = true
+			}
+			exec = exec[1:]
+		}
+		exec = strings.TrimSpace(exec)
+		if strings.Contains(strings.ReplaceAll(exec, "%%", ""), "%") {
+			p.issue(source, 0, "ExecStart="+exec, "error", "命令包含 systemd 说明符（%n、%i 等），请手工转换")
+			return "", nil, "", false
+		}
+		exec = strings.ReplaceAll(exec, "%%", "%")
+		if ignoreFailure {
+			exec = "(" + exec + ") || true"
+		}
+		cmds = append(cmds, exec)
+	}
+
+	var env []string
+	for _, line := range svc["Environment"] {
+		for _, pair := range splitSystemdWords(line) {
+			if strings.Contains(pair, "=") {
+				env = append(env, pair)
+			}
+		}
+	}
+	for _, key := range []string{"EnvironmentFile", "User", "Group", "ExecStartPre", "ExecStartPost", "TimeoutStartSec"} {
+		for _, v := range svc[key] {
+			p.issue(source, 0, key+"="+v, "warning", key+" 未转换，请确认是否需要手工处理")
+		}
+	}
+	workdir := ""
+	if v := svc["WorkingDirectory"]; len(v) > 0 {
+		workdir = strings.TrimPrefix(v[len(v)-1], "-")
+	}
+	return strings.Join(cmds, " && "), env, workdir, true
+}
+
+// splitSystemdWords 按空白拆分，支持双引号包裹
+func splitSystemdWords(s string) []string {
+	var words []string
+	var cur strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t') && !quoted:
+			if cur.Len() > 0 {
+				words = append(words, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		words = append(words, cur.String())
+	}
+	return words
+}
+
+// convertOnCalendar 将 systemd OnCalendar 转换为6段 cron 表达式
+// 支持格式：[星期] [*-]月-日 时:分[:秒] [时区]，以及 daily/weekly 等简写
+func convertOnCalendar(spec string) (string, error) {
+	spec = strings.TrimSpace(spec)
+	if expr, ok := systemdCalendarShorthands[strings.ToLower(spec)]; ok {
+		return expr, nil
+	}
+	tokens := strings.Fields(spec)
+	dow := "*"
+	if len(tokens) > 0 && isCalendarWeekday(tokens[0]) {
+		v, err := convertCalendarWeekdays(tokens[0])
+		if err != nil {
+			return "", err
+		}
+		dow = v
+		tokens = tokens[1:]
+	}
+	tz := ""
+	if n := len(tokens); n > 0 && !strings.ContainsAny(tokens[n-1], ":-*") {
+		if _, err := time.LoadLocation(tokens[n-1]); err != nil {
+			return "", fmt.Errorf("无法识别: %s", tokens[n-1])
+		}
+		tz = tokens[n-1]
+		tokens = tokens[:n-1]
+	}
+	date, clock := "*-*-*", "00:00:00"
+	for _, tok := range tokens {
+		switch {
+		case strings.Contains(tok, ":"):
+			clock = tok
+		case strings.Contains(tok, "-"):
+			date = tok
+		default:
+			return "", fmt.Errorf("无法识别: %s", tok)
+		}
+	}
+
+	dateParts := strings.Split(date, "-")
+	if len(dateParts) == 2 {
+		dateParts = append([]string{"*"}, dateParts...)
+	}
+	if len(dateParts) != 3 {
+		return "", fmt.Errorf("日期格式错误: %s", date)
+	}
+	if dateParts[0] != "*" {
+		return "", fmt.Errorf("不支持指定年份: %s", dateParts[0])
+	}
+	if strings.Contains(dateParts[2], "~") {
+		return "", fmt.Errorf("不支持按月末倒数的日期: %s", date)
+	}
+	clockParts := strings.Split(clock, ":")
+	if len(clockParts) == 2 {
+		clockParts = append(clockParts, "00")
+	}
+	if len(clockParts) != 3 {
+		return "", fmt.Errorf("时间格式错误: %s", clock)
+	}
+	if strings.Contains(clockParts[2], ".") {
+		return "", fmt.Errorf("不支持小数秒: %s", clockParts[2])
+	}
+
+	fields := []string{clockParts[2], clockParts[1], clockParts[0], dateParts[2], dateParts[1]}
+	for i, f := range fields {
+		f = strings.ReplaceAll(f, "..", "-")
+		if !calendarFieldRe.MatchString(f) {
+			return "", fmt.Errorf("字段格式错误: %s", f)
+		}
+		fields[i] = leadingZeroRe.ReplaceAllString(f, "$1$2")
+	}
+	expr := strings.Join(append(fields, dow), " ")
+	if tz != "" {
+		expr = "CRON_TZ=" + tz + " " + expr
+	}
+	return expr, nil
+}
+
+// isCalendarWeekday 判断是否为星期字段（以字母开头）
+func isCalendarWeekday(tok string) bool {
+	c := tok[0]
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// convertCalendarWeekdays 将 Mon..Fri,Sun 转换为 mon-fri,sun
+func convertCalendarWeekdays(tok string) (string, error) {
+	var parts []string
+	for _, item := range strings.Split(tok, ",") {
+		var names []string
+		for _, day := range strings.Split(item, "..") {
+			if len(day) < 3 {
+				return "", fmt.Errorf("无法识别的星期: %s", day)
+			}
+			names = append(names, strings.ToLower(day[:3]))
+		}
+		if len(names) > 2 {
+			return "", fmt.Errorf("无法识别的星期: %s", item)
+		}
+		parts = append(parts, strings.Join(names, "-"))
+	}
+	return strings.Join(parts, ","), nil
+}
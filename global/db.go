@@ -351,6 +351,8 @@ func autoMigrate() error {
 	// 迁移所有模型
 	err := DB.AutoMigrate(
 		&jobs.Jobs{},
+		&jobs.JobExec{},
+		&jobs.CookieJarEntry{},
 		&admins.Admin{},
 	)
 
@@ -0,0 +1,112 @@
+package global
+
+import (
+	"sync/atomic"
+	"time"
+
+	"xiaohuAdmin/models/jobs"
+)
+
+// 排空模式：服务停止时不再接受新的调度触发与手动执行，等待在途执行结束
+var draining atomic.Bool
+
+// IsDraining 是否处于排空（停止中）状态
+func IsDraining() bool {
+	return draining.Load()
+}
+
+// getDrainTimeout 排空等待时长
+func getDrainTimeout() time.Duration {
+	return time.Duration(GetJobsConfigInt("jobs.drain_timeout_seconds", 30)) * time.Second
+}
+
+// getDrainKillGrace 取消剩余执行后的等待时长（jobs.drain_kill_grace_seconds，默认5秒）
+func getDrainKillGrace() time.Duration {
+	return time.Duration(GetJobsConfigInt("jobs.drain_kill_grace_seconds", 5)) * time.Second
+}
+
+// waitExecs 等待在途执行结束，超时返回 false
+func waitExecs(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if len(ListRunningExecs(0)) == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// DrainJobs 排空：停止调度器并等待所有来源（调度/手动/探测）的在途执行，
+// 超过 jobs.drain_timeout_seconds 后取消剩余执行，并将其记录为 interrupted
+func DrainJobs() {
+	// 与 registerExecUnlessDraining 同一把锁：此后不再登记新的手动执行
+	execMu.Lock()
+	draining.Store(true)
+	execMu.Unlock()
+	timeout := getDrainTimeout()
+	if ZapLog != nil {
+		ZapLog.Warn("进入排空模式，等待在途执行结束",
+			LogField("running", len(ListRunningExecs(0))),
+			LogField("timeout", timeout.String()))
+	}
+	// 停止调度器（不再等待，统一由执行登记判断在途执行）
+	if Timer != nil {
+		Timer.Stop()
+	}
+	runningMu.Lock()
+	TimerRunning = false
+	runningMu.Unlock()
+	if waitExecs(timeout) {
+		return
+	}
+
+	remaining := ListRunningExecs(0)
+	execMu.Lock()
+	for _, re := range runningExecs {
+		re.interrupted = true
+		re.cancel()
+	}
+	execMu.Unlock()
+	if ZapLog != nil {
+		ZapLog.Warn("排空超时，已取消剩余执行", LogField("count", len(remaining)))
+	}
+	// 给被取消的执行留出写日志与登记的时间；仍未结束的将在下次启动时标记为中断
+	waitExecs(getDrainKillGrace())
+}
+
+// RequeueInterruptedExecs 启动时重新执行上次被中断的任务（jobs.drain_requeue 开启时）
+// 每个任务只补跑一次，按定时触发的规则执行（并发策略、执行次数上限、熔断暂停），已停止/心跳任务不补跑
+func RequeueInterruptedExecs() {
+	if DB == nil || !GetJobsConfigBool("jobs.drain_requeue", false) {
+		return
+	}
+	var execs []jobs.JobExec
+	if err := DB.Where("status=? AND requeued=?", ExecStatusInterrupted, false).Find(&execs).Error; err != nil {
+		if ZapLog != nil {
+			ZapLog.Error("查询中断执行失败", LogError(err))
+		}
+		return
+	}
+	seen := make(map[uint]bool)
+	for _, e := range execs {
+		DB.Model(&jobs.JobExec{}).Where("id=?", e.ID).Update("requeued", true)
+		if seen[e.JobID] {
+			continue
+		}
+		seen[e.JobID] = true
+		var job Jobs
+		if err := DB.First(&job, e.JobID).Error; err != nil {
+			continue
+		}
+		if job.State == 2 || job.Mode == "heartbeat" {
+			continue
+		}
+		if ZapLog != nil {
+			ZapLog.Info("补跑中断的任务", LogField("job_id", job.ID), LogField("interrupted_exec_id", e.ExecID))
+		}
+		go runJobWithPolicy(&job, "requeue")
+	}
+}
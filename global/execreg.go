@@ -0,0 +1,207 @@
+package global
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"xiaohuAdmin/models/jobs"
+)
+
+// 执行状态
+const (
+	ExecStatusRunning     = "running"
+	ExecStatusSuccess     = "success"
+	ExecStatusFailed      = "failed"
+	ExecStatusWarning     = "warning" // 成功但退出码标记为警告
+	ExecStatusInterrupted = "interrupted"
+)
+
+// RunningExec 正在执行的任务实例
+type RunningExec struct {
+	ExecID    string    `json:"exec_id"`
+	JobID     uint      `json:"job_id"`
+	JobName   string    `json:"job_name"`
+	Mode      string    `json:"mode"`
+	Source    string    `json:"source"`
+	StartedAt time.Time `json:"started_at"`
+	Pid       int       `json:"pid"`
+	Attempt   int       `json:"attempt"`
+
+	cancel      context.CancelFunc
+	interrupted bool // 排空超时被取消
+}
+
+type execCtxKey struct{}
+
+var (
+	execMu       sync.RWMutex
+	runningExecs = make(map[string]*RunningExec)
+)
+
+// registerExec 登记一次执行（内存 + 数据库），返回带执行信息的可取消上下文
+func registerExec(parent context.Context, job *Jobs, execID, source string) (context.Context, *RunningExec) {
+	ctx, re, _ := addExec(parent, job, execID, source, false)
+	return ctx, re
+}
+
+// registerExecUnlessDraining 登记一次执行，排空开始后不再登记；
+// 与 DrainJobs 在同一把锁下判断，登记成功的执行一定会被排空等待
+func registerExecUnlessDraining(parent context.Context, job *Jobs, execID, source string) (context.Context, *RunningExec, bool) {
+	return addExec(parent, job, execID, source, true)
+}
+
+func addExec(parent context.Context, job *Jobs, execID, source string, rejectDraining bool) (context.Context, *RunningExec, bool) {
+	ctx, cancel := context.WithCancel(parent)
+	re := &RunningExec{
+		ExecID:    execID,
+		JobID:     job.ID,
+		JobName:   job.Name,
+		Mode:      job.Mode,
+		Source:    source,
+		StartedAt: time.Now(),
+		cancel:    cancel,
+	}
+	execMu.Lock()
+	if rejectDraining && draining.Load() {
+		execMu.Unlock()
+		cancel()
+		return nil, nil, false
+	}
+	runningExecs[execID] = re
+	execMu.Unlock()
+	MetricsIncRunning()
+
+	if DB != nil {
+		rec := jobs.JobExec{
+			ExecID:    execID,
+			JobID:     job.ID,
+			JobName:   job.Name,
+			Mode:      job.Mode,
+			Source:    source,
+			Status:    ExecStatusRunning,
+			StartedAt: re.StartedAt,
+		}
+		if err := DB.Create(&rec).Error; err != nil && ZapLog != nil {
+			ZapLog.Warn("写入执行登记失败", LogField("exec_id", execID), LogError(err))
+		}
+	}
+	return context.WithValue(ctx, execCtxKey{}, re), re, true
+}
+
+// isInterrupted 执行是否因排空超时被取消
+func (re *RunningExec) isInterrupted() bool {
+	execMu.RLock()
+	defer execMu.RUnlock()
+	return re.interrupted
+}
+
+// finishExec 结束登记，更新数据库状态
+func finishExec(re *RunningExec, status string) {
+	if re == nil {
+		return
+	}
+	re.cancel()
+	execMu.Lock()
+	delete(runningExecs, re.ExecID)
+	execMu.Unlock()
+	MetricsDecRunning()
+
+	if DB != nil {
+		now := time.Now()
+		err := DB.Model(&jobs.JobExec{}).Where("exec_id=?", re.ExecID).Updates(map[string]interface{}{
+			"status":      status,
+			"finished_at": &now,
+		}).Error
+		if err != nil && ZapLog != nil {
+			ZapLog.Warn("更新执行登记失败", LogField("exec_id", re.ExecID), LogError(err))
+		}
+	}
+}
+
+// execFromContext 获取上下文中的执行实例（可能为 nil）
+func execFromContext(ctx context.Context) *RunningExec {
+	re, _ := ctx.Value(execCtxKey{}).(*RunningExec)
+	return re
+}
+
+// setExecPid 记录命令进程ID
+func setExecPid(ctx context.Context, pid int) {
+	re := execFromContext(ctx)
+	if re == nil {
+		return
+	}
+	execMu.Lock()
+	re.Pid = pid
+	execMu.Unlock()
+	if DB != nil {
+		DB.Model(&jobs.JobExec{}).Where("exec_id=?", re.ExecID).Update("pid", pid)
+	}
+}
+
+// setExecAttempt 记录当前尝试次数
+func setExecAttempt(ctx context.Context, attempt int) {
+	re := execFromContext(ctx)
+	if re == nil {
+		return
+	}
+	execMu.Lock()
+	re.Attempt = attempt
+	execMu.Unlock()
+	if DB != nil {
+		DB.Model(&jobs.JobExec{}).Where("exec_id=?", re.ExecID).Update("attempt", attempt)
+	}
+}
+
+// ListRunningExecs 列出正在执行的任务实例，jobID 为 0 时返回全部
+func ListRunningExecs(jobID uint) []RunningExec {
+	execMu.RLock()
+	list := make([]RunningExec, 0, len(runningExecs))
+	for _, re := range runningExecs {
+		if jobID == 0 || re.JobID == jobID {
+			list = append(list, *re)
+		}
+	}
+	execMu.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].StartedAt.Before(list[j].StartedAt) })
+	return list
+}
+
+// RunningCount 任务当前正在执行的实例数
+func RunningCount(jobID uint) int {
+	execMu.RLock()
+	defer execMu.RUnlock()
+	n := 0
+	for _, re := range runningExecs {
+		if re.JobID == jobID {
+			n++
+		}
+	}
+	return n
+}
+
+// SweepOrphanExecs 启动时清理：上次进程遗留的 running 记录标记为 interrupted，
+// 旧版本写入的 state=1（执行中）恢复为启用，并清理过期的执行登记
+func SweepOrphanExecs() {
+	if DB == nil {
+		return
+	}
+	now := time.Now()
+	res := DB.Model(&jobs.JobExec{}).Where("status=?", ExecStatusRunning).Updates(map[string]interface{}{
+		"status":      ExecStatusInterrupted,
+		"finished_at": &now,
+	})
+	if res.Error != nil {
+		if ZapLog != nil {
+			ZapLog.Error("标记中断执行失败", LogError(res.Error))
+		}
+	} else if res.RowsAffected > 0 && ZapLog != nil {
+		ZapLog.Warn("已将遗留的执行记录标记为中断", LogField("count", res.RowsAffected))
+	}
+
+	DB.Model(&jobs.Jobs{}).Where("state=?", 1).Update("state", 0)
+
+	keepDays := GetJobsConfigInt("jobs.exec_record_keep_days", 7)
+	DB.Where("status<>? AND started_at<?", ExecStatusRunning, now.AddDate(0, 0, -keepDays)).Delete(&jobs.JobExec{})
+}
@@ -0,0 +1,66 @@
+package global
+
+import (
+	"testing"
+	"time"
+
+	"xiaohuAdmin/models/jobs"
+)
+
+func TestSweepOrphanExecs(t *testing.T) {
+	newTestDB(t)
+	legacy := createTestJob(t, &Jobs{Name: "legacy-running", CronExpr: "* * * * *", Mode: "command", State: 1})
+	stopped := createTestJob(t, &Jobs{Name: "stopped", CronExpr: "* * * * *", Mode: "command", State: 2})
+	suspended := createTestJob(t, &Jobs{Name: "suspended", CronExpr: "* * * * *", Mode: "command", State: JobStateSuspended})
+
+	now := time.Now()
+	finished := now.Add(-time.Minute)
+	old := now.AddDate(0, 0, -10)
+	for _, e := range []jobs.JobExec{
+		{ExecID: "orphan-1", JobID: legacy.ID, Status: ExecStatusRunning, StartedAt: now.Add(-time.Hour)},
+		{ExecID: "orphan-2", JobID: stopped.ID, Status: ExecStatusRunning, StartedAt: now.Add(-time.Minute)},
+		{ExecID: "done", JobID: legacy.ID, Status: ExecStatusSuccess, StartedAt: finished, FinishedAt: &finished},
+		{ExecID: "expired", JobID: legacy.ID, Status: ExecStatusFailed, StartedAt: old, FinishedAt: &old},
+	} {
+		if err := DB.Create(&e).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	SweepOrphanExecs()
+
+	statuses := make(map[string]jobs.JobExec)
+	var execs []jobs.JobExec
+	DB.Find(&execs)
+	for _, e := range execs {
+		statuses[e.ExecID] = e
+	}
+	for _, id := range []string{"orphan-1", "orphan-2"} {
+		e, ok := statuses[id]
+		if !ok || e.Status != ExecStatusInterrupted || e.FinishedAt == nil {
+			t.Fatalf("%s 应标记为 interrupted 并记录结束时间: %+v", id, e)
+		}
+	}
+	if e := statuses["done"]; e.Status != ExecStatusSuccess || !e.FinishedAt.Equal(finished) {
+		t.Fatalf("已结束的记录不应改变: %+v", e)
+	}
+	if _, ok := statuses["expired"]; ok {
+		t.Fatal("超过 jobs.exec_record_keep_days 的记录应被清理")
+	}
+
+	// 旧版本遗留的 state=1 重置为等待，其余状态不变
+	for job, want := range map[*Jobs]int{legacy: 0, stopped: 2, suspended: JobStateSuspended} {
+		if cur := reloadTestJob(t, job.ID); cur.State != want {
+			t.Fatalf("%s 状态应为 %d，实际 %d", job.Name, want, cur.State)
+		}
+	}
+
+	// 再次扫描不会改动已中断的记录
+	interruptedAt := *statuses["orphan-1"].FinishedAt
+	SweepOrphanExecs()
+	var e jobs.JobExec
+	DB.Where("exec_id=?", "orphan-1").First(&e)
+	if e.Status != ExecStatusInterrupted || !e.FinishedAt.Equal(interruptedAt) {
+		t.Fatalf("重复扫描不应改动已中断的记录: %+v", e)
+	}
+}
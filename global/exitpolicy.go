@@ -0,0 +1,241 @@
+package global
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 命令/脚本任务的退出码语义
+//
+//	【success_codes】0,1          视为成功的退出码，默认 0
+//	【warning_codes】24           执行成功但标记为警告（如 rsync 的部分文件消失）
+//	【retry_codes】75,111         可重试的退出码，按【interval】间隔重新执行（未配置时指数退避），最多【retries】次（默认3次）
+//	【retries】5
+//	【stderr_fail】(?i)error|fatal  stderr 匹配该正则时判定失败（可多行，任一匹配即失败）
+//
+// 退出码支持区间写法，如 8-16。警告执行的聚合日志状态为“警告”，执行登记状态为 warning，
+// 计入 jobs_exec_warning_total 指标并发送 warning 通知事件；熔断与恢复判断视同成功。
+
+// 退出码区间展开的上限，避免 0-99999999 之类的配置
+const maxExitCodeListSize = 1024
+
+// 未配置【interval】时重试的指数退避上限
+const maxRetryBackoff = 30 * time.Second
+
+// ExitPolicy 退出码判定规则（嵌入命令与脚本配置，JSON字段与标签同名）
+type ExitPolicy struct {
+	SuccessCodes []int    `json:"success_codes,omitempty"` // 视为成功的退出码，为空时只有 0
+	WarningCodes []int    `json:"warning_codes,omitempty"` // 成功但标记警告的退出码
+	RetryCodes   []int    `json:"retry_codes,omitempty"`   // 可重试的退出码
+	Retries      int      `json:"retries,omitempty"`       // 最多重试次数，0 时默认3次
+	StderrFail   []string `json:"stderr_fail,omitempty"`   // stderr 匹配即失败的正则
+}
+
+// exitOutcome 单次执行的判定结果
+type exitOutcome int
+
+const (
+	exitSucceeded exitOutcome = iota
+	exitWarning
+	exitRetryable
+	exitFailed
+)
+
+// parseExitCodes 解析退出码列表，支持逗号分隔与 a-b 区间
+func parseExitCodes(value string) ([]int, error) {
+	var codes []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi := part, part
+		// 允许负数退出码（Windows），区间分隔符为第一个数字之后的 -
+		if i := strings.Index(part[1:], "-"); i >= 0 {
+			lo, hi = strings.TrimSpace(part[:i+1]), strings.TrimSpace(part[i+2:])
+		}
+		from, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, fmt.Errorf("退出码应为整数或区间，当前为 %q", part)
+		}
+		to, err := strconv.Atoi(hi)
+		if err != nil {
+			return nil, fmt.Errorf("退出码应为整数或区间，当前为 %q", part)
+		}
+		if to < from {
+			return nil, fmt.Errorf("退出码区间 %q 起点大于终点", part)
+		}
+		for c := from; c <= to; c++ {
+			if len(codes) >= maxExitCodeListSize {
+				return nil, fmt.Errorf("退出码列表超过 %d 个", maxExitCodeListSize)
+			}
+			if !seen[c] {
+				seen[c] = true
+				codes = append(codes, c)
+			}
+		}
+	}
+	sort.Ints(codes)
+	return codes, nil
+}
+
+// parseExitPolicyTag 解析退出码相关标签，不是这些标签时返回 false
+func parseExitPolicyTag(p *ExitPolicy, tag, value string) bool {
+	switch tag {
+	case "success_codes", "warning_codes", "retry_codes":
+		codes, err := parseExitCodes(value)
+		if err != nil {
+			return true
+		}
+		switch tag {
+		case "success_codes":
+			p.SuccessCodes = codes
+		case "warning_codes":
+			p.WarningCodes = codes
+		case "retry_codes":
+			p.RetryCodes = codes
+		}
+	case "retries":
+		if n, err := strconv.Atoi(value); err == nil {
+			p.Retries = n
+		}
+	case "stderr_fail":
+		if value != "" {
+			p.StderrFail = append(p.StderrFail, value)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+func containsCode(codes []int, code int) bool {
+	for _, c := range codes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// retryLimit 可重试退出码的最多重试次数
+func (p *ExitPolicy) retryLimit() int {
+	if len(p.RetryCodes) == 0 {
+		return 0
+	}
+	if p.Retries > 0 {
+		return p.Retries
+	}
+	return 3
+}
+
+// retryDelay 第 retry 次重试前的等待时间：配置了【interval】时固定间隔，否则从1秒起指数退避，最长30秒
+func retryDelay(retry, interval int) time.Duration {
+	if interval > 0 {
+		return time.Duration(interval) * time.Second
+	}
+	if retry >= 5 {
+		return maxRetryBackoff
+	}
+	if d := time.Second << retry; d < maxRetryBackoff {
+		return d
+	}
+	return maxRetryBackoff
+}
+
+// waitRetry 判断本次执行是否需要重试，需要时等待后返回 true 与重试说明（写入输出）
+// 第 retry 次执行（从0开始）已达重试上限或 ctx 结束时返回 false
+func (p *ExitPolicy) waitRetry(ctx context.Context, retry, interval, exitCode int, stderr string, err error) (bool, string) {
+	outcome, reason := p.classify(exitCode, stderr, err)
+	if outcome != exitRetryable || retry >= p.retryLimit() {
+		return false, ""
+	}
+	delay := retryDelay(retry, interval)
+	note := fmt.Sprintf("\n[重试] %s，%s后第 %d/%d 次重试\n", reason, delay, retry+1, p.retryLimit())
+	if !sleepContext(ctx, delay) {
+		return false, note
+	}
+	return true, note
+}
+
+// classify 判定单次执行结果，返回结果与原因（成功时为空）
+// err 为进程启动失败、超时等非退出码错误时直接判定失败
+func (p *ExitPolicy) classify(exitCode int, stderr string, err error) (exitOutcome, string) {
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return exitFailed, err.Error()
+	}
+	if exitErr != nil && exitCode < 0 {
+		// 被信号终止（含超时被杀）
+		return exitFailed, err.Error()
+	}
+	for _, pattern := range p.StderrFail {
+		re, rerr := regexp.Compile(pattern)
+		if rerr != nil {
+			continue
+		}
+		if m := re.FindString(stderr); m != "" {
+			return exitFailed, fmt.Sprintf("stderr 匹配失败规则 %s: %s", pattern, previewText(strings.TrimSpace(m), 200))
+		}
+	}
+	switch {
+	case containsCode(p.WarningCodes, exitCode):
+		return exitWarning, fmt.Sprintf("退出码 %d 标记为警告", exitCode)
+	case len(p.SuccessCodes) == 0 && exitCode == 0, containsCode(p.SuccessCodes, exitCode):
+		return exitSucceeded, ""
+	case containsCode(p.RetryCodes, exitCode):
+		return exitRetryable, fmt.Sprintf("退出码 %d 可重试", exitCode)
+	}
+	return exitFailed, fmt.Sprintf("退出码 %d", exitCode)
+}
+
+// apply 按规则修正单次执行的成功标志与错误
+func (p *ExitPolicy) apply(exitCode int, stderr string, err error) (bool, error) {
+	outcome, reason := p.classify(exitCode, stderr, err)
+	switch outcome {
+	case exitSucceeded, exitWarning:
+		return true, nil
+	}
+	if err == nil {
+		err = errors.New(reason)
+	}
+	return false, err
+}
+
+// validateExitPolicyTag 校验退出码相关标签的取值
+func validateExitPolicyTag(report *JobConfigReport, line int, tag, value string) {
+	switch tag {
+	case "success_codes", "warning_codes", "retry_codes":
+		if _, err := parseExitCodes(value); err != nil {
+			report.errorf(line, tag, "%v", err)
+		} else if strings.TrimSpace(value) == "" {
+			report.errorf(line, tag, "退出码列表不能为空")
+		}
+	case "stderr_fail":
+		if value == "" {
+			report.errorf(line, tag, "正则不能为空")
+		} else if _, err := regexp.Compile(value); err != nil {
+			report.errorf(line, tag, "正则表达式无效: %v", err)
+		}
+	}
+}
+
+// validateExitPolicy 校验退出码规则的组合
+func validateExitPolicy(report *JobConfigReport, p *ExitPolicy) {
+	for _, c := range p.RetryCodes {
+		if containsCode(p.SuccessCodes, c) || containsCode(p.WarningCodes, c) || (len(p.SuccessCodes) == 0 && c == 0) {
+			report.warnf(0, "retry_codes", "退出码 %d 同时被视为成功，不会重试", c)
+		}
+	}
+	if p.Retries > 0 && len(p.RetryCodes) == 0 {
+		report.warnf(0, "retries", "未配置 retry_codes，retries 不生效")
+	}
+}
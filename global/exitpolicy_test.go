@@ -0,0 +1,221 @@
+package global
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testExitErr 运行 shell 得到真实的 *exec.ExitError
+func testExitErr(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("bash", "-c", fmt.Sprintf("exit %d", code)).Run()
+	if code != 0 && err == nil {
+		t.Fatalf("退出码 %d 应返回错误", code)
+	}
+	return err
+}
+
+func TestParseExitCodes(t *testing.T) {
+	for value, want := range map[string]string{
+		"0":         "[0]",
+		"1, 24,1":   "[1 24]",
+		"8-10,2":    "[2 8 9 10]",
+		"-1":        "[-1]",
+		"-2--1, 0":  "[-2 -1 0]",
+		" , 3 , ":   "[3]",
+		"":          "[]",
+		"5-3":       "error",
+		"a":         "error",
+		"0-9999999": "error",
+	} {
+		codes, err := parseExitCodes(value)
+		got := fmt.Sprint(codes)
+		if codes == nil {
+			got = "[]"
+		}
+		if err != nil {
+			got = "error"
+		}
+		if got != want {
+			t.Fatalf("%q: 期望 %s，实际 %s (%v)", value, want, got, err)
+		}
+	}
+}
+
+func TestExitPolicyClassify(t *testing.T) {
+	startErr := errors.New(`exec: "nope": executable file not found in $PATH`)
+	killed := exec.Command("bash", "-c", "kill -9 $$").Run()
+
+	for _, tc := range []struct {
+		name       string
+		policy     ExitPolicy
+		code       int
+		stderr     string
+		err        error
+		want       exitOutcome
+		wantReason string
+		wantOK     bool
+	}{
+		{name: "默认成功", code: 0, want: exitSucceeded, wantOK: true},
+		{name: "默认失败", code: 1, want: exitFailed, wantReason: "退出码 1"},
+		{name: "自定义成功码", policy: ExitPolicy{SuccessCodes: []int{0, 1}}, code: 1, want: exitSucceeded, wantOK: true},
+		{name: "自定义成功码不含0", policy: ExitPolicy{SuccessCodes: []int{1}}, code: 0, want: exitFailed, wantReason: "退出码 0"},
+		{name: "警告码", policy: ExitPolicy{WarningCodes: []int{24}}, code: 24, want: exitWarning, wantReason: "退出码 24 标记为警告", wantOK: true},
+		{name: "警告码优先于成功码", policy: ExitPolicy{SuccessCodes: []int{0, 24}, WarningCodes: []int{24}}, code: 24, want: exitWarning, wantOK: true},
+		{name: "重试码", policy: ExitPolicy{RetryCodes: []int{75}}, code: 75, want: exitRetryable, wantReason: "退出码 75 可重试"},
+		{name: "成功码优先于重试码", policy: ExitPolicy{RetryCodes: []int{0}}, code: 0, want: exitSucceeded, wantOK: true},
+		{name: "stderr匹配", policy: ExitPolicy{StderrFail: []string{"(?i)fatal"}}, code: 0, stderr: "line1\nFATAL: disk full\n", want: exitFailed, wantReason: "stderr 匹配失败规则 (?i)fatal: FATAL"},
+		{name: "stderr未匹配", policy: ExitPolicy{StderrFail: []string{"(?i)fatal"}}, code: 0, stderr: "warning only", want: exitSucceeded, wantOK: true},
+		{name: "stderr任一规则匹配", policy: ExitPolicy{StderrFail: []string{"^x", "error"}}, code: 0, stderr: "some error", want: exitFailed, wantReason: "stderr 匹配失败规则 error"},
+		{name: "stderr优先于警告码", policy: ExitPolicy{WarningCodes: []int{24}, StderrFail: []string{"error"}}, code: 24, stderr: "error", want: exitFailed},
+		{name: "无效正则忽略", policy: ExitPolicy{StderrFail: []string{"("}}, code: 0, stderr: "(", want: exitSucceeded, wantOK: true},
+		{name: "启动失败", policy: ExitPolicy{SuccessCodes: []int{0, 1}}, code: 0, err: startErr, want: exitFailed, wantReason: "executable file not found"},
+		{name: "被信号终止", policy: ExitPolicy{SuccessCodes: []int{-1}}, code: -1, err: killed, want: exitFailed, wantReason: "killed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.err
+			if err == nil && tc.code > 0 {
+				err = testExitErr(t, tc.code)
+			}
+			got, reason := tc.policy.classify(tc.code, tc.stderr, err)
+			if got != tc.want || !strings.Contains(reason, tc.wantReason) {
+				t.Fatalf("判定不符: 期望 %d %q，实际 %d %q", tc.want, tc.wantReason, got, reason)
+			}
+			if (got == exitSucceeded) != (reason == "") {
+				t.Fatalf("只有成功时原因为空: %d %q", got, reason)
+			}
+
+			ok, aerr := tc.policy.apply(tc.code, tc.stderr, err)
+			if ok != tc.wantOK || (aerr == nil) != ok {
+				t.Fatalf("apply 结果不符: ok=%v err=%v", ok, aerr)
+			}
+			// 原本没有错误时（如 stderr 匹配），错误信息为判定原因
+			if !ok && err == nil && aerr.Error() != reason {
+				t.Fatalf("apply 错误应为判定原因: %v", aerr)
+			}
+		})
+	}
+}
+
+func TestExitPolicyRetryLimit(t *testing.T) {
+	for _, tc := range []struct {
+		policy ExitPolicy
+		want   int
+	}{
+		{ExitPolicy{}, 0},
+		{ExitPolicy{Retries: 5}, 0},
+		{ExitPolicy{RetryCodes: []int{75}}, 3},
+		{ExitPolicy{RetryCodes: []int{75}, Retries: 1}, 1},
+	} {
+		if got := tc.policy.retryLimit(); got != tc.want {
+			t.Fatalf("%+v: 重试上限期望 %d，实际 %d", tc.policy, tc.want, got)
+		}
+	}
+	for retry, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second} {
+		if got := retryDelay(retry, 0); got != want {
+			t.Fatalf("第 %d 次重试退避期望 %s，实际 %s", retry, want, got)
+		}
+	}
+	if got := retryDelay(10, 7); got != 7*time.Second {
+		t.Fatalf("配置间隔时固定等待，实际 %s", got)
+	}
+}
+
+// testCountingCommand 每次执行计数加一，返回 codes 中对应次数的退出码（超出后使用最后一个）
+func testCountingCommand(t *testing.T, codes ...int) (string, string) {
+	counter := filepath.Join(t.TempDir(), "count")
+	var cases strings.Builder
+	for i, c := range codes[:len(codes)-1] {
+		fmt.Fprintf(&cases, "%d) exit %d;; ", i+1, c)
+	}
+	cmd := fmt.Sprintf(`n=$(( $(cat %s 2>/dev/null || echo 0) + 1 )); echo $n > %s; echo "run $n"; case $n in %s*) exit %d;; esac`,
+		counter, counter, cases.String(), codes[len(codes)-1])
+	return cmd, counter
+}
+
+func runCountingJob(t *testing.T, command string, tags ...string) (bool, string, int, string, error) {
+	t.Helper()
+	job := &Jobs{ID: 9301, Name: "retry", Mode: "command", Command: "【command】" + command + "\n" + strings.Join(tags, "\n")}
+	ok, warning, _, code, stdout, _, err := executeCommandJobForSummary(context.Background(), job, nil)
+	return ok, warning, code, stdout, err
+}
+
+func TestCommandRetryLoop(t *testing.T) {
+	t.Run("重试后成功", func(t *testing.T) {
+		cmd, _ := testCountingCommand(t, 75, 0)
+		ok, _, code, stdout, err := runCountingJob(t, cmd, "【retry_codes】75", "【retries】3", "【interval】1")
+		if !ok || err != nil || code != 0 {
+			t.Fatalf("重试后应成功: ok=%v code=%d err=%v\n%s", ok, code, err, stdout)
+		}
+		if strings.Count(stdout, "[重试]") != 1 || !strings.Contains(stdout, "退出码 75 可重试，1s后第 1/3 次重试") || !strings.Contains(stdout, "run 2") {
+			t.Fatalf("应只重试 1 次:\n%s", stdout)
+		}
+	})
+
+	t.Run("重试耗尽", func(t *testing.T) {
+		cmd, _ := testCountingCommand(t, 75)
+		ok, _, code, stdout, err := runCountingJob(t, cmd, "【retry_codes】75", "【retries】2", "【interval】1")
+		if ok || code != 75 || err == nil {
+			t.Fatalf("重试耗尽应失败: ok=%v code=%d err=%v", ok, code, err)
+		}
+		if strings.Count(stdout, "[重试]") != 2 || !strings.Contains(stdout, "run 3") || strings.Contains(stdout, "run 4") {
+			t.Fatalf("应执行 1+2 次:\n%s", stdout)
+		}
+	})
+
+	t.Run("不可重试的退出码不重试", func(t *testing.T) {
+		cmd, _ := testCountingCommand(t, 1)
+		ok, _, code, stdout, _ := runCountingJob(t, cmd, "【retry_codes】75", "【interval】1")
+		if ok || code != 1 || strings.Contains(stdout, "[重试]") || strings.Contains(stdout, "run 2") {
+			t.Fatalf("退出码 1 不应重试: ok=%v code=%d\n%s", ok, code, stdout)
+		}
+	})
+
+	t.Run("警告执行", func(t *testing.T) {
+		cmd, _ := testCountingCommand(t, 24)
+		ok, warning, code, stdout, err := runCountingJob(t, cmd, "【warning_codes】24")
+		if !ok || err != nil || code != 24 || warning != "退出码 24 标记为警告" || !strings.Contains(stdout, "[警告]") {
+			t.Fatalf("退出码 24 应为警告: ok=%v warning=%q err=%v\n%s", ok, warning, err, stdout)
+		}
+	})
+
+	t.Run("stderr失败不重试", func(t *testing.T) {
+		ok, _, _, stdout, err := runCountingJob(t, "echo 'ERROR: boom' >&2", "【stderr_fail】(?i)error", "【retry_codes】75")
+		if ok || err == nil || !strings.Contains(err.Error(), "stderr 匹配失败规则") || strings.Contains(stdout, "[重试]") {
+			t.Fatalf("stderr 匹配应直接失败: ok=%v err=%v\n%s", ok, err, stdout)
+		}
+	})
+
+	t.Run("取消时停止重试", func(t *testing.T) {
+		cmd, counter := testCountingCommand(t, 75)
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		job := &Jobs{ID: 9302, Name: "retry-cancel", Mode: "command", Command: "【command】" + cmd + "\n【retry_codes】75\n【interval】5"}
+		start := time.Now()
+		ok, _, _, _, _, _, err := executeCommandJobForSummary(ctx, job, nil)
+		if ok || !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 3*time.Second {
+			t.Fatalf("取消后应立即结束: ok=%v err=%v 耗时 %s", ok, err, time.Since(start))
+		}
+		if n := testReadCounter(t, counter); n != 1 {
+			t.Fatalf("取消后不应再执行，实际执行 %d 次", n)
+		}
+	})
+}
+
+func testReadCounter(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return n
+}
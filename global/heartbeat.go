@@ -0,0 +1,171 @@
+package global
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"xiaohuAdmin/models/jobs"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// 心跳监控任务（mode: heartbeat）
+// 任务本身不执行任何内容，而是等待外部通过 /ping/<token> 上报；
+// 在 cron 计划时间 + 宽限期内未收到心跳时，记录一次失败执行并发送通知。
+
+// HeartbeatConfig 心跳任务配置
+type HeartbeatConfig struct {
+	Grace int `json:"grace"` // 宽限期（秒）
+}
+
+// 心跳上报类型
+const (
+	PingSuccess = "success"
+	PingStart   = "start"
+	PingFail    = "fail"
+)
+
+// 记录 start 上报时间，用于计算成功/失败上报时的耗时
+var heartbeatStarts sync.Map // map[uint]time.Time
+
+// parseHeartbeatConfig 解析心跳任务配置（【grace】秒数）
+func parseHeartbeatConfig(command string) *HeartbeatConfig {
+	cfg := &HeartbeatConfig{Grace: GetJobsConfigInt("jobs.heartbeat_grace_seconds", 300)}
+	for _, line := range strings.Split(command, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "【grace】") {
+			if v, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "【grace】"))); err == nil && v >= 0 {
+				cfg.Grace = v
+			}
+		}
+	}
+	return cfg
+}
+
+// EnsurePingToken 心跳任务未设置token时自动生成
+func EnsurePingToken(job *Jobs) {
+	if job.Mode == "heartbeat" && strings.TrimSpace(job.PingToken) == "" {
+		job.PingToken = strings.ReplaceAll(uuid.NewString(), "-", "")
+	}
+}
+
+// heartbeatPeriod 计算 cron 周期（本次计划时间到下次计划时间的间隔）
+func heartbeatPeriod(spec string, at time.Time) time.Duration {
+	parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+	sched, err := parser.Parse(spec)
+	if err != nil {
+		return 0
+	}
+	return sched.Next(at).Sub(at)
+}
+
+// scheduleHeartbeatCheck 在计划时间到达时调用，宽限期结束后检查是否收到心跳
+func scheduleHeartbeatCheck(job *Jobs, expectedAt time.Time) {
+	cfg := parseHeartbeatConfig(job.Command)
+	period := heartbeatPeriod(job.CronExpr, expectedAt)
+	time.AfterFunc(time.Duration(cfg.Grace)*time.Second, func() {
+		checkHeartbeat(job, expectedAt, period, cfg)
+	})
+}
+
+// checkHeartbeat 检查上一个周期内是否收到过心跳，未收到则记录失败并告警
+func checkHeartbeat(job *Jobs, expectedAt time.Time, period time.Duration, cfg *HeartbeatConfig) {
+	var current Jobs
+	if err := DB.Select("id,state,mode,last_ping_at,created_at").First(&current, job.ID).Error; err != nil {
+		return // 任务已删除
+	}
+	if current.Mode != "heartbeat" || current.State == 2 {
+		return
+	}
+	// 从未收到心跳时以创建时间为基准，新建任务至少等待一个完整周期
+	windowStart := expectedAt.Add(-period)
+	ref := current.CreatedAt
+	if current.LastPingAt != nil {
+		ref = *current.LastPingAt
+	}
+	if ref.After(windowStart) {
+		return
+	}
+
+	now := time.Now()
+	msg := fmt.Sprintf("计划时间 %s 后 %d 秒宽限期内未收到心跳", expectedAt.Format("2006-01-02 15:04:05"), cfg.Grace)
+	if current.LastPingAt != nil {
+		msg += "，最近一次心跳：" + current.LastPingAt.Format("2006-01-02 15:04:05")
+	}
+	log := &JobExecLog{
+		Time:     now.Format("2006-01-02 15:04:05.000"),
+		EndTime:  now.Format("2006-01-02 15:04:05.000"),
+		JobID:    job.ID,
+		JobName:  job.Name,
+		Mode:     job.Mode,
+		ExecID:   uuid.NewString(),
+		Source:   "heartbeat",
+		Status:   "失败",
+		ErrorMsg: msg,
+	}
+	finishExecLog(job, log, false)
+	NotifyJobEvent(job, NotifyOnMissed, log)
+	if ZapLog != nil {
+		ZapLog.Warn("心跳丢失",
+			LogField("job_id", job.ID),
+			LogField("name", job.Name),
+			LogField("expected_at", expectedAt.Format("2006-01-02 15:04:05")))
+	}
+}
+
+// RecordPing 处理外部心跳上报，返回本次记录的执行ID（start 上报不产生执行记录）
+func RecordPing(token, kind, body, remoteIP string) (string, error) {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return "", fmt.Errorf("token不能为空")
+	}
+	if kind == "" {
+		kind = PingSuccess
+	}
+	if kind != PingSuccess && kind != PingStart && kind != PingFail {
+		return "", fmt.Errorf("不支持的心跳类型: %s", kind)
+	}
+	var job Jobs
+	if err := DB.Where("ping_token = ? AND mode = ?", token, "heartbeat").First(&job).Error; err != nil {
+		return "", fmt.Errorf("心跳任务不存在")
+	}
+
+	now := time.Now()
+	if kind == PingStart {
+		heartbeatStarts.Store(job.ID, now)
+		NewJobLogger(job.ID, job.Name).writeLog("START", "收到开始心跳，来源 "+remoteIP)
+		return "", nil
+	}
+
+	startTime := now
+	if v, ok := heartbeatStarts.LoadAndDelete(job.ID); ok {
+		startTime = v.(time.Time)
+	}
+	if err := DB.Model(&jobs.Jobs{}).Where("id=?", job.ID).Update("last_ping_at", now).Error; err != nil {
+		return "", fmt.Errorf("记录心跳失败: %v", err)
+	}
+
+	success := kind == PingSuccess
+	maxBytes := GetJobsConfigInt("jobs.log_line_truncate", 1000)
+	log := &JobExecLog{
+		Time:       startTime.Format("2006-01-02 15:04:05.000"),
+		EndTime:    now.Format("2006-01-02 15:04:05.000"),
+		JobID:      job.ID,
+		JobName:    job.Name,
+		Mode:       job.Mode,
+		ExecID:     uuid.NewString(),
+		Source:     "ping",
+		Status:     map[bool]string{true: "成功", false: "失败"}[success],
+		DurationMs: now.Sub(startTime).Milliseconds(),
+		Stdout:     previewText(body, maxBytes),
+	}
+	if !success {
+		log.ErrorMsg = "外部上报失败（来源 " + remoteIP + "）"
+	}
+	finishExecLog(&job, log, success)
+	return log.ExecID, nil
+}
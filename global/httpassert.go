@@ -0,0 +1,359 @@
+package global
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP响应断言（【assert】，可多行；结构化配置为 assert 字符串数组）
+// 配置断言后，请求成功需全部断言通过；未配置 status 断言时仍要求状态码为 2xx。
+//
+//	status 200,201,300-399       状态码集合与区间，也可写 2xx；status !in 500-599 表示排除
+//	json $.code == 0             JSONPath 取值比较
+//	json $.data.items exists     路径存在
+//	body matches "ok":\s*true    正则匹配；!matches 为不匹配
+//	body contains success        响应体包含文本
+//	header Content-Type contains json
+//	body_size <= 1048576         响应体字节数
+//	latency < 800ms              单次请求耗时（含读取响应体），支持 ms/s，纯数字为毫秒
+//
+// 运算符：== != > >= < <= contains !contains matches !matches exists !exists
+
+// HTTPAssertionResult 单条断言的执行结果（写入聚合日志）
+type HTTPAssertionResult struct {
+	Assertion string `json:"assertion"`
+	Passed    bool   `json:"passed"`
+	Actual    string `json:"actual,omitempty"`  // 实际值
+	Message   string `json:"message,omitempty"` // 未通过原因
+}
+
+// httpAssertion 解析后的断言
+type httpAssertion struct {
+	Raw     string
+	Subject string // status/json/header/body/body_size/latency
+	Arg     string // JSONPath 或响应头名称
+	Op      string
+	Value   string
+	re      *regexp.Regexp
+	ranges  [][2]int // status 断言的区间
+	limit   float64  // body_size/latency 的数值（latency 为毫秒）
+}
+
+// httpAssertResponse 断言所需的响应信息
+type httpAssertResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       string // 完整响应体（UTF-8）
+	BodySize   int    // 原始响应体字节数
+	Latency    time.Duration
+}
+
+var httpAssertOps = []string{"==", "!=", ">=", "<=", ">", "<", "contains", "!contains", "matches", "!matches", "exists", "!exists"}
+
+// 各断言对象支持的运算符
+var httpAssertSubjectOps = map[string][]string{
+	"status":    {"in", "!in"},
+	"json":      httpAssertOps,
+	"header":    {"==", "!=", "contains", "!contains", "matches", "!matches", "exists", "!exists"},
+	"body":      {"contains", "!contains", "matches", "!matches"},
+	"body_size": {"==", "!=", ">=", "<=", ">", "<"},
+	"latency":   {">=", "<=", ">", "<"},
+}
+
+// parseHTTPAssertion 解析单条断言表达式
+func parseHTTPAssertion(expr string) (*httpAssertion, error) {
+	a := &httpAssertion{Raw: strings.TrimSpace(expr)}
+	subject, rest := cutAssertField(a.Raw)
+	a.Subject = strings.ToLower(subject)
+	ops, ok := httpAssertSubjectOps[a.Subject]
+	if !ok {
+		return nil, fmt.Errorf("不支持的断言对象 %q，可选 status/json/header/body/body_size/latency", subject)
+	}
+	if a.Subject == "json" || a.Subject == "header" {
+		a.Arg, rest = cutAssertField(rest)
+		if a.Arg == "" {
+			return nil, fmt.Errorf("%s 断言缺少%s", a.Subject, map[string]string{"json": "路径", "header": "响应头名称"}[a.Subject])
+		}
+	}
+	op, value := cutAssertField(rest)
+	if a.Subject == "status" && !containsString(ops, op) {
+		// status 省略运算符时为 in
+		op, value = "in", rest
+	}
+	if !containsString(ops, op) {
+		if op == "" {
+			return nil, fmt.Errorf("缺少运算符")
+		}
+		return nil, fmt.Errorf("%s 断言不支持运算符 %q，可选 %s", a.Subject, op, strings.Join(ops, " "))
+	}
+	a.Op = op
+	if strings.HasSuffix(op, "exists") {
+		if value != "" {
+			return nil, fmt.Errorf("%s 不需要比较值", op)
+		}
+		return a, nil
+	}
+	if value == "" {
+		return nil, fmt.Errorf("缺少比较值")
+	}
+	a.Value = unquoteAssertValue(value)
+
+	var err error
+	switch {
+	case a.Subject == "status":
+		a.ranges, err = parseStatusRanges(a.Value)
+	case strings.HasSuffix(op, "matches"):
+		a.re, err = regexp.Compile(a.Value)
+		if err != nil {
+			err = fmt.Errorf("正则表达式无效: %v", err)
+		}
+	case a.Subject == "body_size":
+		n, perr := strconv.Atoi(a.Value)
+		if perr != nil || n < 0 {
+			err = fmt.Errorf("响应体大小应为非负整数（字节），当前为 %q", a.Value)
+		}
+		a.limit = float64(n)
+	case a.Subject == "latency":
+		d, perr := parseAssertDuration(a.Value)
+		if perr != nil {
+			err = perr
+		}
+		a.limit = float64(d.Milliseconds())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// cutAssertField 取出第一个以空白分隔的字段
+func cutAssertField(s string) (string, string) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexAny(s, " \t"); i >= 0 {
+		return s[:i], strings.TrimSpace(s[i+1:])
+	}
+	return s, ""
+}
+
+// unquoteAssertValue 去掉比较值两侧成对的引号
+func unquoteAssertValue(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// parseStatusRanges 解析状态码集合：200,201,300-399,4xx
+func parseStatusRanges(value string) ([][2]int, error) {
+	var ranges [][2]int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi := part, part
+		if len(part) == 3 && strings.HasSuffix(strings.ToLower(part), "xx") {
+			lo, hi = part[:1]+"00", part[:1]+"99"
+		} else if l, h, ok := strings.Cut(part, "-"); ok {
+			lo, hi = strings.TrimSpace(l), strings.TrimSpace(h)
+		}
+		from, err1 := strconv.Atoi(lo)
+		to, err2 := strconv.Atoi(hi)
+		if err1 != nil || err2 != nil || from < 100 || to > 599 || from > to {
+			return nil, fmt.Errorf("状态码格式无效: %s", part)
+		}
+		ranges = append(ranges, [2]int{from, to})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("缺少状态码")
+	}
+	return ranges, nil
+}
+
+// parseAssertDuration 解析耗时：500ms、2s，纯数字为毫秒
+func parseAssertDuration(value string) (time.Duration, error) {
+	if n, err := strconv.Atoi(value); err == nil && n >= 0 {
+		return time.Duration(n) * time.Millisecond, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("耗时格式无效 %q，示例 500ms、2s", value)
+	}
+	return d, nil
+}
+
+// parseHTTPAssertions 解析全部断言，返回第一个错误
+func parseHTTPAssertions(exprs []string) ([]*httpAssertion, error) {
+	list := make([]*httpAssertion, 0, len(exprs))
+	for _, expr := range exprs {
+		a, err := parseHTTPAssertion(expr)
+		if err != nil {
+			return nil, fmt.Errorf("断言 %q 无效: %v", expr, err)
+		}
+		list = append(list, a)
+	}
+	return list, nil
+}
+
+// hasStatusAssertion 是否包含状态码断言
+func hasStatusAssertion(list []*httpAssertion) bool {
+	for _, a := range list {
+		if a.Subject == "status" {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluate 执行断言
+func (a *httpAssertion) evaluate(resp *httpAssertResponse) HTTPAssertionResult {
+	r := HTTPAssertionResult{Assertion: a.Raw}
+	switch a.Subject {
+	case "status":
+		r.Actual = strconv.Itoa(resp.StatusCode)
+		in := false
+		for _, rg := range a.ranges {
+			if resp.StatusCode >= rg[0] && resp.StatusCode <= rg[1] {
+				in = true
+				break
+			}
+		}
+		r.Passed = in == (a.Op == "in")
+	case "json":
+		v, err := jsonPathLookup(resp.Body, a.Arg)
+		exists := err == nil
+		switch a.Op {
+		case "exists", "!exists":
+			r.Passed = exists == (a.Op == "exists")
+			if exists {
+				r.Actual = previewText(jsonValueString(v), 200)
+			} else if !r.Passed {
+				r.Message = err.Error()
+			}
+			return a.finish(r)
+		}
+		if !exists {
+			r.Message = err.Error()
+			return r
+		}
+		r.Actual = jsonValueString(v)
+		r.Passed, r.Message = a.compare(r.Actual)
+	case "header":
+		values, ok := resp.Header[http.CanonicalHeaderKey(a.Arg)]
+		switch a.Op {
+		case "exists", "!exists":
+			r.Passed = ok == (a.Op == "exists")
+			r.Actual = strings.Join(values, ", ")
+			return a.finish(r)
+		}
+		if !ok {
+			r.Message = "响应头不存在: " + a.Arg
+			return r
+		}
+		r.Actual = strings.Join(values, ", ")
+		r.Passed, r.Message = a.compare(r.Actual)
+	case "body":
+		r.Passed, r.Message = a.compare(resp.Body)
+		r.Actual = previewText(resp.Body, 200)
+	case "body_size":
+		r.Actual = strconv.Itoa(resp.BodySize)
+		r.Passed = compareNumbers(float64(resp.BodySize), a.Op, a.limit)
+	case "latency":
+		r.Actual = fmt.Sprintf("%dms", resp.Latency.Milliseconds())
+		r.Passed = compareNumbers(float64(resp.Latency.Milliseconds()), a.Op, a.limit)
+	}
+	return a.finish(r)
+}
+
+// finish 为未通过且没有原因的断言补充说明
+func (a *httpAssertion) finish(r HTTPAssertionResult) HTTPAssertionResult {
+	if !r.Passed && r.Message == "" {
+		if r.Actual != "" {
+			r.Message = fmt.Sprintf("实际值 %s", previewText(r.Actual, 200))
+		} else {
+			r.Message = "条件不成立"
+		}
+	}
+	return r
+}
+
+// compare 按运算符比较实际值与期望值（json/header/body）
+func (a *httpAssertion) compare(actual string) (bool, string) {
+	switch a.Op {
+	case "contains":
+		return strings.Contains(actual, a.Value), ""
+	case "!contains":
+		return !strings.Contains(actual, a.Value), ""
+	case "matches":
+		return a.re.MatchString(actual), ""
+	case "!matches":
+		return !a.re.MatchString(actual), ""
+	}
+	x, err1 := strconv.ParseFloat(actual, 64)
+	y, err2 := strconv.ParseFloat(a.Value, 64)
+	if err1 == nil && err2 == nil {
+		return compareNumbers(x, a.Op, y), ""
+	}
+	switch a.Op {
+	case "==":
+		return actual == a.Value, ""
+	case "!=":
+		return actual != a.Value, ""
+	}
+	return false, fmt.Sprintf("%s 需要数值，实际值 %s", a.Op, previewText(actual, 200))
+}
+
+func compareNumbers(x float64, op string, y float64) bool {
+	switch op {
+	case "==":
+		return x == y
+	case "!=":
+		return x != y
+	case ">":
+		return x > y
+	case ">=":
+		return x >= y
+	case "<":
+		return x < y
+	case "<=":
+		return x <= y
+	}
+	return false
+}
+
+// runHTTPAssertions 执行全部断言，未配置 status 断言时追加隐含的 2xx 检查
+func runHTTPAssertions(list []*httpAssertion, resp *httpAssertResponse) ([]HTTPAssertionResult, bool) {
+	if !hasStatusAssertion(list) {
+		implicit, _ := parseHTTPAssertion("status 2xx")
+		list = append([]*httpAssertion{implicit}, list...)
+	}
+	results := make([]HTTPAssertionResult, 0, len(list))
+	passed := true
+	for _, a := range list {
+		r := a.evaluate(resp)
+		if !r.Passed {
+			passed = false
+		}
+		results = append(results, r)
+	}
+	return results, passed
+}
+
+// failedAssertionsError 汇总未通过的断言
+func failedAssertionsError(results []HTTPAssertionResult) error {
+	var msgs []string
+	for _, r := range results {
+		if !r.Passed {
+			msgs = append(msgs, fmt.Sprintf("%s（%s）", r.Assertion, r.Message))
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("断言未通过: %s", strings.Join(msgs, "；"))
+}
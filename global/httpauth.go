@@ -0,0 +1,340 @@
+package global
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HTTP任务认证（【auth】类型 参数=值|||参数=值）
+//
+//	【auth】basic username=admin|||password=secret:admin_pw
+//	【auth】bearer token=env:API_TOKEN
+//	【auth】apikey name=X-API-Key|||value=secret:api_key|||in=header
+//	【auth】oauth2 token_url=https://sso/token|||client_id=job|||client_secret=secret:sso|||scope=read write
+//
+// 密码、令牌等取值支持 env:/file:/secret: 引用（见 ResolveSecret）。
+// oauth2 使用 client_credentials 模式，令牌按 token_url+client_id+scope 缓存至过期，多个任务共享；
+// 请求返回 401 时刷新令牌并重试一次。
+
+// 认证类型
+const (
+	HTTPAuthBasic  = "basic"
+	HTTPAuthBearer = "bearer"
+	HTTPAuthAPIKey = "apikey"
+	HTTPAuthOAuth2 = "oauth2"
+)
+
+var httpAuthTypes = []string{HTTPAuthBasic, HTTPAuthBearer, HTTPAuthAPIKey, HTTPAuthOAuth2}
+
+// HTTPAuth HTTP任务认证配置
+type HTTPAuth struct {
+	Type         string `json:"type"`
+	Username     string `json:"username,omitempty"`      // basic
+	Password     string `json:"password,omitempty"`      // basic
+	Token        string `json:"token,omitempty"`         // bearer
+	Name         string `json:"name,omitempty"`          // apikey 请求头或查询参数名
+	Value        string `json:"value,omitempty"`         // apikey
+	In           string `json:"in,omitempty"`            // apikey 位置 header/query，默认 header
+	TokenURL     string `json:"token_url,omitempty"`     // oauth2
+	ClientID     string `json:"client_id,omitempty"`     // oauth2
+	ClientSecret string `json:"client_secret,omitempty"` // oauth2
+	Scope        string `json:"scope,omitempty"`         // oauth2，多个以空格分隔
+	ClientAuth   string `json:"client_auth,omitempty"`   // oauth2 客户端凭证位置 basic/body，默认 basic
+}
+
+// 各认证类型的参数，第一个列表为必填
+var httpAuthFields = map[string][2][]string{
+	HTTPAuthBasic:  {{"username"}, {"password"}},
+	HTTPAuthBearer: {{"token"}, nil},
+	HTTPAuthAPIKey: {{"name", "value"}, {"in"}},
+	HTTPAuthOAuth2: {{"token_url", "client_id", "client_secret"}, {"scope", "client_auth"}},
+}
+
+// parseHTTPAuth 解析【auth】标签
+func parseHTTPAuth(value string) (*HTTPAuth, error) {
+	typ, rest := cutAssertField(value)
+	auth := &HTTPAuth{Type: strings.ToLower(typ)}
+	if _, ok := httpAuthFields[auth.Type]; !ok {
+		return nil, fmt.Errorf("不支持的认证类型 %q，可选 %s", typ, strings.Join(httpAuthTypes, "/"))
+	}
+	params := make(map[string]string)
+	parseHTTPPairs(rest, params)
+	for k, v := range params {
+		switch strings.ToLower(k) {
+		case "username":
+			auth.Username = v
+		case "password":
+			auth.Password = v
+		case "token":
+			auth.Token = v
+		case "name":
+			auth.Name = v
+		case "value":
+			auth.Value = v
+		case "in":
+			auth.In = v
+		case "token_url":
+			auth.TokenURL = v
+		case "client_id":
+			auth.ClientID = v
+		case "client_secret":
+			auth.ClientSecret = v
+		case "scope":
+			auth.Scope = v
+		case "client_auth":
+			auth.ClientAuth = v
+		default:
+			return nil, fmt.Errorf("未知的认证参数: %s", k)
+		}
+	}
+	return auth, nil
+}
+
+// fields 认证参数的当前取值
+func (a *HTTPAuth) fields() map[string]string {
+	return map[string]string{
+		"username": a.Username, "password": a.Password, "token": a.Token,
+		"name": a.Name, "value": a.Value, "in": a.In,
+		"token_url": a.TokenURL, "client_id": a.ClientID, "client_secret": a.ClientSecret,
+		"scope": a.Scope, "client_auth": a.ClientAuth,
+	}
+}
+
+// validateHTTPAuth 校验认证配置
+func validateHTTPAuth(report *JobConfigReport, line int, a *HTTPAuth) {
+	spec, ok := httpAuthFields[strings.ToLower(a.Type)]
+	if !ok {
+		report.errorf(line, "auth", "不支持的认证类型 %q，可选 %s", a.Type, strings.Join(httpAuthTypes, "/"))
+		return
+	}
+	values := a.fields()
+	for _, f := range spec[0] {
+		if strings.TrimSpace(values[f]) == "" {
+			report.errorf(line, "auth", "%s 认证缺少 %s", a.Type, f)
+		}
+	}
+	for f, v := range values {
+		if v != "" && !containsString(spec[0], f) && !containsString(spec[1], f) {
+			report.warnf(line, "auth", "%s 认证不使用 %s，将被忽略", a.Type, f)
+		}
+	}
+	if a.In != "" && a.In != "header" && a.In != "query" {
+		report.errorf(line, "auth", "apikey 位置应为 header 或 query，当前为 %q", a.In)
+	}
+	if a.ClientAuth != "" && a.ClientAuth != "basic" && a.ClientAuth != "body" {
+		report.errorf(line, "auth", "client_auth 应为 basic 或 body，当前为 %q", a.ClientAuth)
+	}
+	if a.TokenURL != "" && !strings.Contains(a.TokenURL, "{{") {
+		if u, err := url.Parse(a.TokenURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			report.errorf(line, "auth", "token_url 不是合法的 http(s) 地址: %s", a.TokenURL)
+		}
+	}
+	for _, f := range []string{"password", "token", "value", "client_secret"} {
+		if !strings.Contains(values[f], "{{") {
+			checkSecretRef(report, line, "auth", f, values[f])
+		}
+	}
+}
+
+// describe 认证摘要（不含密钥）
+func (a *HTTPAuth) describe() string {
+	switch a.Type {
+	case HTTPAuthBasic:
+		return fmt.Sprintf("basic（用户 %s）", a.Username)
+	case HTTPAuthAPIKey:
+		in := a.In
+		if in == "" {
+			in = "header"
+		}
+		return fmt.Sprintf("apikey（%s %s）", in, a.Name)
+	case HTTPAuthOAuth2:
+		return fmt.Sprintf("oauth2 client_credentials（client_id %s）", a.ClientID)
+	}
+	return a.Type
+}
+
+// applyHTTPAuth 为请求添加认证信息；refresh 为 true 时强制刷新 OAuth2 令牌
+func applyHTTPAuth(ctx context.Context, client *http.Client, a *HTTPAuth, req *http.Request, refresh bool) error {
+	switch a.Type {
+	case HTTPAuthBasic:
+		user, err := ResolveSecret(a.Username)
+		if err != nil {
+			return err
+		}
+		pass, err := ResolveSecret(a.Password)
+		if err != nil {
+			return err
+		}
+		req.SetBasicAuth(user, pass)
+	case HTTPAuthBearer:
+		token, err := ResolveSecret(a.Token)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case HTTPAuthAPIKey:
+		value, err := ResolveSecret(a.Value)
+		if err != nil {
+			return err
+		}
+		if a.In == "query" {
+			q := req.URL.Query()
+			q.Set(a.Name, value)
+			req.URL.RawQuery = q.Encode()
+		} else {
+			req.Header.Set(a.Name, value)
+		}
+	case HTTPAuthOAuth2:
+		token, err := oauth2Tokens.get(ctx, client, a, refresh)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", token.authorization())
+	default:
+		return fmt.Errorf("不支持的认证类型: %s", a.Type)
+	}
+	return nil
+}
+
+// oauth2Token 访问令牌
+type oauth2Token struct {
+	AccessToken string
+	TokenType   string
+	Expiry      time.Time // 零值表示未返回有效期，直到 401 才刷新
+}
+
+func (t *oauth2Token) valid() bool {
+	return t != nil && t.AccessToken != "" && (t.Expiry.IsZero() || time.Now().Before(t.Expiry))
+}
+
+func (t *oauth2Token) authorization() string {
+	typ := t.TokenType
+	if typ == "" || strings.EqualFold(typ, "bearer") {
+		typ = "Bearer"
+	}
+	return typ + " " + t.AccessToken
+}
+
+// oauth2TokenCache 令牌缓存，同一客户端的并发请求只获取一次令牌
+type oauth2TokenCache struct {
+	mu      sync.Mutex
+	entries map[string]*oauth2CacheEntry
+}
+
+type oauth2CacheEntry struct {
+	mu    sync.Mutex
+	token *oauth2Token
+}
+
+var oauth2Tokens = &oauth2TokenCache{entries: make(map[string]*oauth2CacheEntry)}
+
+// 令牌提前刷新的时间，避免请求途中过期
+const oauth2ExpirySkew = 30 * time.Second
+
+// oauth2CacheKey 缓存键：token_url + client_id + scope，包含密钥摘要以便密钥轮换后重新获取
+func oauth2CacheKey(a *HTTPAuth, secret string) string {
+	scopes := strings.Fields(a.Scope)
+	sort.Strings(scopes)
+	sum := sha256.Sum256([]byte(secret))
+	return strings.Join([]string{a.TokenURL, a.ClientID, strings.Join(scopes, " "), hex.EncodeToString(sum[:8])}, "|")
+}
+
+// get 获取令牌：缓存有效时直接返回；refresh 为 true 时丢弃缓存重新获取
+func (c *oauth2TokenCache) get(ctx context.Context, client *http.Client, a *HTTPAuth, refresh bool) (*oauth2Token, error) {
+	clientID, err := ResolveSecret(a.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := ResolveSecret(a.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	key := oauth2CacheKey(a, secret)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &oauth2CacheEntry{}
+		c.entries[key] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if !refresh && entry.token.valid() {
+		return entry.token, nil
+	}
+	token, err := requestOAuth2Token(ctx, client, a, clientID, secret)
+	if err != nil {
+		entry.token = nil
+		return nil, err
+	}
+	entry.token = token
+	return token, nil
+}
+
+// requestOAuth2Token 以 client_credentials 模式向 token_url 请求令牌
+func requestOAuth2Token(ctx context.Context, client *http.Client, a *HTTPAuth, clientID, secret string) (*oauth2Token, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if a.Scope != "" {
+		form.Set("scope", strings.Join(strings.Fields(a.Scope), " "))
+	}
+	if a.ClientAuth == "body" {
+		form.Set("client_id", clientID)
+		form.Set("client_secret", secret)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("创建令牌请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if a.ClientAuth != "body" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求令牌失败: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取令牌响应失败: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("令牌接口返回 %d: %s", resp.StatusCode, previewText(string(body), 200))
+	}
+	var data struct {
+		AccessToken string      `json:"access_token"`
+		TokenType   string      `json:"token_type"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("令牌响应不是合法JSON: %v", err)
+	}
+	if data.AccessToken == "" {
+		return nil, fmt.Errorf("令牌响应缺少 access_token")
+	}
+	token := &oauth2Token{AccessToken: data.AccessToken, TokenType: data.TokenType}
+	if secs, err := strconv.ParseInt(data.ExpiresIn.String(), 10, 64); err == nil && secs > 0 {
+		ttl := time.Duration(secs) * time.Second
+		if ttl > 2*oauth2ExpirySkew {
+			ttl -= oauth2ExpirySkew
+		}
+		token.Expiry = time.Now().Add(ttl)
+	}
+	LogInfo("OAuth2令牌已获取", LogField("token_url", a.TokenURL), LogField("client_id", clientID),
+		LogField("expires_in", data.ExpiresIn.String()))
+	return token, nil
+}
@@ -0,0 +1,219 @@
+package global
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+const (
+	testOAuthClientID = "job-client"
+	testOAuthSecret   = "oauth-secret-7f3a"
+)
+
+// testOAuthServer 令牌接口与受保护接口
+// 令牌接口每次签发新令牌 tok-N；受保护接口只接受最新令牌，revoked 中的令牌返回 401
+type testOAuthServer struct {
+	*httptest.Server
+	expiresIn int
+
+	tokenRequests, apiRequests atomic.Int64
+	mu                         sync.Mutex
+	current                    string
+	revoked                    map[string]bool
+	alwaysUnauthorized         bool
+}
+
+func newTestOAuthServer(t *testing.T, expiresIn int) *testOAuthServer {
+	t.Helper()
+	s := &testOAuthServer{expiresIn: expiresIn, revoked: make(map[string]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != testOAuthClientID || pass != testOAuthSecret || r.FormValue("grant_type") != "client_credentials" {
+			http.Error(w, "invalid_client", http.StatusUnauthorized)
+			return
+		}
+		n := s.tokenRequests.Add(1)
+		s.mu.Lock()
+		s.current = fmt.Sprintf("tok-%d", n)
+		token := s.current
+		s.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"access_token": token, "token_type": "bearer", "expires_in": s.expiresIn})
+	})
+	mux.HandleFunc("/api", func(w http.ResponseWriter, r *http.Request) {
+		s.apiRequests.Add(1)
+		s.mu.Lock()
+		want := "Bearer " + s.current
+		revoked := s.revoked[s.current] || s.alwaysUnauthorized
+		s.mu.Unlock()
+		if r.Header.Get("Authorization") != want || revoked {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testOAuthServer) revokeCurrent() {
+	s.mu.Lock()
+	s.revoked[s.current] = true
+	s.mu.Unlock()
+}
+
+func (s *testOAuthServer) jobConfig() *HTTPConfig {
+	return &HTTPConfig{
+		URL:     s.URL + "/api",
+		Mode:    "GET",
+		Timeout: 5,
+		Proxy:   ProxyDirect,
+		Auth: &HTTPAuth{
+			Type:         HTTPAuthOAuth2,
+			TokenURL:     s.URL + "/token",
+			ClientID:     testOAuthClientID,
+			ClientSecret: testOAuthSecret,
+			Scope:        "read",
+		},
+	}
+}
+
+// expireOAuthTokens 使缓存中的令牌全部过期
+func expireOAuthTokens() {
+	oauth2Tokens.mu.Lock()
+	defer oauth2Tokens.mu.Unlock()
+	for _, entry := range oauth2Tokens.entries {
+		entry.mu.Lock()
+		if entry.token != nil {
+			entry.token.Expiry = time.Now().Add(-time.Second)
+		}
+		entry.mu.Unlock()
+	}
+}
+
+func execHTTPJob(t *testing.T, config *HTTPConfig) *httpExecResult {
+	t.Helper()
+	res, err := executeHTTPConfig(context.Background(), config, nil)
+	if err != nil {
+		t.Fatalf("执行HTTP任务失败: %v\n%s", err, res.Summary)
+	}
+	return res
+}
+
+func TestOAuth2TokenCached(t *testing.T) {
+	s := newTestOAuthServer(t, 3600)
+	for i := 0; i < 3; i++ {
+		if res := execHTTPJob(t, s.jobConfig()); !res.Success {
+			t.Fatalf("第 %d 次执行失败:\n%s", i+1, res.Summary)
+		}
+	}
+	if n := s.tokenRequests.Load(); n != 1 {
+		t.Fatalf("令牌应只获取 1 次，实际 %d 次", n)
+	}
+
+	// 不同 scope 使用独立的缓存
+	config := s.jobConfig()
+	config.Auth.Scope = "write"
+	execHTTPJob(t, config)
+	if n := s.tokenRequests.Load(); n != 2 {
+		t.Fatalf("不同 scope 应重新获取令牌，实际共 %d 次", n)
+	}
+}
+
+func TestOAuth2TokenExpiryRefresh(t *testing.T) {
+	s := newTestOAuthServer(t, 3600)
+	execHTTPJob(t, s.jobConfig())
+	expireOAuthTokens()
+	if res := execHTTPJob(t, s.jobConfig()); !res.Success {
+		t.Fatalf("令牌过期后应刷新并成功:\n%s", res.Summary)
+	}
+	if n := s.tokenRequests.Load(); n != 2 {
+		t.Fatalf("令牌过期后应重新获取，实际共 %d 次", n)
+	}
+
+	// 有效期按 expires_in 扣除提前量计算
+	token, err := oauth2Tokens.get(context.Background(), http.DefaultClient, s.jobConfig().Auth, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left := time.Until(token.Expiry); left > time.Hour-oauth2ExpirySkew || left < time.Hour-oauth2ExpirySkew-time.Minute {
+		t.Fatalf("令牌有效期不符: 剩余 %s", left)
+	}
+}
+
+func TestOAuth2RefreshOn401(t *testing.T) {
+	s := newTestOAuthServer(t, 3600)
+	execHTTPJob(t, s.jobConfig())
+
+	// 服务端吊销令牌：401 后刷新令牌并重试一次
+	s.revokeCurrent()
+	s.apiRequests.Store(0)
+	res := execHTTPJob(t, s.jobConfig())
+	if !res.Success || res.StatusCode != http.StatusOK {
+		t.Fatalf("刷新令牌后应成功:\n%s", res.Summary)
+	}
+	if n := s.tokenRequests.Load(); n != 2 {
+		t.Fatalf("401 后应刷新令牌 1 次，实际共获取 %d 次", n)
+	}
+	if n := s.apiRequests.Load(); n != 2 {
+		t.Fatalf("应只重试 1 次，实际请求 %d 次", n)
+	}
+	if !strings.Contains(res.Summary, "刷新OAuth2令牌后重试") {
+		t.Fatalf("摘要应记录刷新重试:\n%s", res.Summary)
+	}
+
+	// 刷新后仍然 401 时不再重试
+	s.mu.Lock()
+	s.alwaysUnauthorized = true
+	s.mu.Unlock()
+	s.apiRequests.Store(0)
+	res, _ = executeHTTPConfig(context.Background(), s.jobConfig(), nil)
+	if res.Success || res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("持续 401 应失败:\n%s", res.Summary)
+	}
+	if n := s.apiRequests.Load(); n != 2 {
+		t.Fatalf("持续 401 时应只重试 1 次，实际请求 %d 次", n)
+	}
+}
+
+func TestOAuth2SecretNotLogged(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	prev := ZapLog
+	ZapLog = zap.New(core)
+	defer func() { ZapLog = prev }()
+
+	s := newTestOAuthServer(t, 3600)
+	res := execHTTPJob(t, s.jobConfig())
+	s.revokeCurrent()
+	res2 := execHTTPJob(t, s.jobConfig())
+
+	if logs.FilterMessage("OAuth2令牌已获取").Len() != 2 {
+		t.Fatalf("应记录 2 次令牌获取，实际日志: %v", logs.All())
+	}
+	for _, secret := range []string{testOAuthSecret, "tok-1", "tok-2"} {
+		for _, summary := range []string{res.Summary, res2.Summary} {
+			if strings.Contains(summary, secret) {
+				t.Fatalf("执行摘要包含密钥 %q:\n%s", secret, summary)
+			}
+		}
+		for _, entry := range logs.All() {
+			text := entry.Message + fmt.Sprint(entry.ContextMap())
+			if strings.Contains(text, secret) {
+				t.Fatalf("日志包含密钥 %q: %s", secret, text)
+			}
+		}
+	}
+}
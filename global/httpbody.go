@@ -0,0 +1,236 @@
+package global
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// HTTP任务请求体
+// 【body_type】指定请求体类型，未指定时：配置了【files】为 multipart，【body_file】为 file，
+// 【form】为 form，【data】是合法JSON为 json，否则为 raw。
+// 未在【headers】中指定 Content-Type 时按类型自动设置；multipart 的 Content-Type 含分隔符，始终自动设置。
+
+// 请求体类型
+const (
+	HTTPBodyRaw       = "raw"
+	HTTPBodyJSON      = "json"
+	HTTPBodyForm      = "form"
+	HTTPBodyMultipart = "multipart"
+	HTTPBodyFile      = "file"
+)
+
+// 支持的请求体类型
+var httpBodyTypes = []string{HTTPBodyRaw, HTTPBodyJSON, HTTPBodyForm, HTTPBodyMultipart, HTTPBodyFile}
+
+// httpRequestBody 构建好的请求体，多次请求时重复使用
+type httpRequestBody struct {
+	Type        string
+	ContentType string // 自动设置的 Content-Type，为空不设置
+	Data        []byte
+}
+
+// parseHTTPPairs 解析 名称=值|||名称=值 格式，写入 dst
+func parseHTTPPairs(value string, dst map[string]string) {
+	for _, pair := range strings.Split(value, "|||") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		if k, v, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(k) != "" {
+			dst[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+}
+
+// httpBodyType 确定请求体类型
+func httpBodyType(c *HTTPConfig) string {
+	if c.BodyType != "" {
+		return strings.ToLower(c.BodyType)
+	}
+	switch {
+	case len(c.Files) > 0:
+		return HTTPBodyMultipart
+	case c.BodyFile != "":
+		return HTTPBodyFile
+	case len(c.Form) > 0:
+		return HTTPBodyForm
+	case isJSONDocument(c.Data):
+		return HTTPBodyJSON
+	}
+	return HTTPBodyRaw
+}
+
+// isJSONDocument 是否为JSON对象或数组（纯数字/字符串仍按 raw 处理）
+func isJSONDocument(s string) bool {
+	s = strings.TrimSpace(s)
+	return (strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")) && json.Valid([]byte(s))
+}
+
+// httpConfigHasBody 是否配置了请求体
+func httpConfigHasBody(c *HTTPConfig) bool {
+	return c.Data != "" || len(c.Form) > 0 || len(c.Files) > 0 || c.BodyFile != ""
+}
+
+// httpMethodSendsBody GET/HEAD 不发送请求体（与旧版本行为一致）
+func httpMethodSendsBody(method string) bool {
+	return method != "GET" && method != "HEAD"
+}
+
+// httpBodyMaxBytes 请求体大小上限
+func httpBodyMaxBytes() int64 {
+	n := GetJobsConfigInt("jobs.http_body_max_bytes", 10*1024*1024)
+	if n <= 0 {
+		n = 10 * 1024 * 1024
+	}
+	return int64(n)
+}
+
+// buildHTTPBody 按配置构建请求体，未配置请求体时返回 nil
+func buildHTTPBody(c *HTTPConfig) (*httpRequestBody, error) {
+	if !httpConfigHasBody(c) {
+		return nil, nil
+	}
+	body := &httpRequestBody{Type: httpBodyType(c)}
+	switch body.Type {
+	case HTTPBodyRaw:
+		body.Data = []byte(c.Data)
+		// 推断为 raw 时与旧版本一致不设置 Content-Type，只有显式声明 raw 时才设置
+		if strings.EqualFold(strings.TrimSpace(c.BodyType), HTTPBodyRaw) {
+			body.ContentType = "text/plain; charset=utf-8"
+		}
+	case HTTPBodyJSON:
+		if !json.Valid([]byte(c.Data)) {
+			return nil, fmt.Errorf("请求体不是合法的JSON")
+		}
+		body.Data = []byte(c.Data)
+		body.ContentType = "application/json; charset=utf-8"
+	case HTTPBodyForm:
+		if len(c.Form) > 0 {
+			values := url.Values{}
+			for k, v := range c.Form {
+				values.Set(k, v)
+			}
+			body.Data = []byte(values.Encode())
+		} else {
+			// 未配置【form】时【data】视为已编码的表单
+			body.Data = []byte(c.Data)
+		}
+		body.ContentType = "application/x-www-form-urlencoded"
+	case HTTPBodyMultipart:
+		data, contentType, err := buildMultipartBody(c)
+		if err != nil {
+			return nil, err
+		}
+		body.Data, body.ContentType = data, contentType
+	case HTTPBodyFile:
+		if c.BodyFile == "" {
+			return nil, fmt.Errorf("file 类型请求体需配置 body_file")
+		}
+		data, err := readHTTPBodyFile(c.BodyFile)
+		if err != nil {
+			return nil, err
+		}
+		body.Data = data
+		body.ContentType = fileContentType(c.BodyFile)
+	default:
+		return nil, fmt.Errorf("不支持的请求体类型: %s", body.Type)
+	}
+	if int64(len(body.Data)) > httpBodyMaxBytes() {
+		return nil, fmt.Errorf("请求体 %d 字节，超过上限 %d 字节", len(body.Data), httpBodyMaxBytes())
+	}
+	return body, nil
+}
+
+// buildMultipartBody 构建 multipart/form-data 请求体，字段与文件按名称排序
+func buildMultipartBody(c *HTTPConfig) ([]byte, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, k := range sortedKeys(c.Form) {
+		if err := w.WriteField(k, c.Form[k]); err != nil {
+			return nil, "", err
+		}
+	}
+	for _, field := range sortedKeys(c.Files) {
+		path := c.Files[field]
+		data, err := readHTTPBodyFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+		h := make(textproto.MIMEHeader)
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(field), quoteEscaper.Replace(filepath.Base(path))))
+		h.Set("Content-Type", fileContentType(path))
+		part, err := w.CreatePart(h)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, "", err
+		}
+		if int64(buf.Len()) > httpBodyMaxBytes() {
+			return nil, "", fmt.Errorf("请求体超过上限 %d 字节", httpBodyMaxBytes())
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), w.FormDataContentType(), nil
+}
+
+// 与 mime/multipart 相同的引号转义
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// readHTTPBodyFile 读取请求体文件，超过上限时报错
+func readHTTPBodyFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取请求体文件失败: %v", err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("请求体文件是目录: %s", path)
+	}
+	if info.Size() > httpBodyMaxBytes() {
+		return nil, fmt.Errorf("文件 %s 共 %d 字节，超过请求体上限 %d 字节", path, info.Size(), httpBodyMaxBytes())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取请求体文件失败: %v", err)
+	}
+	return data, nil
+}
+
+// fileContentType 按扩展名推断文件类型
+func fileContentType(path string) string {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// headerValue 不区分大小写查找请求头
+func headerValue(headers map[string]string, name string) (string, bool) {
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,202 @@
+package global
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"xiaohuAdmin/models/jobs"
+)
+
+// HTTP任务输出提取（【extract】名称=表达式|||名称=表达式，可多行）
+//
+//	【extract】price=json:$.data.price|||queue=regex:queued=(\d+)
+//	【extract】etag=header:ETag|||code=status
+//	【extract_metrics】true
+//
+// 表达式与工作流步骤的 outputs 相同：json:路径、regex:表达式（有捕获组时取第一组）、header:名称、status、output。
+// 提取结果写入执行登记（可按任务查询时间序列）与聚合日志，可在通知模板中以 {{.Outputs.price}} 引用；
+// 开启 extract_metrics 后数值结果同时写入 Prometheus 指标 jobs_http_extract_value。
+
+var extractNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// extractResponseValue 按表达式从响应中提取值
+func extractResponseValue(expr, body string, header http.Header, code int) (string, error) {
+	kind, arg, _ := strings.Cut(strings.TrimSpace(expr), ":")
+	switch strings.ToLower(kind) {
+	case "json":
+		v, err := jsonPathLookup(body, arg)
+		if err != nil {
+			return "", err
+		}
+		return jsonValueString(v), nil
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return "", fmt.Errorf("正则表达式无效: %v", err)
+		}
+		m := re.FindStringSubmatch(body)
+		if m == nil {
+			return "", fmt.Errorf("正则未匹配: %s", arg)
+		}
+		if len(m) > 1 {
+			return m[1], nil
+		}
+		return m[0], nil
+	case "header":
+		if header == nil {
+			return "", fmt.Errorf("非HTTP步骤没有响应头")
+		}
+		v := header.Get(arg)
+		if v == "" {
+			return "", fmt.Errorf("响应头不存在: %s", arg)
+		}
+		return v, nil
+	case "status":
+		return strconv.Itoa(code), nil
+	case "output":
+		return body, nil
+	}
+	return "", fmt.Errorf("不支持的提取方式: %s", expr)
+}
+
+// validateExtractExpr 校验提取表达式
+func validateExtractExpr(expr string) error {
+	kind, arg, _ := strings.Cut(strings.TrimSpace(expr), ":")
+	switch strings.ToLower(kind) {
+	case "json", "header":
+		if strings.TrimSpace(arg) == "" {
+			return fmt.Errorf("%s: 后缺少路径或名称", kind)
+		}
+		if strings.EqualFold(kind, "json") {
+			if _, err := splitJSONPath(arg); err != nil {
+				return err
+			}
+		}
+	case "regex":
+		if _, err := regexp.Compile(arg); err != nil {
+			return fmt.Errorf("正则表达式无效: %v", err)
+		}
+	case "status", "output":
+	default:
+		return fmt.Errorf("不支持的提取方式 %q，可选 json:/regex:/header:/status/output", expr)
+	}
+	return nil
+}
+
+// validateHTTPExtract 校验【extract】配置
+func validateHTTPExtract(report *JobConfigReport, line int, extract map[string]string) {
+	for _, name := range sortedKeys(extract) {
+		if !extractNamePattern.MatchString(name) {
+			report.errorf(line, "extract", "输出名称 %q 只能包含字母、数字与下划线，且不能以数字开头", name)
+			continue
+		}
+		if err := validateExtractExpr(extract[name]); err != nil {
+			report.errorf(line, "extract", "%s: %v", name, err)
+		}
+	}
+}
+
+// runHTTPExtract 执行全部提取，返回成功的结果与失败原因
+func runHTTPExtract(extract map[string]string, body string, header http.Header, code int) (map[string]string, map[string]string) {
+	outputs := make(map[string]string, len(extract))
+	failures := make(map[string]string)
+	for name, expr := range extract {
+		v, err := extractResponseValue(expr, body, header, code)
+		if err != nil {
+			failures[name] = err.Error()
+			continue
+		}
+		outputs[name] = v
+	}
+	return outputs, failures
+}
+
+// setExecOutputs 将提取结果写入执行登记
+func setExecOutputs(execID string, outputs map[string]string) {
+	if DB == nil || len(outputs) == 0 {
+		return
+	}
+	data, err := json.Marshal(outputs)
+	if err != nil {
+		return
+	}
+	if err := DB.Model(&jobs.JobExec{}).Where("exec_id=?", execID).Update("outputs", string(data)).Error; err != nil {
+		LogWarn("写入执行输出失败", LogField("exec_id", execID), LogError(err))
+	}
+}
+
+// OutputPoint 输出时间序列中的一个点
+type OutputPoint struct {
+	ExecID  string            `json:"exec_id"`
+	Time    time.Time         `json:"time"`
+	Status  string            `json:"status"`
+	Value   *float64          `json:"value,omitempty"`   // 指定 name 且为数值时
+	Raw     string            `json:"raw,omitempty"`     // 指定 name 时的原始值
+	Outputs map[string]string `json:"outputs,omitempty"` // 未指定 name 时的全部输出
+}
+
+// JobOutputSeries 查询任务提取输出的时间序列（按时间正序），name 为空时返回全部输出
+func JobOutputSeries(jobID uint, name string, since time.Time, limit int) ([]OutputPoint, error) {
+	if limit <= 0 || limit > 5000 {
+		limit = 500
+	}
+	q := DB.Model(&jobs.JobExec{}).Where("job_id = ? AND outputs <> ''", jobID)
+	if !since.IsZero() {
+		q = q.Where("started_at >= ?", since)
+	}
+	var rows []jobs.JobExec
+	if err := q.Order("started_at DESC").Limit(limit).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	points := make([]OutputPoint, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
+		r := rows[i]
+		var outputs map[string]string
+		if err := json.Unmarshal([]byte(r.Outputs), &outputs); err != nil {
+			continue
+		}
+		p := OutputPoint{ExecID: r.ExecID, Time: r.StartedAt, Status: r.Status}
+		if name == "" {
+			p.Outputs = outputs
+		} else {
+			v, ok := outputs[name]
+			if !ok {
+				continue
+			}
+			p.Raw = v
+			if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+				p.Value = &f
+			}
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// describeOutputs 提取结果摘要（按名称排序）
+func describeOutputs(outputs, failures map[string]string) string {
+	var b strings.Builder
+	names := make([]string, 0, len(outputs)+len(failures))
+	for k := range outputs {
+		names = append(names, k)
+	}
+	for k := range failures {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	b.WriteString("\n提取结果:")
+	for _, name := range names {
+		if v, ok := outputs[name]; ok {
+			b.WriteString(fmt.Sprintf("\n  %s = %s", name, previewText(v, 200)))
+		} else {
+			b.WriteString(fmt.Sprintf("\n  %s: 提取失败 - %s", name, failures[name]))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,167 @@
+package global
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// HTTP任务代理
+//
+//	【proxy】http://user:secret:proxy_pass@proxy.internal:3128
+//	【proxy】socks5h://user:env:SOCKS_PASS@10.0.0.2:1080
+//	【no_proxy】.internal,10.0.0.0/8,localhost
+//	【proxy】direct
+//
+// 支持 http/https/socks5/socks5h：用户名与密码取自代理地址，密码可以是 env:/secret: 引用；
+// socks5 在本机解析目标域名，socks5h 交由代理解析。未配置【proxy】时使用全局 jobs.http_proxy，
+// direct 表示本任务不使用代理。不代理列表与 NO_PROXY 环境变量格式相同（域名后缀、IP、CIDR、主机:端口），
+// localhost 与回环地址始终直连。
+
+// ProxyDirect 【proxy】direct 表示忽略全局代理
+const ProxyDirect = "direct"
+
+// httpProxySettings 本次执行实际使用的代理
+type httpProxySettings struct {
+	URL     string // 解析引用后的代理地址，为空表示直连
+	Display string // 隐藏密码后的代理地址
+	NoProxy string // 不代理列表
+	Direct  bool   // 【proxy】direct
+}
+
+// effectiveHTTPProxy 任务代理优先，未配置时使用全局代理
+func effectiveHTTPProxy(c *HTTPConfig) (*httpProxySettings, error) {
+	s := &httpProxySettings{NoProxy: c.NoProxy}
+	raw := strings.TrimSpace(c.Proxy)
+	if strings.EqualFold(raw, ProxyDirect) {
+		s.Direct = true
+		return s, nil
+	}
+	if raw == "" {
+		raw = strings.TrimSpace(Viper.GetString("jobs.http_proxy"))
+	}
+	if s.NoProxy == "" {
+		s.NoProxy = strings.TrimSpace(Viper.GetString("jobs.http_no_proxy"))
+	}
+	if raw == "" {
+		return s, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("解析代理URL失败: %v", err)
+	}
+	s.Display = u.Redacted()
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok && IsSecretRef(pass) {
+			resolved, err := ResolveSecret(pass)
+			if err != nil {
+				return nil, fmt.Errorf("代理密码: %v", err)
+			}
+			u.User = url.UserPassword(u.User.Username(), resolved)
+		}
+	}
+	s.URL = u.String()
+	return s, nil
+}
+
+// RedactProxy 隐藏代理地址中的密码
+func RedactProxy(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+// describe 代理摘要
+func (s *httpProxySettings) describe() string {
+	switch {
+	case s.Direct:
+		return "直连（忽略全局代理）"
+	case s.URL == "":
+		return "无代理"
+	case s.NoProxy != "":
+		return fmt.Sprintf("使用代理 %s（不代理: %s）", s.Display, s.NoProxy)
+	}
+	return "使用代理 " + s.Display
+}
+
+// applyHTTPProxy 为 Transport 配置代理，dial 为直连拨号函数，返回经过代理的拨号函数
+func applyHTTPProxy(transport *http.Transport, dialer *net.Dialer, proxyAddr, noProxy string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	dial := dialer.DialContext
+	if proxyAddr == "" {
+		return dial, nil
+	}
+	proxyURL, err := url.Parse(proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("解析代理URL失败: %v", err)
+	}
+	// 按不代理列表决定是否走代理，返回 nil 表示直连
+	proxyFor := (&httpproxy.Config{HTTPProxy: proxyAddr, HTTPSProxy: proxyAddr, NoProxy: noProxy}).ProxyFunc()
+
+	switch scheme := strings.ToLower(proxyURL.Scheme); scheme {
+	case "http", "https":
+		// 代理地址中的用户名密码由 http.Transport 以 Proxy-Authorization 发送
+		transport.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxyFor(req.URL)
+		}
+		return dial, nil
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			pass, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{User: proxyURL.User.Username(), Password: pass}
+		}
+		socks, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, dialer)
+		if err != nil {
+			return nil, fmt.Errorf("创建SOCKS代理拨号器失败: %v", err)
+		}
+		socksDial := socks.(proxy.ContextDialer).DialContext
+		return func(ctx context.Context, network, addr string) (net.Conn, error) {
+			if u, _ := proxyFor(&url.URL{Scheme: "https", Host: addr}); u == nil {
+				return dial(ctx, network, addr)
+			}
+			if scheme == "socks5" {
+				resolved, err := resolveProxyTarget(ctx, addr)
+				if err != nil {
+					return nil, err
+				}
+				addr = resolved
+			}
+			return socksDial(ctx, network, addr)
+		}, nil
+	}
+	return nil, fmt.Errorf("不支持的代理协议 %q，可选 %s", proxyURL.Scheme, strings.Join(supportedProxySchemes, "/"))
+}
+
+// resolveProxyTarget socks5 在本机解析目标域名（优先IPv4）
+func resolveProxyTarget(ctx context.Context, addr string) (string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(host) != nil {
+		return addr, nil
+	}
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("解析目标地址失败: %v", err)
+	}
+	if len(ips) == 0 {
+		return "", fmt.Errorf("解析目标地址失败: %s 没有可用的IP", host)
+	}
+	ip := ips[0].IP
+	for _, a := range ips {
+		if a.IP.To4() != nil {
+			ip = a.IP
+			break
+		}
+	}
+	return net.JoinHostPort(ip.String(), port), nil
+}
@@ -0,0 +1,189 @@
+package global
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// HTTP任务限流
+//
+// 服务端限流：响应为 429/503 且带 Retry-After 或 X-RateLimit-Reset（RateLimit-Reset）时，
+// 下一次请求前按服务端要求等待（不少于【interval】），等待时长超过【max_retry_wait】
+// （默认 jobs.http_max_retry_wait_seconds）或执行截止时间时不再等待，本次执行失败并注明原因。
+// 服务端要求的等待同时作用于同一主机的其他任务。
+//
+// 客户端限流：jobs.http_host_rate_limits 按主机配置每秒请求数（host 以 . 开头匹配子域名），
+// 未配置的主机使用 jobs.http_host_rate_limit（0 不限制）；同一主机的所有任务共享限速。
+
+// retryAfterDelay 解析限流响应要求的等待时长，ok 为 false 表示不是限流响应或未给出时长
+func retryAfterDelay(resp *http.Response, now time.Time) (time.Duration, string, bool) {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0, "", false
+	}
+	if v := strings.TrimSpace(resp.Header.Get("Retry-After")); v != "" {
+		if secs, err := strconv.ParseFloat(v, 64); err == nil && secs >= 0 {
+			return time.Duration(secs * float64(time.Second)), "Retry-After", true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return clampDelay(t.Sub(now)), "Retry-After", true
+		}
+	}
+	for _, name := range []string{"X-RateLimit-Reset", "RateLimit-Reset"} {
+		v := strings.TrimSpace(resp.Header.Get(name))
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 {
+			continue
+		}
+		switch {
+		case n > 1e12:
+			// 毫秒时间戳
+			return clampDelay(time.UnixMilli(int64(n)).Sub(now)), name, true
+		case n > 1e9:
+			// 秒级时间戳
+			return clampDelay(time.Unix(int64(n), 0).Sub(now)), name, true
+		default:
+			// 剩余秒数
+			return time.Duration(n * float64(time.Second)), name, true
+		}
+	}
+	return 0, "", false
+}
+
+func clampDelay(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// httpMaxRetryWait 任务允许的最长限流等待
+func httpMaxRetryWait(c *HTTPConfig) time.Duration {
+	secs := c.MaxRetryWait
+	if secs <= 0 {
+		secs = GetJobsConfigInt("jobs.http_max_retry_wait_seconds", 60)
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// checkRetryWait 判断限流等待是否可行，不可行时返回原因
+func checkRetryWait(ctx context.Context, wait, max time.Duration) error {
+	if wait > max {
+		return fmt.Errorf("目标限流，要求等待 %s，超过允许的最长等待 %s", formatWait(wait), formatWait(max))
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return fmt.Errorf("目标限流，要求等待 %s，超过执行截止时间（剩余 %s）", formatWait(wait), formatWait(time.Until(deadline)))
+	}
+	return nil
+}
+
+func formatWait(d time.Duration) string {
+	if d >= time.Second {
+		return fmt.Sprintf("%.0f秒", math.Ceil(d.Seconds()))
+	}
+	return fmt.Sprintf("%dms", d.Milliseconds())
+}
+
+// HostRateLimit 主机限速配置（config.yaml 中 jobs.http_host_rate_limits 列表项）
+type HostRateLimit struct {
+	Host string  `mapstructure:"host" json:"host"` // 主机名，以 . 开头匹配子域名
+	RPS  float64 `mapstructure:"rps" json:"rps"`   // 每秒请求数，0 不限制
+}
+
+var (
+	hostLimitersMu sync.Mutex
+	hostLimiters   = make(map[string]*rate.Limiter) // 主机名 -> 客户端限速
+	hostPauses     = make(map[string]time.Time)     // 主机:端口 -> 服务端要求的暂停截止时间
+)
+
+// hostRateLimit 主机的每秒请求数配置，0 表示不限制
+func hostRateLimit(host string) float64 {
+	cfg := GetGlobalConfig()
+	if cfg == nil {
+		return 0
+	}
+	// 精确匹配优先，以 . 开头的主机匹配子域名，取最长的匹配
+	best, bestLen := 0.0, 0
+	for _, l := range cfg.Jobs.HTTPHostRateLimits {
+		k := strings.ToLower(strings.TrimSpace(l.Host))
+		if k == host {
+			return l.RPS
+		}
+		if strings.HasPrefix(k, ".") && strings.HasSuffix(host, k) && len(k) > bestLen {
+			best, bestLen = l.RPS, len(k)
+		}
+	}
+	if bestLen > 0 {
+		return best
+	}
+	return cfg.Jobs.HTTPHostRateLimit
+}
+
+// getHostLimiter 获取主机的限速器，配置变化时更新速率，不限速时返回 nil
+func getHostLimiter(host string) *rate.Limiter {
+	host = strings.ToLower(host)
+	limit := hostRateLimit(host)
+	hostLimitersMu.Lock()
+	defer hostLimitersMu.Unlock()
+	l := hostLimiters[host]
+	burst := int(math.Max(1, math.Ceil(limit)))
+	switch {
+	case limit <= 0:
+		delete(hostLimiters, host)
+		return nil
+	case l == nil:
+		l = rate.NewLimiter(rate.Limit(limit), burst)
+		hostLimiters[host] = l
+	case l.Limit() != rate.Limit(limit):
+		l.SetLimit(rate.Limit(limit))
+		l.SetBurst(burst)
+	}
+	return l
+}
+
+// pauseHost 记录服务端要求的暂停，访问同一地址的其他任务也会等待
+func pauseHost(u *url.URL, until time.Time) {
+	key := strings.ToLower(u.Host)
+	hostLimitersMu.Lock()
+	defer hostLimitersMu.Unlock()
+	if until.After(hostPauses[key]) {
+		hostPauses[key] = until
+	}
+}
+
+// waitHostSlot 请求前等待服务端要求的暂停与主机限速，返回等待时长
+func waitHostSlot(ctx context.Context, u *url.URL, max time.Duration) (time.Duration, error) {
+	start := time.Now()
+	key := strings.ToLower(u.Host)
+	hostLimitersMu.Lock()
+	pause := time.Until(hostPauses[key])
+	if pause <= 0 {
+		delete(hostPauses, key)
+	}
+	hostLimitersMu.Unlock()
+	if pause > 0 {
+		if err := checkRetryWait(ctx, pause, max); err != nil {
+			return 0, err
+		}
+		if !sleepContext(ctx, pause) {
+			return time.Since(start), ctx.Err()
+		}
+	}
+	if limiter := getHostLimiter(u.Hostname()); limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return time.Since(start), fmt.Errorf("主机 %s 客户端限速等待失败: %v", u.Hostname(), err)
+		}
+	}
+	return time.Since(start), nil
+}
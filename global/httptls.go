@@ -0,0 +1,215 @@
+package global
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTP任务TLS配置（【tls】参数=值|||参数=值）
+//
+//	【tls】ca=/etc/pki/internal-ca.pem|||server_name=api.internal|||min_version=1.2
+//	【tls】cert=secret:client_cert|||key=secret:client_key
+//	【tls】insecure=true
+//
+// ca/cert/key 可以是文件路径、PEM 内容或 env:/file:/secret: 引用（引用解析后为 PEM 内容或路径）。
+// ca 追加到系统根证书之上；insecure 关闭证书校验，每次执行都会记录警告。
+
+// HTTPTLSConfig HTTP任务TLS配置
+type HTTPTLSConfig struct {
+	CA         string `json:"ca,omitempty"`          // CA证书（路径/PEM/引用）
+	Cert       string `json:"cert,omitempty"`        // 客户端证书（路径/PEM/引用）
+	Key        string `json:"key,omitempty"`         // 客户端私钥（路径/PEM/引用）
+	ServerName string `json:"server_name,omitempty"` // SNI 与证书校验使用的主机名
+	MinVersion string `json:"min_version,omitempty"` // 最低版本 1.0/1.1/1.2/1.3
+	Insecure   bool   `json:"insecure,omitempty"`    // 跳过证书校验（仅用于排查）
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// parseHTTPTLS 解析【tls】标签
+func parseHTTPTLS(value string) (*HTTPTLSConfig, error) {
+	params := make(map[string]string)
+	parseHTTPPairs(value, params)
+	c := &HTTPTLSConfig{}
+	for k, v := range params {
+		switch strings.ToLower(k) {
+		case "ca":
+			c.CA = v
+		case "cert":
+			c.Cert = v
+		case "key":
+			c.Key = v
+		case "server_name":
+			c.ServerName = v
+		case "min_version":
+			c.MinVersion = v
+		case "insecure":
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("insecure 应为 true 或 false，当前为 %q", v)
+			}
+			c.Insecure = b
+		default:
+			return nil, fmt.Errorf("未知的TLS参数: %s", k)
+		}
+	}
+	return c, nil
+}
+
+// loadPEM 读取证书/私钥：引用先解析，结果含 PEM 头时直接使用，否则作为文件路径读取
+func loadPEM(value string) ([]byte, error) {
+	v, err := ResolveSecret(value)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(v, "-----BEGIN") {
+		return []byte(v), nil
+	}
+	data, err := os.ReadFile(strings.TrimSpace(v))
+	if err != nil {
+		return nil, fmt.Errorf("读取证书文件失败: %v", err)
+	}
+	return data, nil
+}
+
+// tlsMaterial 已加载的证书内容
+type tlsMaterial struct {
+	CA, Cert, Key []byte
+}
+
+// loadTLSMaterial 读取CA、客户端证书与私钥
+func loadTLSMaterial(c *HTTPTLSConfig) (*tlsMaterial, error) {
+	m := &tlsMaterial{}
+	var err error
+	if c.CA != "" {
+		if m.CA, err = loadPEM(c.CA); err != nil {
+			return nil, fmt.Errorf("CA证书: %v", err)
+		}
+	}
+	if c.Cert != "" || c.Key != "" {
+		if c.Cert == "" || c.Key == "" {
+			return nil, fmt.Errorf("客户端证书需同时配置 cert 与 key")
+		}
+		if m.Cert, err = loadPEM(c.Cert); err != nil {
+			return nil, fmt.Errorf("客户端证书: %v", err)
+		}
+		if m.Key, err = loadPEM(c.Key); err != nil {
+			return nil, fmt.Errorf("客户端私钥: %v", err)
+		}
+	}
+	return m, nil
+}
+
+// buildTLSClientConfig 按任务配置构建 tls.Config
+func buildTLSClientConfig(c *HTTPTLSConfig) (*tls.Config, error) {
+	m, err := loadTLSMaterial(c)
+	if err != nil {
+		return nil, err
+	}
+	return buildTLSClientConfigFrom(c, m)
+}
+
+// buildTLSClientConfigFrom 由已加载的证书内容构建 tls.Config
+func buildTLSClientConfigFrom(c *HTTPTLSConfig, m *tlsMaterial) (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.Insecure,
+	}
+	if c.MinVersion != "" {
+		v, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(c.MinVersion), "tls")]
+		if !ok {
+			return nil, fmt.Errorf("不支持的TLS版本: %s，可选 1.0/1.1/1.2/1.3", c.MinVersion)
+		}
+		cfg.MinVersion = v
+	}
+	if len(m.CA) > 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(m.CA) {
+			return nil, fmt.Errorf("CA证书中没有可用的PEM证书")
+		}
+		cfg.RootCAs = pool
+	}
+	if len(m.Cert) > 0 {
+		pair, err := tls.X509KeyPair(m.Cert, m.Key)
+		if err != nil {
+			return nil, fmt.Errorf("客户端证书与私钥不匹配或格式错误: %v", err)
+		}
+		cfg.Certificates = []tls.Certificate{pair}
+	}
+	return cfg, nil
+}
+
+// validateHTTPTLS 校验TLS配置，证书在当前主机无法加载时给出警告
+func validateHTTPTLS(report *JobConfigReport, line int, c *HTTPTLSConfig) {
+	if c.MinVersion != "" {
+		if _, ok := tlsVersions[strings.TrimPrefix(strings.ToLower(c.MinVersion), "tls")]; !ok {
+			report.errorf(line, "tls", "不支持的TLS版本: %s，可选 1.0/1.1/1.2/1.3", c.MinVersion)
+			return
+		}
+	}
+	if (c.Cert == "") != (c.Key == "") {
+		report.errorf(line, "tls", "客户端证书需同时配置 cert 与 key")
+		return
+	}
+	if c.Key != "" && strings.Contains(c.Key, "-----BEGIN") {
+		report.warnf(line, "tls", "私钥以明文保存在任务配置中，建议使用 env:/file:/secret: 引用")
+	}
+	if c.Insecure {
+		report.warnf(line, "tls", "insecure=true 将跳过证书校验，仅建议临时排查使用")
+	}
+	if _, err := buildTLSClientConfig(c); err != nil {
+		report.warnf(line, "tls", "当前主机无法加载TLS配置: %v", err)
+	}
+}
+
+// describe TLS配置摘要
+func (c *HTTPTLSConfig) describe() string {
+	var parts []string
+	if c.CA != "" {
+		parts = append(parts, "自定义CA")
+	}
+	if c.Cert != "" {
+		parts = append(parts, "客户端证书")
+	}
+	if c.ServerName != "" {
+		parts = append(parts, "SNI "+c.ServerName)
+	}
+	if c.MinVersion != "" {
+		parts = append(parts, "最低版本 "+c.MinVersion)
+	}
+	if c.Insecure {
+		parts = append(parts, "跳过证书校验")
+	}
+	return strings.Join(parts, "，")
+}
+
+// tlsResult 本次连接协商的TLS信息
+type tlsResult struct {
+	Version    string
+	CertExpiry time.Time
+}
+
+// tlsStateResult 从连接状态中提取TLS版本与服务端证书到期时间
+func tlsStateResult(state *tls.ConnectionState) *tlsResult {
+	if state == nil {
+		return nil
+	}
+	r := &tlsResult{Version: tls.VersionName(state.Version)}
+	if len(state.PeerCertificates) > 0 {
+		r.CertExpiry = state.PeerCertificates[0].NotAfter
+	}
+	return r
+}
@@ -0,0 +1,286 @@
+package global
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// HTTP任务连接复用
+// 相同有效设置（代理、TLS）的任务共享同一个 http.Transport，保留 keep-alive 连接与 TLS 会话；
+// 超过 jobs.http_transport_idle_seconds 未使用的 Transport 会被关闭并移出缓存。
+
+// httpTransportSettings 决定 Transport 的有效设置
+type httpTransportSettings struct {
+	Proxy   string // 解析引用后的代理地址
+	NoProxy string
+	TLS     *HTTPTLSConfig
+	tls     *tlsMaterial // 已加载的证书内容，证书文件更新后生成新的 Transport
+}
+
+// key 缓存键（摘要，不含明文凭证）
+func (s *httpTransportSettings) key() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "proxy=%s\nno_proxy=%s\n", s.Proxy, s.NoProxy)
+	if s.TLS != nil {
+		fmt.Fprintf(h, "tls=%s|%s|%t\n", s.TLS.ServerName, s.TLS.MinVersion, s.TLS.Insecure)
+		if s.tls != nil {
+			h.Write(s.tls.CA)
+			h.Write([]byte{0})
+			h.Write(s.tls.Cert)
+			h.Write([]byte{0})
+			h.Write(s.tls.Key)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cachedTransport 缓存的 Transport
+type cachedTransport struct {
+	transport *http.Transport
+	lastUsed  time.Time
+	inUse     int
+}
+
+// httpTransportCache Transport 缓存
+type httpTransportCache struct {
+	mu      sync.Mutex
+	entries map[string]*cachedTransport
+}
+
+var httpTransports = &httpTransportCache{entries: make(map[string]*cachedTransport)}
+
+// HTTPPoolStats 连接池统计
+type HTTPPoolStats struct {
+	Transports    int   `json:"transports"`     // 缓存中的 Transport 数
+	InUse         int   `json:"in_use"`         // 正在使用的 Transport 数
+	CacheHits     int64 `json:"cache_hits"`     // 复用 Transport 次数
+	CacheMisses   int64 `json:"cache_misses"`   // 新建 Transport 次数
+	Evictions     int64 `json:"evictions"`      // 因空闲被关闭的 Transport 数
+	OpenConns     int64 `json:"open_conns"`     // 当前打开的连接数
+	Dials         int64 `json:"dials"`          // 新建连接次数
+	ReusedConns   int64 `json:"reused_conns"`   // 复用连接的请求数
+	TLSHandshakes int64 `json:"tls_handshakes"` // TLS握手次数
+}
+
+var httpPoolCounters struct {
+	hits, misses, evictions, openConns, dials, reused, handshakes atomic.Int64
+}
+
+// countPoolEvent 累计连接池事件
+func countPoolEvent(counter *atomic.Int64, event string) {
+	counter.Add(1)
+	httpPoolEventsTotal.WithLabelValues(event).Inc()
+}
+
+func (c *httpTransportCache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+var httpTransportJanitorOnce sync.Once
+
+// acquire 获取（或新建）设置对应的 Transport，用完后调用 release
+func (c *httpTransportCache) acquire(s *httpTransportSettings) (*http.Transport, func(), error) {
+	httpTransportJanitorOnce.Do(startHTTPTransportJanitor)
+	key := s.key()
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		entry.inUse++
+		entry.lastUsed = time.Now()
+		c.mu.Unlock()
+		countPoolEvent(&httpPoolCounters.hits, "transport_hit")
+	} else {
+		c.mu.Unlock()
+		transport, err := newHTTPTransport(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.mu.Lock()
+		// 并发新建时以先放入缓存的为准
+		if existing, ok := c.entries[key]; ok {
+			transport.CloseIdleConnections()
+			entry = existing
+		} else {
+			entry = &cachedTransport{transport: transport}
+			c.entries[key] = entry
+		}
+		entry.inUse++
+		entry.lastUsed = time.Now()
+		c.mu.Unlock()
+		countPoolEvent(&httpPoolCounters.misses, "transport_miss")
+	}
+
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			c.mu.Lock()
+			entry.inUse--
+			entry.lastUsed = time.Now()
+			c.mu.Unlock()
+		})
+	}
+	return entry.transport, release, nil
+}
+
+// evictIdle 关闭空闲超时的 Transport
+func (c *httpTransportCache) evictIdle(idle time.Duration) int {
+	c.mu.Lock()
+	var closing []*http.Transport
+	for key, entry := range c.entries {
+		if entry.inUse == 0 && time.Since(entry.lastUsed) >= idle {
+			closing = append(closing, entry.transport)
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+	for _, t := range closing {
+		t.CloseIdleConnections()
+		countPoolEvent(&httpPoolCounters.evictions, "transport_evict")
+	}
+	return len(closing)
+}
+
+// stats 连接池统计
+func (c *httpTransportCache) stats() HTTPPoolStats {
+	c.mu.Lock()
+	st := HTTPPoolStats{Transports: len(c.entries)}
+	for _, entry := range c.entries {
+		if entry.inUse > 0 {
+			st.InUse++
+		}
+	}
+	c.mu.Unlock()
+	st.CacheHits = httpPoolCounters.hits.Load()
+	st.CacheMisses = httpPoolCounters.misses.Load()
+	st.Evictions = httpPoolCounters.evictions.Load()
+	st.OpenConns = httpPoolCounters.openConns.Load()
+	st.Dials = httpPoolCounters.dials.Load()
+	st.ReusedConns = httpPoolCounters.reused.Load()
+	st.TLSHandshakes = httpPoolCounters.handshakes.Load()
+	return st
+}
+
+// GetHTTPPoolStats HTTP任务连接池统计
+func GetHTTPPoolStats() HTTPPoolStats {
+	return httpTransports.stats()
+}
+
+// httpTransportIdle Transport 空闲多久后关闭
+func httpTransportIdle() time.Duration {
+	secs := GetJobsConfigInt("jobs.http_transport_idle_seconds", 300)
+	if secs <= 0 {
+		secs = 300
+	}
+	return time.Duration(secs) * time.Second
+}
+
+// startHTTPTransportJanitor 定期清理空闲 Transport（首次使用时启动）
+func startHTTPTransportJanitor() {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n := httpTransports.evictIdle(httpTransportIdle()); n > 0 && ZapLog != nil {
+				ZapLog.Info("已关闭空闲的HTTP连接池", LogField("count", n))
+			}
+		}
+	}()
+}
+
+// newHTTPTransport 按设置新建 Transport
+func newHTTPTransport(s *httpTransportSettings) (*http.Transport, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		DisableCompression:  false,
+		ForceAttemptHTTP2:   true,
+	}
+	if s.TLS != nil {
+		tlsCfg, err := buildTLSClientConfigFrom(s.TLS, s.tls)
+		if err != nil {
+			return nil, fmt.Errorf("TLS配置错误: %v", err)
+		}
+		transport.TLSClientConfig = tlsCfg
+	}
+
+	dial, err := applyHTTPProxy(transport, dialer, s.Proxy, s.NoProxy)
+	if err != nil {
+		return nil, err
+	}
+
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		countPoolEvent(&httpPoolCounters.dials, "dial")
+		httpPoolCounters.openConns.Add(1)
+		return &countedConn{Conn: conn}, nil
+	}
+	return transport, nil
+}
+
+// countedConn 关闭时减少打开连接计数
+type countedConn struct {
+	net.Conn
+	closed atomic.Bool
+}
+
+func (c *countedConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		httpPoolCounters.openConns.Add(-1)
+	}
+	return c.Conn.Close()
+}
+
+// httpConnTrace 记录单次请求的连接复用与TLS握手
+type httpConnTrace struct {
+	reused       bool
+	tlsHandshake time.Duration
+	tlsStart     time.Time
+}
+
+// withConnTrace 为请求上下文挂载连接跟踪
+func (t *httpConnTrace) withConnTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.reused = info.Reused
+			if info.Reused {
+				countPoolEvent(&httpPoolCounters.reused, "conn_reused")
+			}
+		},
+		TLSHandshakeStart: func() { t.tlsStart = time.Now() },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				t.tlsHandshake = time.Since(t.tlsStart)
+				countPoolEvent(&httpPoolCounters.handshakes, "tls_handshake")
+			}
+		},
+	})
+}
+
+// describe 连接摘要
+func (t *httpConnTrace) describe() string {
+	if t.reused {
+		return "复用连接"
+	}
+	if t.tlsHandshake > 0 {
+		return fmt.Sprintf("新建连接（TLS握手 %dms）", t.tlsHandshake.Milliseconds())
+	}
+	return "新建连接"
+}
@@ -0,0 +1,108 @@
+package global
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"sync/atomic"
+	"testing"
+)
+
+// newTLSJobServer 启动 TLS 测试服务，返回信任其证书的 HTTP 任务配置
+func newTLSJobServer(tb testing.TB) (*httptest.Server, *HTTPConfig) {
+	tb.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	tb.Cleanup(srv.Close)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	config := &HTTPConfig{
+		URL:     srv.URL,
+		Mode:    "GET",
+		Timeout: 10,
+		Proxy:   ProxyDirect,
+		TLS:     &HTTPTLSConfig{CA: string(ca)},
+	}
+	return srv, config
+}
+
+// connCounter 通过 httptrace 统计新建连接与 TLS 握手
+type connCounter struct {
+	newConns, handshakes atomic.Int64
+}
+
+func (c *connCounter) context(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				c.newConns.Add(1)
+			}
+		},
+		TLSHandshakeStart: func() { c.handshakes.Add(1) },
+	})
+}
+
+func runHTTPJob(tb testing.TB, ctx context.Context, config *HTTPConfig) {
+	res, err := executeHTTPConfig(ctx, config, nil)
+	if err != nil {
+		tb.Fatalf("执行HTTP任务失败: %v", err)
+	}
+	if !res.Success {
+		tb.Fatalf("HTTP任务未成功: %s", res.Summary)
+	}
+}
+
+func TestHTTPJobReusesTransport(t *testing.T) {
+	_, config := newTLSJobServer(t)
+	var counter connCounter
+	ctx := counter.context(context.Background())
+	for i := 0; i < 5; i++ {
+		runHTTPJob(t, ctx, config)
+	}
+	if n := counter.handshakes.Load(); n != 1 {
+		t.Fatalf("5 次执行应只握手 1 次，实际 %d 次", n)
+	}
+	if n := counter.newConns.Load(); n != 1 {
+		t.Fatalf("5 次执行应只新建 1 个连接，实际 %d 个", n)
+	}
+
+	// 空闲淘汰后重新建立连接
+	httpTransports.evictIdle(0)
+	runHTTPJob(t, ctx, config)
+	if n := counter.handshakes.Load(); n != 2 {
+		t.Fatalf("淘汰后应重新握手，实际共 %d 次", n)
+	}
+}
+
+// BenchmarkHTTPJobTransport 对比共享 Transport 与每次执行新建 Transport
+// per_run 在每次执行前清空缓存，等同于改造前每次执行新建 Transport
+func BenchmarkHTTPJobTransport(b *testing.B) {
+	for _, bc := range []struct {
+		name   string
+		perRun bool
+	}{
+		{"cached", false},
+		{"per_run", true},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			_, config := newTLSJobServer(b)
+			httpTransports.evictIdle(0)
+			var counter connCounter
+			ctx := counter.context(context.Background())
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if bc.perRun {
+					httpTransports.evictIdle(0)
+				}
+				runHTTPJob(b, ctx, config)
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(counter.handshakes.Load())/float64(b.N), "handshakes/op")
+			b.ReportMetric(float64(counter.newConns.Load())/float64(b.N), "conns/op")
+			httpTransports.evictIdle(0)
+		})
+	}
+}
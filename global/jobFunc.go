@@ -1,6 +1,7 @@
 package global
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -196,33 +197,71 @@ func Database(args []string) (string, error) {
 	}
 }
 
-// Email 邮件发送函数（仅示例，未实现实际发送）
+// Email 邮件发送函数
+// 参数：收件人(多个用逗号分隔) 主题 内容 [渠道名]，未指定渠道时使用第一个 smtp 渠道
 func Email(args []string) (string, error) {
 	if len(args) < 3 {
 		return "", fmt.Errorf("Email函数需要至少3个参数: 收件人 主题 内容")
 	}
-	to := args[0]
-	subject := args[1]
-	// args[2] 为内容参数，暂未用
-	return fmt.Sprintf("发送邮件到: %s, 主题: %s", to, subject), nil
+	var ch NotifyChannelConfig
+	var ok bool
+	if len(args) > 3 {
+		ch, ok = GetNotifyChannel(args[3])
+	} else {
+		_, ch, ok = firstChannelOfType("smtp", "email")
+	}
+	if !ok {
+		return "", fmt.Errorf("未配置可用的邮件渠道（notify.channels 中 type: smtp）")
+	}
+	var to []string
+	for _, addr := range strings.Split(args[0], ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	if err := sendSMTP(ch, to, args[1], args[2]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("发送邮件到: %s, 主题: %s", strings.Join(to, ","), args[1]), nil
 }
 
-// SMS 短信发送函数（仅示例，未实现实际发送）
+// SMS 短信发送函数
+// 通过 notify.sms_channel 指定的 webhook 渠道转发到短信网关，请求体为 {"phone":"...","content":"..."}
 func SMS(args []string) (string, error) {
 	if len(args) < 2 {
 		return "", fmt.Errorf("SMS函数需要至少2个参数: 手机号 内容")
 	}
+	name := GetConfigString("notify.sms_channel")
+	if name == "" {
+		return "", fmt.Errorf("未配置短信渠道（notify.sms_channel）")
+	}
+	ch, ok := GetNotifyChannel(name)
+	if !ok {
+		return "", fmt.Errorf("短信渠道未配置: %s", name)
+	}
 	phone := args[0]
-	// args[1] 为内容参数，暂未用
+	payload := map[string]string{"phone": phone, "content": strings.Join(args[1:], " ")}
+	if err := postNotifyJSON(ch.URL, ch.Headers, payload); err != nil {
+		return "", err
+	}
 	return fmt.Sprintf("发送短信到: %s", phone), nil
 }
 
-// Webhook Webhook调用函数（仅示例，未实现实际调用）
+// Webhook Webhook调用函数
+// 参数：URL [内容...]，内容为合法JSON时原样发送，否则包装为 {"text":"..."}
 func Webhook(args []string) (string, error) {
 	if len(args) < 1 {
 		return "", fmt.Errorf("Webhook函数需要至少1个参数: URL")
 	}
 	url := args[0]
+	text := strings.Join(args[1:], " ")
+	var payload interface{} = map[string]string{"text": text}
+	if raw := json.RawMessage(text); text != "" && json.Valid(raw) {
+		payload = raw
+	}
+	if err := postNotifyJSON(url, nil, payload); err != nil {
+		return "", err
+	}
 	return fmt.Sprintf("调用Webhook: %s", url), nil
 }
 
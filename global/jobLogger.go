@@ -264,7 +264,7 @@ type JobExecLog struct {
 	EndTime    string   `json:"end_time"` // 任务结束时间
 	JobID      uint     `json:"job_id"`
 	JobName    string   `json:"job_name"`
-	Status     string   `json:"status"` // 成功/失败
+	Status     string   `json:"status"` // 成功/警告/失败
 	DurationMs int64    `json:"duration_ms"`
 	Mode       string   `json:"mode"`
 	ExecID     string   `json:"exec_id,omitempty"`
@@ -281,6 +281,17 @@ type JobExecLog struct {
 	FuncArgs   []string `json:"func_args,omitempty"`
 	FuncResult string   `json:"func_result,omitempty"`
 	ErrorMsg   string   `json:"error_msg,omitempty"`
+	Warning    string   `json:"warning,omitempty"` // 成功但被标记为警告的原因（命令/脚本退出码）
+
+	Artifacts  []ArtifactRef         `json:"artifacts,omitempty"`  // 大输出与收集的文件产物
+	Steps      []WorkflowStepLog     `json:"steps,omitempty"`      // 工作流逐步执行记录
+	Assertions []HTTPAssertionResult `json:"assertions,omitempty"` // HTTP响应断言结果
+	Hosts      []SSHHostResult       `json:"hosts,omitempty"`      // SSH任务逐台主机执行结果
+
+	TLSVersion    string `json:"tls_version,omitempty"`     // 协商的TLS版本
+	TLSCertExpiry string `json:"tls_cert_expiry,omitempty"` // 服务端证书到期时间
+
+	Outputs map[string]string `json:"outputs,omitempty"` // HTTP任务提取的输出
 }
 
 // 写入聚合日志
@@ -0,0 +1,264 @@
+package global
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"xiaohuAdmin/models/jobs"
+)
+
+// 结构化执行配置（Jobs.Config，JSON）
+// http/command/script/ssh/func 模式使用带版本号的 JSON 保存配置，取代【】行格式：
+//
+//	{"version":1,"http":{"url":"https://example.com","mode":"POST","data":"多行\n内容"}}
+//	{"version":1,"command":{"command":"/opt/backup.sh","env":["A=1"],"timeout":600},"artifacts":["/tmp/*.log"]}
+//	{"version":1,"script":{"interpreter":"python3","script":"import sys\nprint(sys.version)"}}
+//	{"version":1,"ssh":{"hosts":["10.0.0.11","10.0.0.12"],"user":"deploy","key":"secret:deploy_key","command":"uptime"}}
+//	{"version":1,"function":{"name":"Hello","args":["a"]}}
+//
+// Config 非空时以其为准；为空时仍按 Command 中的【】格式解析，保存时会自动转换为 Config。
+
+// JobConfigVersion 当前结构化配置版本
+const JobConfigVersion = 1
+
+// JobConfig 结构化执行配置
+type JobConfig struct {
+	Version   int             `json:"version"`
+	Artifacts []string        `json:"artifacts,omitempty"` // 执行后收集的产物文件，支持通配符
+	HTTP      *HTTPConfig     `json:"http,omitempty"`
+	Command   *CommandConfig  `json:"command,omitempty"`
+	Script    *ScriptConfig   `json:"script,omitempty"`
+	SSH       *SSHConfig      `json:"ssh,omitempty"`
+	Function  *FunctionConfig `json:"function,omitempty"`
+}
+
+// hasStructuredConfig 该模式是否使用结构化配置
+func hasStructuredConfig(mode string) bool {
+	switch mode {
+	case "http", "command", "script", "ssh", "func", "function":
+		return true
+	}
+	return false
+}
+
+// ParseJobConfig 解析结构化配置，并检查版本与模式是否匹配
+func ParseJobConfig(mode, raw string) (*JobConfig, error) {
+	var cfg JobConfig
+	dec := json.NewDecoder(strings.NewReader(strings.TrimSpace(raw)))
+	dec.DisallowUnknownFields() // 字段名拼写错误时报错，而不是静默忽略
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("执行配置解析失败: %v", err)
+	}
+	if cfg.Version == 0 {
+		cfg.Version = JobConfigVersion
+	}
+	if cfg.Version > JobConfigVersion {
+		return nil, fmt.Errorf("执行配置版本 %d 高于当前支持的版本 %d", cfg.Version, JobConfigVersion)
+	}
+	switch mode {
+	case "http":
+		if cfg.HTTP == nil {
+			return nil, fmt.Errorf("http 模式缺少 http 配置")
+		}
+		applyHTTPConfigDefaults(cfg.HTTP)
+		cfg.Command, cfg.Script, cfg.SSH, cfg.Function = nil, nil, nil, nil
+	case "command":
+		if cfg.Command == nil {
+			return nil, fmt.Errorf("command 模式缺少 command 配置")
+		}
+		applyCommandConfigDefaults(cfg.Command)
+		cfg.HTTP, cfg.Script, cfg.SSH, cfg.Function = nil, nil, nil, nil
+	case "script":
+		if cfg.Script == nil {
+			return nil, fmt.Errorf("script 模式缺少 script 配置")
+		}
+		applyScriptConfigDefaults(cfg.Script)
+		cfg.HTTP, cfg.Command, cfg.SSH, cfg.Function = nil, nil, nil, nil
+	case "ssh":
+		if cfg.SSH == nil {
+			return nil, fmt.Errorf("ssh 模式缺少 ssh 配置")
+		}
+		applySSHConfigDefaults(cfg.SSH)
+		cfg.HTTP, cfg.Command, cfg.Script, cfg.Function = nil, nil, nil, nil
+	case "func", "function":
+		if cfg.Function == nil {
+			return nil, fmt.Errorf("func 模式缺少 function 配置")
+		}
+		applyFunctionConfigDefaults(cfg.Function)
+		cfg.HTTP, cfg.Command, cfg.Script, cfg.SSH = nil, nil, nil, nil
+	default:
+		return nil, fmt.Errorf("%s 模式不使用结构化配置", mode)
+	}
+	return &cfg, nil
+}
+
+// BuildJobConfig 由【】格式生成结构化配置
+func BuildJobConfig(mode, command string) (*JobConfig, error) {
+	cfg := &JobConfig{Version: JobConfigVersion, Artifacts: parseArtifactPatterns(command)}
+	var err error
+	switch mode {
+	case "http":
+		cfg.HTTP, err = parseHTTPConfig(command)
+	case "command":
+		cfg.Command, err = parseCommandConfig(command)
+	case "script":
+		// 只在【script】之前的标签行中查找【artifacts】
+		header, _, _ := splitScriptCommand(command)
+		cfg.Artifacts = parseArtifactPatterns(header)
+		cfg.Script, err = parseScriptConfig(command)
+	case "ssh":
+		cfg.SSH, err = parseSSHConfig(command)
+	case "func", "function":
+		cfg.Function, err = parseFunctionConfig(command)
+	default:
+		return nil, fmt.Errorf("%s 模式不使用结构化配置", mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyHTTPConfigDefaults 补全JSON中省略的字段
+func applyHTTPConfigDefaults(c *HTTPConfig) {
+	if c.Headers == nil {
+		c.Headers = make(map[string]string)
+	}
+	c.Mode = strings.ToUpper(strings.TrimSpace(c.Mode))
+	if c.Mode == "" {
+		c.Mode = "GET"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = GetJobsConfigInt("jobs.default_timeout_seconds", 60)
+	}
+	if c.Auth != nil {
+		c.Auth.Type = strings.ToLower(strings.TrimSpace(c.Auth.Type))
+	}
+}
+
+func applyCommandConfigDefaults(c *CommandConfig) {
+	if c.Env == nil {
+		c.Env = make([]string, 0)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = GetJobsConfigInt("jobs.default_timeout_seconds", 30)
+	}
+}
+
+func applyFunctionConfigDefaults(c *FunctionConfig) {
+	if c.Args == nil {
+		c.Args = make([]string, 0)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = GetJobsConfigInt("jobs.default_timeout_seconds", 30)
+	}
+}
+
+// loadHTTPConfig 读取HTTP任务配置
+func loadHTTPConfig(job *Jobs) (*HTTPConfig, error) {
+	if strings.TrimSpace(job.Config) == "" {
+		return parseHTTPConfig(job.Command)
+	}
+	cfg, err := ParseJobConfig("http", job.Config)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.HTTP, nil
+}
+
+// loadCommandConfig 读取命令任务配置
+func loadCommandConfig(job *Jobs) (*CommandConfig, error) {
+	if strings.TrimSpace(job.Config) == "" {
+		return parseCommandConfig(job.Command)
+	}
+	cfg, err := ParseJobConfig("command", job.Config)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Command, nil
+}
+
+// loadFunctionConfig 读取函数任务配置
+func loadFunctionConfig(job *Jobs) (*FunctionConfig, error) {
+	if strings.TrimSpace(job.Config) == "" {
+		return parseFunctionConfig(job.Command)
+	}
+	cfg, err := ParseJobConfig("func", job.Config)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Function, nil
+}
+
+// jobArtifactPatterns 任务声明的产物文件
+func jobArtifactPatterns(job *Jobs) []string {
+	if strings.TrimSpace(job.Config) != "" && hasStructuredConfig(job.Mode) {
+		if cfg, err := ParseJobConfig(job.Mode, job.Config); err == nil {
+			return cfg.Artifacts
+		}
+		return nil
+	}
+	return parseArtifactPatterns(job.Command)
+}
+
+// PrepareJobConfig 保存前规范化执行配置（需先通过 ValidateJob）
+// 结构化模式：Config 非空时重新序列化，否则由 Command 转换；其他模式清空 Config
+func PrepareJobConfig(job *Jobs) error {
+	if !hasStructuredConfig(job.Mode) {
+		job.Config = ""
+		return nil
+	}
+	var cfg *JobConfig
+	var err error
+	if strings.TrimSpace(job.Config) != "" {
+		cfg, err = ParseJobConfig(job.Mode, job.Config)
+	} else {
+		cfg, err = BuildJobConfig(job.Mode, job.Command)
+	}
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	job.Config = string(data)
+	return nil
+}
+
+// MigrateJobConfigs 将仍使用【】格式的任务转换为结构化配置（启动时执行，Command 原样保留）
+func MigrateJobConfigs() {
+	if DB == nil {
+		return
+	}
+	var list []Jobs
+	if err := DB.Where("(config IS NULL OR config = '') AND mode IN (?)", []string{"http", "command", "script", "ssh", "func", "function"}).
+		Find(&list).Error; err != nil {
+		if ZapLog != nil {
+			ZapLog.Error("查询待迁移任务失败", LogError(err))
+		}
+		return
+	}
+	migrated := 0
+	for i := range list {
+		job := &list[i]
+		if err := PrepareJobConfig(job); err != nil {
+			if ZapLog != nil {
+				ZapLog.Warn("任务配置迁移失败，继续按【】格式执行",
+					LogField("id", job.ID), LogField("name", job.Name), LogError(err))
+			}
+			continue
+		}
+		if err := DB.Model(&jobs.Jobs{}).Where("id=?", job.ID).UpdateColumn("config", job.Config).Error; err != nil {
+			if ZapLog != nil {
+				ZapLog.Error("任务配置迁移写入失败", LogField("id", job.ID), LogError(err))
+			}
+			continue
+		}
+		migrated++
+	}
+	if migrated > 0 && ZapLog != nil {
+		ZapLog.Info("任务配置已迁移为结构化JSON", LogField("count", migrated))
+	}
+}
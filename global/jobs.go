@@ -6,7 +6,6 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"net/url"
 	"os"
@@ -16,15 +15,11 @@ import (
 	"strings"
 	"sync"
 	"time"
-	"unicode/utf8"
 
 	"xiaohuAdmin/models/jobs"
 
 	"github.com/google/uuid"
 	"github.com/robfig/cron/v3"
-	"golang.org/x/net/proxy"
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/transform"
 	"gorm.io/gorm"
 )
 
@@ -55,6 +50,11 @@ func InitJobs() {
 		return
 	}
 
+	// 清理上次进程遗留的执行记录
+	SweepOrphanExecs()
+	// 【】格式配置迁移为结构化JSON
+	MigrateJobConfigs()
+
 	taskMu.Lock()
 	TaskList = make(map[uint]cron.EntryID)
 	taskMu.Unlock()
@@ -69,7 +69,7 @@ func InitJobs() {
 	var dbJobs []Jobs
 	//查询待执行任务列表
 	query := DB.
-		Where("state IN (?)", []int{0, 1}).
+		Where("state IN (?)", SchedulableJobStates).
 		Find(&dbJobs)
 
 	if query.Error != nil {
@@ -103,6 +103,9 @@ func InitJobs() {
 	TimerRunning = true // 设置初始状态
 	runningMu.Unlock()
 
+	// 补跑上次停止时被中断的执行
+	RequeueInterruptedExecs()
+
 }
 
 // 修改停止方法
@@ -133,7 +136,7 @@ func StopTimer() {
 			runningMu.Lock()
 			TimerRunning = false
 			runningMu.Unlock()
-		case <-time.After(30 * time.Second):
+		case <-time.After(getDrainTimeout()):
 			pending := GetTaskCount()
 			if ZapLog != nil {
 				ZapLog.Error("停止任务超时，强制终止",
@@ -165,12 +168,40 @@ func CronExprCheck(spec string) error {
 	return nil
 }
 
-// 创建定时任务
-func CreateJob(job *Jobs) error {
+// ValidateJob 保存前校验任务配置
+func ValidateJob(job *Jobs) error {
 	// 验证cron表达式
 	if err := CronExprCheck(job.CronExpr); err != nil {
 		return fmt.Errorf("cron表达式验证失败: %v", err)
 	}
+	// 验证执行配置：结构化配置优先，否则校验【】格式
+	report := ValidateJobConfig(job.Mode, job.Command)
+	if strings.TrimSpace(job.Config) != "" && hasStructuredConfig(job.Mode) {
+		report = ValidateJobConfigJSON(job.Mode, job.Config)
+	}
+	if !report.Valid {
+		return &JobConfigError{Report: report}
+	}
+	// 验证通知规则
+	if err := ValidateNotifyRules(job.Notify); err != nil {
+		return fmt.Errorf("通知规则验证失败: %v", err)
+	}
+	// 验证熔断配置
+	if err := ValidateBreakerConfig(job.Breaker); err != nil {
+		return fmt.Errorf("熔断配置验证失败: %v", err)
+	}
+	return nil
+}
+
+// 创建定时任务
+func CreateJob(job *Jobs) error {
+	if err := ValidateJob(job); err != nil {
+		return err
+	}
+	if err := PrepareJobConfig(job); err != nil {
+		return err
+	}
+	EnsurePingToken(job)
 
 	// 新增任务到数据库
 	if err := DB.Create(&job).Error; err != nil {
@@ -205,22 +236,7 @@ func CreateJob(job *Jobs) error {
 
 // 新增定时任务到调度器
 func AddJob(job *Jobs) error {
-	// 根据 AllowMode 设置并发策略（支持全局默认）
-	allow := job.AllowMode
-	if allow == 0 { // 0 表示并行；若全局配置指定了1或2，则作为默认
-		cfgDefault := GetJobsConfigInt("jobs.default_allow_mode", 0)
-		if cfgDefault == 1 || cfgDefault == 2 {
-			allow = cfgDefault
-		}
-	}
-	var j cron.Job = handle_Jobs(job)
-	switch allow {
-	case 1: // 串行，仍在执行时跳过
-		j = cron.NewChain(cron.SkipIfStillRunning(&CronLogger{})).Then(j)
-	case 2: // 串行，仍在执行时排队
-		j = cron.NewChain(cron.DelayIfStillRunning(&CronLogger{})).Then(j)
-	}
-	eid, err := Timer.AddJob(job.CronExpr, j)
+	eid, err := Timer.AddJob(job.CronExpr, handle_Jobs(job))
 	if err != nil {
 		if ZapLog != nil {
 			ZapLog.Error("添加任务失败",
@@ -281,221 +297,366 @@ func UpdateJob(job *Jobs) error {
 	return nil
 }
 
-// 手动执行任务
+// DeleteJob 删除任务并清理其调度、熔断状态与任务独立的Cookie罐（接口删除与定义同步共用）
+func DeleteJob(job *Jobs) error {
+	if err := DB.Delete(job).Error; err != nil {
+		return err
+	}
+	// 避免之后复用该ID的任务继承旧的Cookie
+	ClearCookieJar(CookieJarForJob(job.ID), "")
+	ResetBreaker(job.ID)
+	if job.State != 2 {
+		if err := RemoveJob(job.ID); err != nil && ZapLog != nil {
+			// 记录错误但不影响删除操作的成功
+			ZapLog.Error("从调度器移除任务失败", LogField("id", job.ID), LogError(err))
+		}
+	}
+	return nil
+}
+
+// 手动执行任务，排空开始后不再接受（返回空的 execID）
 func RunJobManually(job *Jobs) string {
-	execID := uuid.NewString()
-	go executeJobWithExecID(job, execID)
+	execID, _ := startManualExec(job, nil)
 	return execID
 }
 
+// startManualExec 在返回前完成执行登记，再在新协程中执行，避免排空开始时遗漏尚未登记的手动执行；
+// acquire 不为 nil 时在执行前调用（等待执行权），返回释放函数
+func startManualExec(job *Jobs, acquire func() func()) (string, bool) {
+	execID := uuid.NewString()
+	ctx, re, ok := registerExecUnlessDraining(context.Background(), job, execID, "manual")
+	if !ok {
+		return "", false
+	}
+	go func() {
+		if acquire != nil {
+			defer acquire()()
+		}
+		runRegisteredExec(ctx, re, job)
+	}()
+	return execID, true
+}
+
 // 手动执行任务（带并发策略），返回 execID/是否跳过/原因
 func RunJobManuallyWithPolicy(job *Jobs) (execID string, skipped bool, reason string) {
+	const drainingReason = "服务正在停止，不接受新的执行"
 	// 是否允许手动并发
 	allowConc := GetJobsConfigBool("jobs.manual_allow_concurrent", true)
 	if allowConc {
-		return RunJobManually(job), false, ""
+		if execID, ok := startManualExec(job, nil); ok {
+			return execID, false, ""
+		}
+		return "", true, drainingReason
 	}
 
 	// 不允许手动并发时，按 AllowMode 决定策略
+	ch := getJobSemaphore(job.ID)
+	release := func() { <-ch }
+	var acquire func() func()
 	switch job.AllowMode {
 	case 1: // Skip: 仍在执行时跳过
-		ch := getJobSemaphore(job.ID)
 		select {
 		case ch <- struct{}{}:
 			// 获得执行权
-			execID = uuid.NewString()
-			go func() {
-				defer func() { <-ch }()
-				executeJobWithExecID(job, execID)
-			}()
-			return execID, false, ""
+			acquire = func() func() { return release }
 		default:
 			// 正在执行，跳过
 			return "", true, "任务仍在执行，已按策略跳过"
 		}
 	case 2: // Delay: 排队直到可执行
-		ch := getJobSemaphore(job.ID)
-		execID = uuid.NewString()
-		go func() {
+		acquire = func() func() {
 			ch <- struct{}{}
-			defer func() { <-ch }()
-			executeJobWithExecID(job, execID)
-		}()
-		return execID, false, ""
-	default: // 并行
-		return RunJobManually(job), false, ""
+			return release
+		}
+	}
+	execID, ok := startManualExec(job, acquire)
+	if !ok {
+		if job.AllowMode == 1 {
+			release()
+		}
+		return "", true, drainingReason
 	}
+	return execID, false, ""
+}
+
+// runJobExec 登记并执行任务，返回是否成功与是否因排空被中断
+func runJobExec(job *Jobs, execID, source string) (success, interrupted bool) {
+	ctx, re := registerExec(context.Background(), job, execID, source)
+	return runRegisteredExec(ctx, re, job)
 }
 
-// 执行任务
-func executeJob(job *Jobs) bool {
+// runRegisteredExec 执行已登记的任务并写入聚合日志、产物与指标，返回是否成功与是否因排空被中断
+func runRegisteredExec(ctx context.Context, re *RunningExec, job *Jobs) (bool, bool) {
+	execID, source := re.ExecID, re.Source
 	jobLogger := NewJobLogger(job.ID, job.Name)
 	startTime := time.Now()
-	// running++
-	MetricsSetRunning(1)
 
 	log := &JobExecLog{
 		Time:    startTime.Format("2006-01-02 15:04:05.000"),
 		JobID:   job.ID,
 		JobName: job.Name,
 		Mode:    job.Mode,
-		ExecID:  uuid.NewString(),
-		Source:  "cron",
+		ExecID:  execID,
+		Source:  source,
 	}
+	sink := newArtifactSink(job.ID, execID)
 	var success bool
 	var err error
 
+	artifactBaseDir := ""
 	switch job.Mode {
 	case "command":
-		success, log.Command, log.ExitCode, log.Stdout, log.Stderr, err = executeCommandJobForSummary(job)
+		success, log.Warning, log.Command, log.ExitCode, log.Stdout, log.Stderr, err = executeCommandJobForSummary(ctx, job, sink)
+		if cfg, perr := loadCommandConfig(job); perr == nil {
+			artifactBaseDir = cfg.WorkDir
+		}
+	case "script":
+		success, log.Warning, log.Command, log.ExitCode, log.Stdout, log.Stderr, err = executeCommandJobForSummary(ctx, job, sink)
+		if cfg, perr := loadScriptConfig(job); perr == nil {
+			artifactBaseDir = cfg.WorkDir
+		}
+	case "ssh":
+		var sr *sshExecResult
+		sr, err = executeSSHJobForSummary(ctx, job)
+		success, log.Command, log.ExitCode, log.Stdout, log.Hosts = sr.Success, sr.Command, sr.ExitCode, sr.Summary, sr.Hosts
 	case "http":
-		success, log.Stdout, err = executeHTTPJobForSummary(job)
+		var hr *httpExecResult
+		hr, err = executeHTTPJobForSummary(ctx, job, sink)
+		success, log.Stdout = hr.Success, hr.Summary
+		log.HttpUrl, log.HttpMethod, log.HttpStatus = hr.URL, hr.Method, hr.StatusCode
+		log.Assertions = hr.Assertions
+		if hr.TLS != nil {
+			log.TLSVersion = hr.TLS.Version
+			if !hr.TLS.CertExpiry.IsZero() {
+				log.TLSCertExpiry = hr.TLS.CertExpiry.Local().Format("2006-01-02 15:04:05")
+			}
+		}
+		if len(hr.Outputs) > 0 {
+			log.Outputs = hr.Outputs
+			setExecOutputs(execID, hr.Outputs)
+			if hr.ExtractMetrics {
+				MetricsSetExtractValues(strconv.Itoa(int(job.ID)), job.Name, hr.Outputs)
+			}
+		}
+		if !success && err == nil {
+			err = failedAssertionsError(hr.Assertions)
+		}
 	case "function", "func":
-		success, log.Stdout, err = executeFunctionJobForSummary(job)
+		success, log.Stdout, err = executeFunctionJobForSummary(ctx, job)
+	case "workflow":
+		success, log.Stdout, log.Steps, err = executeWorkflowJobForSummary(ctx, job, sink)
+	case "heartbeat":
+		err = fmt.Errorf("心跳监控任务为被动任务，请通过 /ping/%s 上报", job.PingToken)
 	default:
 		err = fmt.Errorf("不支持的任务模式: %s", job.Mode)
 		success = false
 	}
 
+	// 收集任务声明的产物文件
+	if patterns := jobArtifactPatterns(job); len(patterns) > 0 {
+		for _, problem := range sink.collectFiles(patterns, artifactBaseDir) {
+			jobLogger.Warning(problem)
+		}
+	}
+
 	endTime := time.Now()
 	log.EndTime = endTime.Format("2006-01-02 15:04:05.000")
 	log.Status = map[bool]string{true: "成功", false: "失败"}[success]
+	if !success {
+		log.Warning = ""
+	} else if log.Warning != "" {
+		log.Status = "警告"
+	}
 	log.DurationMs = endTime.Sub(startTime).Milliseconds()
 	if err != nil {
 		log.ErrorMsg = err.Error()
 	}
 
-	jobLogger.WriteSummaryLog(log)
+	offloadLargeOutputs(log, sink)
+	if re.isInterrupted() {
+		// 服务停止时被取消：记为中断，不发送失败通知
+		log.Status = "中断"
+		log.ErrorMsg = "服务停止，执行被中断"
+		jobLogger.WriteSummaryLog(log)
+		finishExec(re, ExecStatusInterrupted)
+		return false, true
+	}
+	finishExecLog(job, log, success)
+	status := map[bool]string{true: ExecStatusSuccess, false: ExecStatusFailed}[success]
+	if success && log.Warning != "" {
+		status = ExecStatusWarning
+	}
+	finishExec(re, status)
+	return success, false
+}
+
+// finishExecLog 写入聚合日志、发送通知并更新指标
+func finishExecLog(job *Jobs, log *JobExecLog, success bool) {
+	NewJobLogger(job.ID, job.Name).WriteSummaryLog(log)
+	dispatchJobNotifications(job, log, success)
 	// 指标
 	MetricsIncExec(strconv.Itoa(int(job.ID)), job.Name, job.Mode)
 	if !success {
 		MetricsIncFail(strconv.Itoa(int(job.ID)), job.Name, job.Mode)
+	} else if log.Warning != "" {
+		MetricsIncWarning(strconv.Itoa(int(job.ID)), job.Name, job.Mode)
 	}
 	MetricsObserveDuration(strconv.Itoa(int(job.ID)), job.Name, job.Mode, float64(log.DurationMs)/1000.0)
-	// running--
-	MetricsSetRunning(-1)
-	return success
 }
 
-// 带外部执行ID的执行函数（用于手动执行返回可跟踪ID）
-func executeJobWithExecID(job *Jobs, execID string) bool {
-	jobLogger := NewJobLogger(job.ID, job.Name)
-	startTime := time.Now()
-	MetricsSetRunning(1)
+func handle_Jobs(job *Jobs) cron.Job {
+	return cron.FuncJob(func() {
+		runJobWithPolicy(job, "cron")
+	})
+}
 
-	log := &JobExecLog{
-		Time:    startTime.Format("2006-01-02 15:04:05.000"),
-		JobID:   job.ID,
-		JobName: job.Name,
-		Mode:    job.Mode,
-		ExecID:  execID,
-		Source:  "manual",
+// jobAllowMode 任务的并发策略：0 并行，1 仍在执行时跳过，2 仍在执行时排队（0 时使用全局默认）
+func jobAllowMode(job *Jobs) int {
+	allow := job.AllowMode
+	if allow == 0 {
+		cfgDefault := GetJobsConfigInt("jobs.default_allow_mode", 0)
+		if cfgDefault == 1 || cfgDefault == 2 {
+			allow = cfgDefault
+		}
 	}
-	var success bool
-	var err error
+	return allow
+}
 
-	switch job.Mode {
-	case "command":
-		success, log.Command, log.ExitCode, log.Stdout, log.Stderr, err = executeCommandJobForSummary(job)
-	case "http":
-		success, log.Stdout, err = executeHTTPJobForSummary(job)
-	case "function", "func":
-		success, log.Stdout, err = executeFunctionJobForSummary(job)
-	default:
-		err = fmt.Errorf("不支持的任务模式: %s", job.Mode)
-		success = false
+// runJobWithPolicy 按调度规则执行一次任务（定时触发与中断补跑共用）：
+// 排空、并发策略、执行次数上限、熔断暂停与半开探测，执行后更新运行次数与熔断状态；
+// 因排空被中断的执行不计入运行次数与熔断统计
+func runJobWithPolicy(job *Jobs, source string) {
+	// 排空中不再接受新的触发
+	if IsDraining() {
+		return
 	}
 
-	endTime := time.Now()
-	log.EndTime = endTime.Format("2006-01-02 15:04:05.000")
-	log.Status = map[bool]string{true: "成功", false: "失败"}[success]
-	log.DurationMs = endTime.Sub(startTime).Milliseconds()
-	if err != nil {
-		log.ErrorMsg = err.Error()
+	// 并发策略：与不允许并发的手动执行共用任务信号量
+	switch jobAllowMode(job) {
+	case 1: // 串行，仍在执行时跳过
+		ch := getJobSemaphore(job.ID)
+		select {
+		case ch <- struct{}{}:
+			defer func() { <-ch }()
+		default:
+			LogInfo("任务仍在执行，已按策略跳过", LogField("job_id", job.ID), LogField("source", source))
+			return
+		}
+	case 2: // 串行，仍在执行时排队
+		ch := getJobSemaphore(job.ID)
+		ch <- struct{}{}
+		defer func() { <-ch }()
+		if IsDraining() {
+			return
+		}
 	}
 
-	jobLogger.WriteSummaryLog(log)
-	MetricsIncExec(strconv.Itoa(int(job.ID)), job.Name, job.Mode)
-	if !success {
-		MetricsIncFail(strconv.Itoa(int(job.ID)), job.Name, job.Mode)
+	// 读取数据库中的最新状态、计数与上限
+	var current Jobs
+	if err := DB.Select("id,max_run_count,run_count,state,suspended_at").First(&current, job.ID).Error; err == nil {
+		if current.State == 2 {
+			return
+		}
+		if current.MaxRunCount > 0 && current.RunCount >= current.MaxRunCount {
+			// 达到上限：置停止并移除
+			stopJobAtLimit(job.ID)
+			return
+		}
 	}
-	MetricsObserveDuration(strconv.Itoa(int(job.ID)), job.Name, job.Mode, float64(log.DurationMs)/1000.0)
-	MetricsSetRunning(-1)
-	return success
-}
 
-func handle_Jobs(job *Jobs) cron.Job {
-	return cron.FuncJob(func() {
-
-		// 读取数据库中的最新计数与上限
-		var current Jobs
-		if err := DB.Select("id,max_run_count,run_count,state").First(&current, job.ID).Error; err == nil {
-			if current.MaxRunCount > 0 && current.RunCount >= current.MaxRunCount {
-				// 达到上限：置停止并移除
-				DB.Model(&jobs.Jobs{}).Where("id=?", job.ID).Update("state", 2)
-				if err := RemoveJob(job.ID); err != nil {
-					if ZapLog != nil {
-						ZapLog.Error("从调度器移除任务失败", LogError(err))
-					}
-				}
-				return
-			}
+	// 心跳任务：不执行内容，宽限期结束后检查是否收到心跳
+	if job.Mode == "heartbeat" {
+		if source == "cron" {
+			scheduleHeartbeatCheck(job, time.Now())
 		}
+		return
+	}
 
-		// 执行前置状态：执行中
-		if err := DB.Model(&jobs.Jobs{}).Where("id=?", job.ID).Update("state", 1).Error; err != nil {
-			if ZapLog != nil {
-				ZapLog.Warn("更新任务为执行中失败", LogError(err), LogField("job_id", job.ID))
-			}
+	// 熔断暂停：未到探测时间直接跳过，到达时执行一次半开探测
+	probing := false
+	if current.State == JobStateSuspended {
+		if !acquireBreakerProbe(job, &current) {
+			return
 		}
+		probing = true
+		source = "probe"
+	}
 
-		// 执行任务
-		success := executeJob(job)
+	// 执行任务（运行状态由执行登记表记录，不再写入 Jobs.State）
+	success, interrupted := runJobExec(job, uuid.NewString(), source)
+	if interrupted {
+		// 探测被中断时任务保持暂停，到下一个探测时间再试
+		return
+	}
 
-		// 统计：原子自增
-		if err := DB.Model(&jobs.Jobs{}).Where("id=?", job.ID).UpdateColumn("run_count", gorm.Expr("run_count + ?", 1)).Error; err != nil {
-			if ZapLog != nil {
-				ZapLog.Warn("更新任务运行次数失败", LogError(err), LogField("job_id", job.ID))
-			}
+	// 统计：原子自增
+	if err := DB.Model(&jobs.Jobs{}).Where("id=?", job.ID).UpdateColumn("run_count", gorm.Expr("run_count + ?", 1)).Error; err != nil {
+		if ZapLog != nil {
+			ZapLog.Warn("更新任务运行次数失败", LogError(err), LogField("job_id", job.ID))
 		}
+	}
 
-		// 读取最新计数判断是否达到上限
-		if err := DB.Select("id,max_run_count,run_count").First(&current, job.ID).Error; err == nil {
-			if current.MaxRunCount > 0 && current.RunCount >= current.MaxRunCount {
-				DB.Model(&jobs.Jobs{}).Where("id=?", job.ID).Update("state", 2)
-				if err := RemoveJob(job.ID); err != nil {
-					if ZapLog != nil {
-						ZapLog.Error("从调度器移除任务失败", LogError(err))
-					}
-				}
-				return
-			}
+	// 读取最新计数判断是否达到上限
+	if err := DB.Select("id,max_run_count,run_count").First(&current, job.ID).Error; err == nil {
+		if current.MaxRunCount > 0 && current.RunCount >= current.MaxRunCount {
+			stopJobAtLimit(job.ID)
+			return
 		}
+	}
 
-		// 执行结束：若仍启用则置为等待
-		if success {
-			DB.Model(&jobs.Jobs{}).Where("id=? AND state<>?", job.ID, 2).Update("state", 0)
-		} else {
-			// 失败也置回等待（可根据需要扩展失败状态）
-			DB.Model(&jobs.Jobs{}).Where("id=? AND state<>?", job.ID, 2).Update("state", 0)
+	if probing {
+		finishBreakerProbe(job, success)
+		return
+	}
+
+	// 熔断判断
+	if cfg, _ := ParseBreakerConfig(job.Breaker); cfg != nil {
+		if reason := recordBreakerResult(job.ID, cfg, success); reason != "" {
+			suspendJob(job, reason)
 		}
+	}
+}
 
-	})
+// stopJobAtLimit 达到最大执行次数：置停止并从调度器移除
+func stopJobAtLimit(id uint) {
+	DB.Model(&jobs.Jobs{}).Where("id=?", id).Update("state", 2)
+	if err := RemoveJob(id); err != nil {
+		if ZapLog != nil {
+			ZapLog.Error("从调度器移除任务失败", LogError(err))
+		}
+	}
 }
 
 // executeHTTPJob 执行HTTP任务
 // HTTPConfig HTTP任务配置结构
 type HTTPConfig struct {
 	URL      string            `json:"url"`
-	Headers  map[string]string `json:"headers"`
+	Headers  map[string]string `json:"headers,omitempty"`
 	Mode     string            `json:"mode"`
-	Times    int               `json:"times"`
-	Interval int               `json:"interval"` // 次数间隔秒
-	Proxy    string            `json:"proxy"`
-	Data     string            `json:"data"`
-	Cookies  string            `json:"cookies"`
-	Result   string            `json:"result"`  // 自定义结果判断字符串
-	Timeout  int               `json:"timeout"` // 超时时间（秒）
+	Times    int               `json:"times,omitempty"`
+	Interval int               `json:"interval,omitempty"` // 次数间隔秒
+
+	MaxRetryWait int    `json:"max_retry_wait,omitempty"` // 服务端限流时最长等待秒数，0 使用 jobs.http_max_retry_wait_seconds
+	Proxy        string `json:"proxy,omitempty"`          // 代理地址，direct 表示忽略全局代理
+	NoProxy      string `json:"no_proxy,omitempty"`       // 不代理列表，逗号分隔
+	Data         string `json:"data,omitempty"`
+	Cookies      string `json:"cookies,omitempty"`
+	Result       string `json:"result,omitempty"` // 自定义结果判断字符串
+	Timeout      int    `json:"timeout"`          // 超时时间（秒）
+
+	BodyType string            `json:"body_type,omitempty"` // 请求体类型 raw/json/form/multipart/file，为空时按内容推断
+	Form     map[string]string `json:"form,omitempty"`      // 表单字段（form/multipart）
+	Files    map[string]string `json:"files,omitempty"`     // 上传文件，字段名 -> 本机路径（multipart）
+	BodyFile string            `json:"body_file,omitempty"` // 从本机文件读取请求体（file）
+	Assert   []string          `json:"assert,omitempty"`    // 响应断言，全部通过才算成功
+	Auth     *HTTPAuth         `json:"auth,omitempty"`      // 认证
+	TLS      *HTTPTLSConfig    `json:"tls,omitempty"`       // TLS（自定义CA、客户端证书等）
+
+	CookieJar    string `json:"cookie_jar,omitempty"`    // 持久化Cookie罐，job 为任务独立罐，其他为共享罐名称
+	KeepResponse bool   `json:"keep_response,omitempty"` // 响应体被截断时是否把完整响应体保存为产物
+
+	Extract        map[string]string `json:"extract,omitempty"`         // 输出提取：名称 -> json:路径 / regex:表达式 / header:名称 / status / output
+	ExtractMetrics bool              `json:"extract_metrics,omitempty"` // 数值输出写入 Prometheus 指标
 }
 
 // parseHTTPConfig 解析HTTP任务配置
@@ -576,6 +737,14 @@ func parseHTTPConfig(command string) (*HTTPConfig, error) {
 			continue
 		}
 
+		// 解析限流最长等待秒数
+		if strings.HasPrefix(line, "【max_retry_wait】") {
+			if secs, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "【max_retry_wait】"))); err == nil {
+				config.MaxRetryWait = secs
+			}
+			continue
+		}
+
 		// 解析代理
 		if strings.HasPrefix(line, "【proxy】") {
 			proxy := strings.TrimPrefix(line, "【proxy】")
@@ -586,6 +755,50 @@ func parseHTTPConfig(command string) (*HTTPConfig, error) {
 			continue
 		}
 
+		// 解析输出提取，格式 名称=表达式|||名称=表达式
+		if strings.HasPrefix(line, "【extract】") {
+			if config.Extract == nil {
+				config.Extract = make(map[string]string)
+			}
+			parseHTTPPairs(strings.TrimPrefix(line, "【extract】"), config.Extract)
+			continue
+		}
+		if strings.HasPrefix(line, "【extract_metrics】") {
+			v := strings.TrimSpace(strings.TrimPrefix(line, "【extract_metrics】"))
+			if v != "" {
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					return nil, fmt.Errorf("extract_metrics 应为 true 或 false，当前为 %q", v)
+				}
+				config.ExtractMetrics = b
+			}
+			continue
+		}
+
+		if strings.HasPrefix(line, "【keep_response】") {
+			v := strings.TrimSpace(strings.TrimPrefix(line, "【keep_response】"))
+			if v != "" {
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					return nil, fmt.Errorf("keep_response 应为 true 或 false，当前为 %q", v)
+				}
+				config.KeepResponse = b
+			}
+			continue
+		}
+
+		// 解析Cookie罐
+		if strings.HasPrefix(line, "【cookie_jar】") {
+			config.CookieJar = strings.TrimSpace(strings.TrimPrefix(line, "【cookie_jar】"))
+			continue
+		}
+
+		// 解析不代理列表
+		if strings.HasPrefix(line, "【no_proxy】") {
+			config.NoProxy = strings.TrimSpace(strings.TrimPrefix(line, "【no_proxy】"))
+			continue
+		}
+
 		// 解析POST数据
 		if strings.HasPrefix(line, "【data】") {
 			data := strings.TrimPrefix(line, "【data】")
@@ -596,6 +809,82 @@ func parseHTTPConfig(command string) (*HTTPConfig, error) {
 			continue
 		}
 
+		// 解析请求体类型
+		if strings.HasPrefix(line, "【body_type】") {
+			bodyType := strings.TrimPrefix(line, "【body_type】")
+			bodyType = strings.TrimSpace(bodyType)
+			if bodyType != "" {
+				config.BodyType = strings.ToLower(bodyType)
+			}
+			continue
+		}
+
+		// 解析表单字段，格式 名称=值|||名称=值
+		if strings.HasPrefix(line, "【form】") {
+			if config.Form == nil {
+				config.Form = make(map[string]string)
+			}
+			parseHTTPPairs(strings.TrimPrefix(line, "【form】"), config.Form)
+			continue
+		}
+
+		// 解析上传文件，格式 字段名=路径|||字段名=路径
+		if strings.HasPrefix(line, "【files】") {
+			if config.Files == nil {
+				config.Files = make(map[string]string)
+			}
+			parseHTTPPairs(strings.TrimPrefix(line, "【files】"), config.Files)
+			continue
+		}
+
+		// 解析认证
+		if strings.HasPrefix(line, "【auth】") {
+			authStr := strings.TrimPrefix(line, "【auth】")
+			authStr = strings.TrimSpace(authStr)
+			if authStr != "" {
+				auth, err := parseHTTPAuth(authStr)
+				if err != nil {
+					return nil, fmt.Errorf("认证配置无效: %v", err)
+				}
+				config.Auth = auth
+			}
+			continue
+		}
+
+		// 解析TLS配置
+		if strings.HasPrefix(line, "【tls】") {
+			tlsStr := strings.TrimPrefix(line, "【tls】")
+			tlsStr = strings.TrimSpace(tlsStr)
+			if tlsStr != "" {
+				tlsCfg, err := parseHTTPTLS(tlsStr)
+				if err != nil {
+					return nil, fmt.Errorf("TLS配置无效: %v", err)
+				}
+				config.TLS = tlsCfg
+			}
+			continue
+		}
+
+		// 解析响应断言（可多行）
+		if strings.HasPrefix(line, "【assert】") {
+			assert := strings.TrimPrefix(line, "【assert】")
+			assert = strings.TrimSpace(assert)
+			if assert != "" {
+				config.Assert = append(config.Assert, assert)
+			}
+			continue
+		}
+
+		// 解析请求体文件
+		if strings.HasPrefix(line, "【body_file】") {
+			bodyFile := strings.TrimPrefix(line, "【body_file】")
+			bodyFile = strings.TrimSpace(bodyFile)
+			if bodyFile != "" {
+				config.BodyFile = bodyFile
+			}
+			continue
+		}
+
 		// 解析Cookie
 		if strings.HasPrefix(line, "【cookies】") {
 			cookies := strings.TrimPrefix(line, "【cookies】")
@@ -636,88 +925,24 @@ func parseHTTPConfig(command string) (*HTTPConfig, error) {
 	return config, nil
 }
 
-// detectEncoding 检测响应编码
-func detectEncoding(body []byte, contentType string) string {
-	// 从Content-Type中检测编码
-	if strings.Contains(strings.ToLower(contentType), "charset=") {
-		parts := strings.Split(contentType, "charset=")
-		if len(parts) > 1 {
-			encoding := strings.TrimSpace(parts[1])
-			encoding = strings.Trim(encoding, ";\"'")
-			return strings.ToLower(encoding)
-		}
-	}
-
-	// 从响应体中检测编码（简单检测）
-	if len(body) > 3 && body[0] == 0xEF && body[1] == 0xBB && body[2] == 0xBF {
-		return "utf-8"
-	}
-
-	// 默认返回utf-8
-	return "utf-8"
-}
-
-// convertToUTF8 转换编码为UTF-8
-func convertToUTF8(body []byte, encoding string) ([]byte, error) {
-	if len(body) == 0 {
-		return body, nil
-	}
-	if utf8.Valid(body) {
-		return body, nil
-	}
-	// Windows下尝试GBK转UTF-8
-	if runtime.GOOS == "windows" {
-		reader := transform.NewReader(bytes.NewReader(body), simplifiedchinese.GBK.NewDecoder())
-		utf8Bytes, err := io.ReadAll(reader)
-		if err == nil && utf8.Valid(utf8Bytes) {
-			return utf8Bytes, nil
-		}
-		// 转换失败则返回原始
-		return body, err
-	}
-	// 其他情况直接返回
-	return body, nil
-}
-
 // 通用命令执行函数，支持详细和简要返回
-func executeCommandJobV2(job *Jobs, needDetail bool) (success bool, command string, exitCode int, stdout string, stderr string, err error) {
-	config, err := parseCommandConfig(job.Command)
+// ctx 用于外部取消（如工作流步骤超时），命令自身超时仍由【timeout】控制
+func executeCommandJobV2(ctx context.Context, job *Jobs, needDetail bool, sink *artifactSink) (success bool, command string, exitCode int, stdout string, stderr string, err error) {
+	config, err := loadCommandConfig(job)
 	if err != nil {
-		return false, config.Command, 0, "", "", fmt.Errorf("解析命令配置失败: %v", err)
+		return false, "", 0, "", "", fmt.Errorf("解析命令配置失败: %v", err)
 	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.Timeout)*time.Second)
+	defer cancel()
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/C", config.Command)
+		cmd = exec.CommandContext(ctx, "cmd", "/C", config.Command)
 	} else {
-		cmd = exec.Command("bash", "-c", config.Command)
+		cmd = exec.CommandContext(ctx, "bash", "-c", config.Command)
 	}
-	if config.WorkDir != "" {
-		cmd.Dir = config.WorkDir
-	}
-	if len(config.Env) > 0 {
-		cmd.Env = append(os.Environ(), config.Env...)
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
-	defer cancel()
-	cmd = exec.CommandContext(ctx, cmd.Path, cmd.Args[1:]...)
-	var stdoutBuf, stderrBuf bytes.Buffer
-	cmd.Stdout = &stdoutBuf
-	cmd.Stderr = &stderrBuf
-	startTime := time.Now()
-	err = cmd.Run()
-	_ = time.Since(startTime) // duration 仅用于统计，可忽略
-	stdoutBytes := stdoutBuf.Bytes()
-	stderrBytes := stderrBuf.Bytes()
-	stdoutUTF8, _ := convertToUTF8(stdoutBytes, "")
-	stderrUTF8, _ := convertToUTF8(stderrBytes, "")
 	command = config.Command
-	stdout = string(stdoutUTF8)
-	stderr = string(stderrUTF8)
-	exitCode = 0
-	if cmd.ProcessState != nil {
-		exitCode = cmd.ProcessState.ExitCode()
-	}
-	success = err == nil && exitCode == 0
+	exitCode, stdout, stderr, err = runJobProcess(ctx, cmd, config.WorkDir, config.Env, config.Encoding, sink)
+	success, err = config.ExitPolicy.apply(exitCode, stderr, err)
 	if !needDetail {
 		// 兼容原 executeCommandJob 返回 (bool, string, error)
 		if err != nil && !strings.Contains(err.Error(), "timeout") {
@@ -728,67 +953,148 @@ func executeCommandJobV2(job *Jobs, needDetail bool) (success bool, command stri
 	return success, command, exitCode, stdout, stderr, err
 }
 
-// 替换 executeCommandJobForSummary
-func executeCommandJobForSummary(job *Jobs) (success bool, command string, exitCode int, stdout string, stderr string, err error) {
-	cfg, perr := parseCommandConfig(job.Command)
-	if perr != nil {
-		return false, cfg.Command, 0, "", "", fmt.Errorf("解析命令配置失败: %v", perr)
+// runJobProcess 启动进程并等待结束，收集输出与退出码（命令与脚本任务共用）
+func runJobProcess(ctx context.Context, cmd *exec.Cmd, workDir string, env []string, encoding string, sink *artifactSink) (exitCode int, stdout string, stderr string, err error) {
+	if workDir != "" {
+		cmd.Dir = workDir
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stdoutBuf, stderrBuf bytes.Buffer
+	cmd.Stdout = &stdoutBuf
+	cmd.Stderr = &stderrBuf
+	// 取消后子进程仍持有输出管道时，最多再等待2秒
+	cmd.WaitDelay = 2 * time.Second
+	if err = cmd.Start(); err == nil {
+		setExecPid(ctx, cmd.Process.Pid)
+		err = cmd.Wait()
+	}
+	stdout = decodeCommandOutput(stdoutBuf.Bytes(), encoding, "stdout", sink)
+	stderr = decodeCommandOutput(stderrBuf.Bytes(), encoding, "stderr", sink)
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	return exitCode, stdout, stderr, err
+}
+
+// 替换 executeCommandJobForSummary（命令与脚本任务共用，按次数与间隔重复执行）
+// 返回可重试退出码的重试，以及警告执行的原因（warning 非空且 success 为 true 时本次执行记为警告）
+func executeCommandJobForSummary(ctx context.Context, job *Jobs, sink *artifactSink) (success bool, warning string, command string, exitCode int, stdout string, stderr string, err error) {
+	// 单次执行
+	runOnce := func() (bool, string, int, string, string, error) {
+		return executeCommandJobV2(ctx, job, true, sink)
+	}
+	var times, interval int
+	var policy ExitPolicy
+	if job.Mode == "script" {
+		cfg, perr := loadScriptConfig(job)
+		if perr != nil {
+			return false, "", "", 0, "", "", fmt.Errorf("解析脚本配置失败: %v", perr)
+		}
+		times, interval, policy = cfg.Times, cfg.Interval, cfg.ExitPolicy
+		runOnce = func() (bool, string, int, string, string, error) {
+			return executeScriptJob(ctx, job, sink)
+		}
+	} else {
+		cfg, perr := loadCommandConfig(job)
+		if perr != nil {
+			return false, "", "", 0, "", "", fmt.Errorf("解析命令配置失败: %v", perr)
+		}
+		times, interval, policy = cfg.Times, cfg.Interval, cfg.ExitPolicy
 	}
 	// 次数与间隔
-	attempts := cfg.Times
+	attempts := times
 	if attempts <= 0 {
 		attempts = 1
 	}
 	var outB strings.Builder
 	var errB strings.Builder
+	var warnings []string
 	var lastExit int
 	var lastErr error
 	anySuccess := false
 	for i := 1; i <= attempts; i++ {
-		s, cmdStr, code, out, er, e := executeCommandJobV2(job, true)
-		if i == 1 {
-			command = cmdStr
-		}
-		lastExit = code
-		lastErr = e
+		setExecAttempt(ctx, i)
 		outB.WriteString(fmt.Sprintf("\n=== 第 %d/%d 次执行 ===\n", i, attempts))
-		if out != "" {
-			outB.WriteString(out)
-		}
-		if er != "" {
-			errB.WriteString(fmt.Sprintf("\n[attempt %d] %s\n", i, er))
+		for retry := 0; ; retry++ {
+			s, cmdStr, code, out, er, e := runOnce()
+			if command == "" {
+				command = cmdStr
+			}
+			lastExit = code
+			lastErr = e
+			if out != "" {
+				outB.WriteString(out)
+			}
+			if er != "" {
+				errB.WriteString(fmt.Sprintf("\n[attempt %d] %s\n", i, er))
+			}
+			outcome, reason := policy.classify(code, er, e)
+			if s {
+				anySuccess = true
+				if outcome == exitWarning {
+					warnings = append(warnings, reason)
+					outB.WriteString(fmt.Sprintf("\n[警告] %s\n", reason))
+				}
+			}
+			again, note := policy.waitRetry(ctx, retry, interval, code, er, e)
+			outB.WriteString(note)
+			if !again {
+				if ctx.Err() != nil {
+					lastErr = ctx.Err()
+				}
+				break
+			}
 		}
-		if s {
-			anySuccess = true
+		if ctx.Err() != nil {
+			break
 		}
-		if i < attempts && cfg.Interval > 0 {
-			time.Sleep(time.Duration(cfg.Interval) * time.Second)
+		if i < attempts && interval > 0 {
+			if !sleepContext(ctx, time.Duration(interval)*time.Second) {
+				lastErr = ctx.Err()
+				break
+			}
 		}
 	}
 	stdout = outB.String()
 	stderr = errB.String()
 	exitCode = lastExit
 	if anySuccess {
-		return true, command, exitCode, stdout, stderr, nil
+		return true, strings.Join(warnings, "；"), command, exitCode, stdout, stderr, nil
+	}
+	return false, "", command, exitCode, stdout, stderr, lastErr
+}
+
+// sleepContext 可取消的等待，ctx 结束时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
 	}
-	return false, command, exitCode, stdout, stderr, lastErr
 }
 
 // CommandConfig 命令任务配置结构
 type CommandConfig struct {
-	Command  string        `json:"command"`  // 要执行的命令
-	WorkDir  string        `json:"work_dir"` // 工作目录
-	Env      []string      `json:"env"`      // 环境变量
-	Timeout  time.Duration `json:"timeout"`  // 超时时间
-	Times    int           `json:"times"`
-	Interval int           `json:"interval"`
+	Command  string   `json:"command"`            // 要执行的命令
+	WorkDir  string   `json:"work_dir,omitempty"` // 工作目录
+	Env      []string `json:"env,omitempty"`      // 环境变量
+	Timeout  int      `json:"timeout"`            // 超时时间（秒）
+	Times    int      `json:"times,omitempty"`
+	Interval int      `json:"interval,omitempty"`
+	Encoding string   `json:"encoding,omitempty"` // 输出编码，为空时自动识别
+	ExitPolicy
 }
 
 // parseCommandConfig 解析命令任务配置
 func parseCommandConfig(command string) (*CommandConfig, error) {
 	config := &CommandConfig{
-		Command: command,                                                                           // 默认整个command就是要执行的命令
-		Timeout: time.Duration(GetJobsConfigInt("jobs.default_timeout_seconds", 30)) * time.Second, // 默认超时
+		Command: command,                                              // 默认整个command就是要执行的命令
+		Timeout: GetJobsConfigInt("jobs.default_timeout_seconds", 30), // 默认超时
 		Env:     make([]string, 0),
 	}
 
@@ -841,7 +1147,7 @@ func parseCommandConfig(command string) (*CommandConfig, error) {
 			timeoutStr = strings.TrimSpace(timeoutStr)
 			if timeoutStr != "" {
 				if timeout, err := strconv.Atoi(timeoutStr); err == nil {
-					config.Timeout = time.Duration(timeout) * time.Second
+					config.Timeout = timeout
 				}
 			}
 			continue
@@ -870,6 +1176,17 @@ func parseCommandConfig(command string) (*CommandConfig, error) {
 			}
 			continue
 		}
+
+		// 解析输出编码
+		if strings.HasPrefix(line, "【encoding】") {
+			config.Encoding = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(line, "【encoding】")))
+			continue
+		}
+
+		// 解析退出码规则
+		if m := jobTagLineRe.FindStringSubmatch(line); m != nil {
+			parseExitPolicyTag(&config.ExitPolicy, strings.TrimSpace(m[1]), strings.TrimSpace(m[2]))
+		}
 	}
 
 	// 如果没有找到【command】标记，则整个command就是命令
@@ -940,10 +1257,10 @@ func IsTimerRunning() bool {
 
 // FunctionConfig 函数任务配置结构
 type FunctionConfig struct {
-	Name     string   `json:"name"` // 函数名
-	Args     []string `json:"args"` // 函数参数
-	Times    int      `json:"times"`
-	Interval int      `json:"interval"`
+	Name     string   `json:"name"`           // 函数名
+	Args     []string `json:"args,omitempty"` // 函数参数
+	Times    int      `json:"times,omitempty"`
+	Interval int      `json:"interval,omitempty"`
 	Timeout  int      `json:"timeout"` // 超时时间（秒）
 }
 
@@ -1079,65 +1396,85 @@ func parseFunctionArgs(argsStr string) []string {
 	return args
 }
 
+// httpExecResult HTTP执行结果（最后一次请求）
+type httpExecResult struct {
+	Success    bool
+	Summary    string      // 请求过程摘要（写入聚合日志）
+	Method     string      // 实际使用的请求方式
+	URL        string      // 请求地址
+	StatusCode int         // 最后一次响应状态码
+	Header     http.Header // 最后一次响应头
+	Body       string      // 最后一次响应体（UTF-8，未截断）
+
+	Assertions []HTTPAssertionResult // 最后一次响应的断言结果
+	TLS        *tlsResult            // 最后一次响应协商的TLS信息
+
+	Outputs        map[string]string // 最后一次响应的提取结果
+	ExtractMetrics bool              // 提取结果是否写入指标
+}
+
 // 新增：http模式的聚合执行
-func executeHTTPJobForSummary(job *Jobs) (success bool, stdout string, err error) {
-	config, err := parseHTTPConfig(job.Command)
+// 响应体超过 http_response_max_bytes 时日志中截断，配置【keep_response】true 时完整响应体另存为产物
+func executeHTTPJobForSummary(ctx context.Context, job *Jobs, sink *artifactSink) (*httpExecResult, error) {
+	config, err := loadHTTPConfig(job)
 	if err != nil {
-		return false, "", fmt.Errorf("解析HTTP配置失败: %v", err)
+		return &httpExecResult{}, fmt.Errorf("解析HTTP配置失败: %v", err)
 	}
+	config.CookieJar = cookieJarName(config.CookieJar, job.ID)
+	return executeHTTPConfig(ctx, config, sink)
+}
 
-	if config.URL == "" {
-		return false, "", fmt.Errorf("URL不能为空")
+// executeHTTPConfig 按配置执行HTTP请求（支持次数/间隔），返回最后一次响应
+func executeHTTPConfig(ctx context.Context, config *HTTPConfig, sink *artifactSink) (*httpExecResult, error) {
+	method := strings.ToUpper(strings.TrimSpace(config.Mode))
+	if method == "" {
+		method = "GET"
 	}
-
-	// 创建自定义Transport
-	transport := &http.Transport{
-		MaxIdleConns:        100,
-		MaxIdleConnsPerHost: 10,
-		IdleConnTimeout:     90 * time.Second,
-		TLSHandshakeTimeout: 10 * time.Second,
-		DisableCompression:  false,
+	res := &httpExecResult{Method: method, URL: config.URL, ExtractMetrics: config.ExtractMetrics}
+	if config.URL == "" {
+		return res, fmt.Errorf("URL不能为空")
 	}
 
 	// 构建请求信息
 	var requestInfo strings.Builder
 	requestInfo.WriteString(fmt.Sprintf("请求地址: %s\n", config.URL))
-	requestInfo.WriteString(fmt.Sprintf("请求方式: %s\n", config.Mode))
+	requestInfo.WriteString(fmt.Sprintf("请求方式: %s\n", method))
 
-	// 代理信息
-	if config.Proxy != "" {
-		requestInfo.WriteString(fmt.Sprintf("代理状态: 使用代理 %s\n", config.Proxy))
-	} else {
-		requestInfo.WriteString("代理状态: 无代理\n")
+	// TLS 配置
+	settings := &httpTransportSettings{TLS: config.TLS}
+	if config.TLS != nil {
+		material, terr := loadTLSMaterial(config.TLS)
+		if terr != nil {
+			requestInfo.WriteString(fmt.Sprintf("TLS错误: %v\n", terr))
+			res.Summary = requestInfo.String()
+			return res, fmt.Errorf("TLS配置错误: %v", terr)
+		}
+		settings.tls = material
+		requestInfo.WriteString(fmt.Sprintf("TLS配置: %s\n", config.TLS.describe()))
+		if config.TLS.Insecure {
+			requestInfo.WriteString("⚠️ 警告: 已跳过TLS证书校验（insecure=true），连接可能被中间人劫持\n")
+			LogWarn("HTTP任务跳过了TLS证书校验", LogField("url", config.URL))
+		}
 	}
 
-	// 设置代理
-	if config.Proxy != "" {
-		proxyURL, perr := url.Parse(config.Proxy)
-		if perr != nil {
-			errorMsg := fmt.Sprintf("代理错误: 解析代理URL失败 - %v", perr)
-			requestInfo.WriteString(errorMsg + "\n")
-			return false, requestInfo.String(), fmt.Errorf("解析代理URL失败: %v", perr)
-		}
+	// 代理信息（任务未配置时使用全局代理）
+	proxySettings, perr := effectiveHTTPProxy(config)
+	if perr != nil {
+		requestInfo.WriteString(fmt.Sprintf("代理错误: %v\n", perr))
+		res.Summary = requestInfo.String()
+		return res, fmt.Errorf("代理配置错误: %v", perr)
+	}
+	settings.Proxy, settings.NoProxy = proxySettings.URL, proxySettings.NoProxy
+	requestInfo.WriteString(fmt.Sprintf("代理状态: %s\n", proxySettings.describe()))
 
-		// 根据代理类型设置不同的处理方式
-		if strings.HasPrefix(config.Proxy, "socks") {
-			// SOCKS代理
-			dialer, derr := proxy.SOCKS5("tcp", proxyURL.Host, nil, proxy.Direct)
-			if derr != nil {
-				errorMsg := fmt.Sprintf("代理错误: 创建SOCKS代理拨号器失败 - %v", derr)
-				requestInfo.WriteString(errorMsg + "\n")
-				return false, requestInfo.String(), fmt.Errorf("创建SOCKS代理拨号器失败: %v", derr)
-			}
-			transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
-				// 该接口不支持ctx取消，只能尽量复用传入的上下文
-				return dialer.Dial(network, addr)
-			}
-		} else {
-			// HTTP/HTTPS代理
-			transport.Proxy = http.ProxyURL(proxyURL)
-		}
+	// 获取共享的Transport（相同代理与TLS设置的任务复用连接）
+	transport, release, terr := httpTransports.acquire(settings)
+	if terr != nil {
+		requestInfo.WriteString(fmt.Sprintf("连接错误: %v\n", terr))
+		res.Summary = requestInfo.String()
+		return res, terr
 	}
+	defer release()
 
 	// 创建HTTP客户端，使用配置的超时时间
 	client := &http.Client{
@@ -1155,8 +1492,64 @@ func executeHTTPJobForSummary(job *Jobs) (success bool, stdout string, err error
 	if config.Cookies != "" {
 		requestInfo.WriteString(fmt.Sprintf("Cookie: %s\n", config.Cookies))
 	}
-	if config.Data != "" && strings.ToUpper(config.Mode) == "POST" {
-		requestInfo.WriteString(fmt.Sprintf("POST数据: %s\n", config.Data))
+
+	// Cookie罐：载入上次保存的Cookie，响应中的 Set-Cookie 自动更新
+	var jar *persistentCookieJar
+	if config.CookieJar != "" {
+		j, jerr := loadCookieJar(config.CookieJar)
+		if jerr != nil {
+			requestInfo.WriteString(fmt.Sprintf("Cookie罐错误: %v\n", jerr))
+			res.Summary = requestInfo.String()
+			return res, jerr
+		}
+		jar = j
+		client.Jar = jar
+		requestInfo.WriteString(fmt.Sprintf("Cookie罐: %s（已载入 %d 个Cookie）\n", config.CookieJar, jar.Len()))
+	}
+	if config.Auth != nil {
+		requestInfo.WriteString(fmt.Sprintf("认证方式: %s\n", config.Auth.describe()))
+	}
+
+	// 构建请求体（多次请求复用）
+	var reqBody *httpRequestBody
+	if httpConfigHasBody(config) {
+		if !httpMethodSendsBody(method) {
+			requestInfo.WriteString(fmt.Sprintf("请求体: %s 请求不发送请求体，已忽略\n", method))
+		} else {
+			b, berr := buildHTTPBody(config)
+			if berr != nil {
+				requestInfo.WriteString(fmt.Sprintf("请求错误: 构建请求体失败 - %v\n", berr))
+				res.Summary = requestInfo.String()
+				return res, fmt.Errorf("构建请求体失败: %v", berr)
+			}
+			reqBody = b
+			requestInfo.WriteString(fmt.Sprintf("请求体: %s，%d 字节\n", b.Type, len(b.Data)))
+			switch b.Type {
+			case HTTPBodyRaw, HTTPBodyJSON, HTTPBodyForm:
+				requestInfo.WriteString(previewText(string(b.Data), 1000) + "\n")
+			case HTTPBodyMultipart:
+				for _, k := range sortedKeys(config.Form) {
+					requestInfo.WriteString(fmt.Sprintf("  字段 %s: %s\n", k, config.Form[k]))
+				}
+				for _, k := range sortedKeys(config.Files) {
+					requestInfo.WriteString(fmt.Sprintf("  文件 %s: %s\n", k, config.Files[k]))
+				}
+			case HTTPBodyFile:
+				requestInfo.WriteString(fmt.Sprintf("  文件: %s\n", config.BodyFile))
+			}
+		}
+	}
+
+	// 响应断言
+	assertions, aerr := parseHTTPAssertions(config.Assert)
+	if aerr != nil {
+		requestInfo.WriteString(fmt.Sprintf("断言错误: %v\n", aerr))
+		res.Summary = requestInfo.String()
+		return res, aerr
+	}
+	if len(assertions) > 0 && config.Result != "" {
+		// 同时配置【result】时作为响应体包含断言
+		assertions = append(assertions, &httpAssertion{Raw: "body contains " + config.Result, Subject: "body", Op: "contains", Value: config.Result})
 	}
 
 	// Times 支持：<=0 视为 1 次
@@ -1173,42 +1566,93 @@ func executeHTTPJobForSummary(job *Jobs) (success bool, stdout string, err error
 
 	// 执行循环
 	anySuccess := false
+	maxRetryWait := httpMaxRetryWait(config)
+	var rateLimitErr error
 	for i := 1; i <= attempts; i++ {
+		setExecAttempt(ctx, i)
 		requestInfo.WriteString(fmt.Sprintf("\n=== 第 %d/%d 次请求 ===\n", i, attempts))
+		var retryWait time.Duration // 服务端限流要求的等待
 
 		// 创建请求
-		var req *http.Request
-		var reqErr error
-		method := strings.ToUpper(config.Mode)
-		if method == "POST" {
+		bodySize := 0
+		if reqBody != nil {
+			bodySize = len(reqBody.Data)
+		}
+		trace := &httpConnTrace{}
+		newRequest := func(refreshAuth bool) (*http.Request, error) {
 			var body io.Reader
-			if config.Data != "" {
-				body = strings.NewReader(config.Data)
+			if reqBody != nil {
+				body = bytes.NewReader(reqBody.Data)
 			}
-			req, reqErr = http.NewRequest("POST", config.URL, body)
-		} else {
-			req, reqErr = http.NewRequest("GET", config.URL, nil)
+			req, err := http.NewRequestWithContext(trace.withConnTrace(ctx), method, config.URL, body)
+			if err != nil {
+				return nil, fmt.Errorf("创建HTTP请求失败 - %v", err)
+			}
+			// 设置头/Cookie
+			for key, value := range config.Headers {
+				req.Header.Set(key, value)
+			}
+			if config.Cookies != "" {
+				req.Header.Set("Cookie", config.Cookies)
+			}
+			if reqBody != nil && reqBody.ContentType != "" {
+				// multipart 的分隔符由本次构建生成，必须使用自动生成的 Content-Type
+				if _, ok := headerValue(config.Headers, "Content-Type"); !ok || reqBody.Type == HTTPBodyMultipart {
+					req.Header.Set("Content-Type", reqBody.ContentType)
+				}
+			}
+			if config.Auth != nil {
+				if err := applyHTTPAuth(ctx, client, config.Auth, req, refreshAuth); err != nil {
+					return nil, fmt.Errorf("认证失败 - %v", err)
+				}
+			}
+			return req, nil
+		}
+		// 请求行在添加认证前记录，避免查询参数中的密钥写入日志
+		if u, perr := url.Parse(config.URL); perr == nil {
+			requestInfo.WriteString(fmt.Sprintf("请求行: %s %s，请求体 %d 字节\n", method, u.RequestURI(), bodySize))
 		}
+		LogDebug("HTTP任务请求", LogField("method", method), LogField("url", config.URL), LogField("body_bytes", bodySize))
+
+		req, reqErr := newRequest(false)
 		if reqErr != nil {
-			errorMsg := fmt.Sprintf("请求错误: 创建HTTP请求失败 - %v", reqErr)
-			requestInfo.WriteString(errorMsg + "\n")
+			requestInfo.WriteString("请求错误: " + reqErr.Error() + "\n")
+			if ctx.Err() != nil {
+				break
+			}
 			// 本次失败，继续下一次
 			continue
 		}
 
-		// 设置头/Cookie
-		for key, value := range config.Headers {
-			req.Header.Set(key, value)
-		}
-		if config.Cookies != "" {
-			req.Header.Set("Cookie", config.Cookies)
+		// 主机限速与服务端要求的暂停（同一主机的任务共享）
+		if waited, werr := waitHostSlot(ctx, req.URL, maxRetryWait); werr != nil {
+			requestInfo.WriteString(fmt.Sprintf("请求错误: %v\n", werr))
+			rateLimitErr = werr
+			break
+		} else if waited >= 10*time.Millisecond {
+			requestInfo.WriteString(fmt.Sprintf("限流: 等待 %s 后发送\n", formatWait(waited)))
 		}
 
 		// 执行请求
+		reqStart := time.Now()
 		resp, doErr := client.Do(req)
+		if doErr == nil && resp.StatusCode == http.StatusUnauthorized && config.Auth != nil && config.Auth.Type == HTTPAuthOAuth2 {
+			// 令牌可能已被服务端吊销：刷新后重试一次
+			resp.Body.Close()
+			requestInfo.WriteString("认证: 返回401，刷新OAuth2令牌后重试\n")
+			if req, reqErr = newRequest(true); reqErr != nil {
+				requestInfo.WriteString("请求错误: " + reqErr.Error() + "\n")
+				continue
+			}
+			reqStart = time.Now()
+			resp, doErr = client.Do(req)
+		}
 		if doErr != nil {
 			errorMsg := fmt.Sprintf("请求错误: HTTP请求失败 - %v", doErr)
 			requestInfo.WriteString(errorMsg + "\n")
+			if ctx.Err() != nil {
+				break
+			}
 			continue
 		}
 		func() {
@@ -1216,19 +1660,42 @@ func executeHTTPJobForSummary(job *Jobs) (success bool, stdout string, err error
 
 			// 状态
 			requestInfo.WriteString(fmt.Sprintf("响应状态: %s (%d)\n", resp.Status, resp.StatusCode))
+			res.StatusCode = resp.StatusCode
+			res.Header = resp.Header
+			if d, source, ok := retryAfterDelay(resp, time.Now()); ok {
+				retryWait = d
+				pauseHost(req.URL, time.Now().Add(d))
+				requestInfo.WriteString(fmt.Sprintf("限流: %s 要求等待 %s\n", source, formatWait(d)))
+			}
 
 			// 读取响应
 			body, rerr := io.ReadAll(resp.Body)
+			latency := time.Since(reqStart)
 			if rerr != nil {
 				requestInfo.WriteString(fmt.Sprintf("响应错误: 读取响应体失败 - %v\n", rerr))
 				return
 			}
-			encoding := detectEncoding(body, resp.Header.Get("Content-Type"))
-			utf8Body, cerr := convertToUTF8(body, encoding)
+			requestInfo.WriteString(fmt.Sprintf("响应耗时: %dms，响应体 %d 字节，%s\n", latency.Milliseconds(), len(body), trace.describe()))
+			if tr := tlsStateResult(resp.TLS); tr != nil {
+				res.TLS = tr
+				if tr.CertExpiry.IsZero() {
+					requestInfo.WriteString(fmt.Sprintf("TLS: %s\n", tr.Version))
+				} else {
+					requestInfo.WriteString(fmt.Sprintf("TLS: %s，证书到期 %s（剩余 %d 天）\n", tr.Version,
+						tr.CertExpiry.Local().Format("2006-01-02 15:04:05"), int(time.Until(tr.CertExpiry).Hours()/24)))
+				}
+			}
+			utf8Body, encoding, cerr := convertToUTF8(body, detectEncoding(body, resp.Header.Get("Content-Type")))
 			if cerr != nil {
-				requestInfo.WriteString(fmt.Sprintf("编码错误: 编码转换失败 - %v\n", cerr))
-				return
+				requestInfo.WriteString(fmt.Sprintf("编码错误: %v，按原始字节处理\n", cerr))
+			} else if encoding != "" {
+				note := ""
+				if name := keepRawOutput(sink, fmt.Sprintf("response_%d.raw", i), "http_resp", body); name != "" {
+					note = fmt.Sprintf("，原始响应见产物 %s", name)
+				}
+				requestInfo.WriteString(fmt.Sprintf("响应编码: %s，已转换为UTF-8%s\n", encoding, note))
 			}
+			res.Body = string(utf8Body)
 
 			// 响应头（仅第一次打印以控制体积）
 			if i == 1 && len(resp.Header) > 0 {
@@ -1240,15 +1707,50 @@ func executeHTTPJobForSummary(job *Jobs) (success bool, stdout string, err error
 				}
 			}
 
-			// 响应内容（截断）
+			// 响应内容（截断），开启【keep_response】时完整响应体转存为产物
 			responseContent := string(utf8Body)
 			if maxBytes > 0 && len(responseContent) > maxBytes {
-				responseContent = responseContent[:maxBytes] + "\n... (响应内容已截断)"
+				note := fmt.Sprintf("\n... (响应内容已截断，共 %d 字节)", len(utf8Body))
+				if config.KeepResponse {
+					if ref := sink.Save(fmt.Sprintf("response_%d.body", i), "http_resp", utf8Body); ref != nil {
+						note = fmt.Sprintf("\n... (响应内容已截断，完整响应见产物 %s，共 %d 字节)", ref.Name, ref.Size)
+					}
+				}
+				responseContent = previewText(responseContent, maxBytes) + note
 			}
 			requestInfo.WriteString("响应内容:\n")
 			requestInfo.WriteString(responseContent)
 
+			// 输出提取（多次请求时以最后一次为准）
+			if len(config.Extract) > 0 {
+				outputs, failures := runHTTPExtract(config.Extract, res.Body, resp.Header, resp.StatusCode)
+				res.Outputs = outputs
+				requestInfo.WriteString(describeOutputs(outputs, failures))
+			}
+
 			// 判断是否成功
+			if len(assertions) > 0 {
+				results, s := runHTTPAssertions(assertions, &httpAssertResponse{
+					StatusCode: resp.StatusCode,
+					Header:     resp.Header,
+					Body:       res.Body,
+					BodySize:   len(body),
+					Latency:    latency,
+				})
+				res.Assertions = results
+				requestInfo.WriteString("\n断言结果:")
+				for _, r := range results {
+					mark := map[bool]string{true: "通过", false: "未通过"}[r.Passed]
+					requestInfo.WriteString(fmt.Sprintf("\n  [%s] %s", mark, r.Assertion))
+					if !r.Passed {
+						requestInfo.WriteString(" - " + r.Message)
+					}
+				}
+				if s {
+					anySuccess = true
+				}
+				return
+			}
 			s := resp.StatusCode >= 200 && resp.StatusCode < 300
 			if config.Result != "" {
 				s = strings.Contains(responseContent, config.Result)
@@ -1258,18 +1760,46 @@ func executeHTTPJobForSummary(job *Jobs) (success bool, stdout string, err error
 				anySuccess = true
 			}
 		}()
-		// 间隔控制（最后一次不等待）
-		if i < attempts && config.Interval > 0 {
-			time.Sleep(time.Duration(config.Interval) * time.Second)
+		// 间隔控制（最后一次不等待），服务端限流时按要求等待
+		if i < attempts {
+			wait := time.Duration(config.Interval) * time.Second
+			if retryWait > 0 {
+				if retryWait > wait {
+					wait = retryWait
+				}
+				if werr := checkRetryWait(ctx, wait, maxRetryWait); werr != nil {
+					requestInfo.WriteString(fmt.Sprintf("\n限流: %v，停止后续请求", werr))
+					rateLimitErr = werr
+					break
+				}
+				requestInfo.WriteString(fmt.Sprintf("\n限流: 等待 %s 后进行第 %d 次请求", formatWait(wait), i+1))
+			}
+			if wait > 0 && !sleepContext(ctx, wait) {
+				break
+			}
+		}
+	}
+
+	if jar != nil {
+		if n, serr := jar.save(); serr != nil {
+			requestInfo.WriteString(fmt.Sprintf("\nCookie罐错误: %v", serr))
+			LogWarn("保存Cookie罐失败", LogField("jar", config.CookieJar), LogError(serr))
+		} else if n > 0 {
+			requestInfo.WriteString(fmt.Sprintf("\nCookie罐: 已保存 %d 项变更，当前共 %d 个Cookie", n, jar.Len()))
 		}
 	}
 
-	return anySuccess, requestInfo.String(), nil
+	res.Success = anySuccess
+	res.Summary = requestInfo.String()
+	if rateLimitErr != nil && !anySuccess {
+		return res, rateLimitErr
+	}
+	return res, nil
 }
 
 // 新增：function模式的聚合执行
-func executeFunctionJobForSummary(job *Jobs) (success bool, stdout string, err error) {
-	config, e := parseFunctionConfig(job.Command)
+func executeFunctionJobForSummary(ctx context.Context, job *Jobs) (success bool, stdout string, err error) {
+	config, e := loadFunctionConfig(job)
 	if e != nil {
 		return false, "", fmt.Errorf("解析函数配置失败: %v", e)
 	}
@@ -1290,10 +1820,11 @@ func executeFunctionJobForSummary(job *Jobs) (success bool, stdout string, err e
 	var lastErr error
 
 	for i := 1; i <= attempts; i++ {
+		setExecAttempt(ctx, i)
 		b.WriteString(fmt.Sprintf("\n=== 第 %d/%d 次执行 ===\n", i, attempts))
 
 		// 创建带超时的上下文
-		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Timeout)*time.Second)
+		attemptCtx, cancel := context.WithTimeout(ctx, time.Duration(config.Timeout)*time.Second)
 
 		// 使用通道实现超时控制
 		resultChan := make(chan struct {
@@ -1320,8 +1851,11 @@ func executeFunctionJobForSummary(job *Jobs) (success bool, stdout string, err e
 				lastErr = result.err
 				b.WriteString(fmt.Sprintf("\n[attempt %d] error: %v\n", i, result.err))
 			}
-		case <-ctx.Done():
+		case <-attemptCtx.Done():
 			lastErr = fmt.Errorf("函数执行超时（%d秒）", config.Timeout)
+			if ctx.Err() != nil {
+				lastErr = fmt.Errorf("函数执行被取消: %v", ctx.Err())
+			}
 			b.WriteString(fmt.Sprintf("\n[attempt %d] timeout: %v\n", i, lastErr))
 		}
 
@@ -1329,7 +1863,9 @@ func executeFunctionJobForSummary(job *Jobs) (success bool, stdout string, err e
 
 		// 间隔控制（最后一次不等待）
 		if i < attempts && config.Interval > 0 {
-			time.Sleep(time.Duration(config.Interval) * time.Second)
+			if !sleepContext(ctx, time.Duration(config.Interval)*time.Second) {
+				break
+			}
 		}
 	}
 
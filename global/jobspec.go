@@ -0,0 +1,231 @@
+package global
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JobSpec 声明式任务定义（YAML定义文件、导入导出共用）
+// notify/breaker 既可以写成JSON字符串，也可以直接写成结构化的 YAML/JSON
+type JobSpec struct {
+	Name        string      `yaml:"name" json:"name"`
+	Desc        string      `yaml:"desc,omitempty" json:"desc,omitempty"`
+	Tags        []string    `yaml:"tags,omitempty" json:"tags,omitempty"`
+	CronExpr    string      `yaml:"cron_expr" json:"cron_expr"`
+	Mode        string      `yaml:"mode" json:"mode"`
+	Command     string      `yaml:"command,omitempty" json:"command,omitempty"`
+	Config      interface{} `yaml:"config,omitempty" json:"config,omitempty"`   // 结构化执行配置，优先于 command
+	Enabled     *bool       `yaml:"enabled,omitempty" json:"enabled,omitempty"` // 默认 true，false 对应 state=2
+	AllowMode   int         `yaml:"allow_mode,omitempty" json:"allow_mode,omitempty"`
+	MaxRunCount uint        `yaml:"max_run_count,omitempty" json:"max_run_count,omitempty"`
+	Notify      interface{} `yaml:"notify,omitempty" json:"notify,omitempty"`
+	Breaker     interface{} `yaml:"breaker,omitempty" json:"breaker,omitempty"`
+	PingToken   string      `yaml:"ping_token,omitempty" json:"ping_token,omitempty"`
+}
+
+// jobSpecFile 定义文件格式：单个任务，或 jobs: 列表
+type jobSpecFile struct {
+	Jobs []JobSpec `yaml:"jobs"`
+}
+
+// ParseJobSpecsYAML 解析YAML定义（支持单个任务、任务列表、jobs: 列表三种写法）
+// 未知字段（如拼错的 cron_exp）报错，避免字段被静默丢弃后同步覆盖任务
+func ParseJobSpecsYAML(data []byte) ([]JobSpec, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if trimmed == "" {
+		return nil, nil
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("YAML解析失败: %v", err)
+	}
+	decode := func(v interface{}) error {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(v); err != nil {
+			return fmt.Errorf("YAML解析失败: %v", err)
+		}
+		return nil
+	}
+	switch {
+	case isJobSpecList(&root):
+		var list []JobSpec
+		if err := decode(&list); err != nil {
+			return nil, err
+		}
+		return list, nil
+	case isJobSpecFile(&root):
+		var file jobSpecFile
+		if err := decode(&file); err != nil {
+			return nil, err
+		}
+		return file.Jobs, nil
+	}
+	var single JobSpec
+	if err := decode(&single); err != nil {
+		return nil, err
+	}
+	return []JobSpec{single}, nil
+}
+
+// isJobSpecList 文档是否为任务列表
+func isJobSpecList(root *yaml.Node) bool {
+	return len(root.Content) > 0 && root.Content[0].Kind == yaml.SequenceNode
+}
+
+// isJobSpecFile 文档是否为 jobs: 列表写法
+func isJobSpecFile(root *yaml.Node) bool {
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return false
+	}
+	m := root.Content[0]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == "jobs" {
+			return true
+		}
+	}
+	return false
+}
+
+// specJSONField 将 notify/breaker 规范为JSON字符串
+func specJSONField(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return strings.TrimSpace(val), nil
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+// specStructuredField 将JSON字符串还原为结构化数据，便于导出为可读的 YAML/JSON
+func specStructuredField(raw string) interface{} {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(raw), &v); err != nil {
+		return raw
+	}
+	return v
+}
+
+// ToJob 转换为任务模型（未设置ID）
+func (s *JobSpec) ToJob() (*Jobs, error) {
+	if strings.TrimSpace(s.Name) == "" {
+		return nil, fmt.Errorf("name 不能为空")
+	}
+	notify, err := specJSONField(s.Notify)
+	if err != nil {
+		return nil, fmt.Errorf("notify 格式错误: %v", err)
+	}
+	breaker, err := specJSONField(s.Breaker)
+	if err != nil {
+		return nil, fmt.Errorf("breaker 格式错误: %v", err)
+	}
+	config, err := specJSONField(s.Config)
+	if err != nil {
+		return nil, fmt.Errorf("config 格式错误: %v", err)
+	}
+	mode := s.Mode
+	if mode == "" {
+		mode = "http"
+	}
+	job := &Jobs{
+		Name:        strings.TrimSpace(s.Name),
+		Desc:        s.Desc,
+		Tags:        JoinJobTags(s.Tags),
+		CronExpr:    s.CronExpr,
+		Mode:        mode,
+		Command:     strings.TrimRight(s.Command, "\n"),
+		Config:      config,
+		AllowMode:   s.AllowMode,
+		MaxRunCount: s.MaxRunCount,
+		Notify:      notify,
+		Breaker:     breaker,
+		PingToken:   s.PingToken,
+	}
+	if s.Enabled != nil && !*s.Enabled {
+		job.State = 2
+	}
+	return job, nil
+}
+
+// JobSpecFromJob 由任务模型生成定义
+func JobSpecFromJob(job *Jobs) JobSpec {
+	spec := JobSpec{
+		Name:        job.Name,
+		Desc:        job.Desc,
+		Tags:        SplitJobTags(job.Tags),
+		CronExpr:    job.CronExpr,
+		Mode:        job.Mode,
+		Command:     job.Command,
+		AllowMode:   job.AllowMode,
+		MaxRunCount: job.MaxRunCount,
+		PingToken:   job.PingToken,
+	}
+	// 已有结构化配置时只导出 config，避免与 command 重复
+	if job.Config != "" {
+		spec.Command = ""
+		spec.Config = specStructuredField(job.Config)
+	}
+	spec.Notify = specStructuredField(job.Notify)
+	spec.Breaker = specStructuredField(job.Breaker)
+	if job.State == 2 {
+		disabled := false
+		spec.Enabled = &disabled
+	}
+	return spec
+}
+
+// SplitJobTags 拆分逗号分隔的标签
+func SplitJobTags(tags string) []string {
+	var list []string
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" && !containsString(list, t) {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// JoinJobTags 规范化标签：去空白、去重后以逗号连接
+func JoinJobTags(tags []string) string {
+	return strings.Join(SplitJobTags(strings.Join(tags, ",")), ",")
+}
+
+// diffJobFields 比较定义与已有任务，返回有差异的字段名
+func diffJobFields(cur *Jobs, want *Jobs) []string {
+	var changed []string
+	check := func(field string, differ bool) {
+		if differ {
+			changed = append(changed, field)
+		}
+	}
+	check("desc", cur.Desc != want.Desc)
+	check("tags", cur.Tags != want.Tags)
+	check("cron_expr", cur.CronExpr != want.CronExpr)
+	check("mode", cur.Mode != want.Mode)
+	check("command", cur.Command != want.Command)
+	check("config", cur.Config != want.Config)
+	check("allow_mode", cur.AllowMode != want.AllowMode)
+	check("max_run_count", cur.MaxRunCount != want.MaxRunCount)
+	check("notify", cur.Notify != want.Notify)
+	check("breaker", cur.Breaker != want.Breaker)
+	check("ping_token", want.PingToken != "" && cur.PingToken != want.PingToken)
+	// 熔断暂停视为启用，不回写
+	curEnabled := cur.State != 2
+	wantEnabled := want.State != 2
+	check("enabled", curEnabled != wantEnabled)
+	return changed
+}
@@ -0,0 +1,364 @@
+package global
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 声明式任务同步（GitOps）
+// 从 jobs.definitions_dir 读取 *.yaml/*.yml 定义，按任务名与 xiaohus_jobs 对账：
+// 新增 create、修改 update、接管同名未托管任务 adopt、删除定义已移除的托管任务 delete。
+
+// 同步动作
+const (
+	SyncActionCreate    = "create"
+	SyncActionUpdate    = "update"
+	SyncActionAdopt     = "adopt"
+	SyncActionDelete    = "delete"
+	SyncActionUnchanged = "unchanged"
+)
+
+// JobSyncChange 单个任务的同步变更
+type JobSyncChange struct {
+	Action string   `json:"action"`
+	Name   string   `json:"name"`
+	JobID  uint     `json:"job_id,omitempty"`
+	File   string   `json:"file,omitempty"`
+	Fields []string `json:"fields,omitempty"` // update/adopt 时有差异的字段
+	Error  string   `json:"error,omitempty"`  // 应用失败原因
+}
+
+// JobSyncResult 同步结果
+type JobSyncResult struct {
+	DryRun  bool            `json:"dry_run"`
+	Dir     string          `json:"dir"`
+	Time    string          `json:"time"`
+	Changes []JobSyncChange `json:"changes"`
+	Errors  []string        `json:"errors"` // 定义文件解析/校验错误
+}
+
+// desiredJob 定义文件中的任务
+type desiredJob struct {
+	job  *Jobs
+	file string
+}
+
+var (
+	jobSyncMu        sync.Mutex
+	jobSyncWatchOnce sync.Once
+)
+
+// getJobDefinitionsDir 定义文件目录，为空表示未启用
+func getJobDefinitionsDir() string {
+	return strings.TrimSpace(GetConfigString("jobs.definitions_dir"))
+}
+
+// loadJobDefinitions 读取并校验定义文件
+// 返回期望状态、解析失败的文件（其托管任务不删除）以及错误列表
+func loadJobDefinitions(dir string) (map[string]desiredJob, map[string]bool, []string, error) {
+	var files []string
+	for _, pattern := range []string{"*.yaml", "*.yml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	desired := make(map[string]desiredJob)
+	keep := make(map[string]bool) // 文件名或任务名：出错时保留对应托管任务
+	var errs []string
+	for _, path := range files {
+		file := filepath.Base(path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: 读取失败: %v", file, err))
+			keep[file] = true
+			continue
+		}
+		specs, err := ParseJobSpecsYAML(data)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", file, err))
+			keep[file] = true
+			continue
+		}
+		for i, spec := range specs {
+			job, err := spec.ToJob()
+			if err == nil {
+				err = ValidateJob(job)
+			}
+			if err == nil {
+				err = PrepareJobConfig(job)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: 第%d个任务 %s: %v", file, i+1, spec.Name, err))
+				keep["name:"+strings.TrimSpace(spec.Name)] = true
+				continue
+			}
+			if prev, ok := desired[job.Name]; ok {
+				errs = append(errs, fmt.Sprintf("%s: 任务名 %s 与 %s 重复，已忽略", file, job.Name, prev.file))
+				continue
+			}
+			job.ManagedBy = file
+			desired[job.Name] = desiredJob{job: job, file: file}
+		}
+	}
+	return desired, keep, errs, nil
+}
+
+// SyncJobDefinitions 执行一次对账，dryRun 时只返回差异不做修改
+func SyncJobDefinitions(dryRun bool) (*JobSyncResult, error) {
+	dir := getJobDefinitionsDir()
+	if dir == "" {
+		return nil, fmt.Errorf("未配置任务定义目录 jobs.definitions_dir")
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("任务定义目录不存在: %s", dir)
+	}
+	if DB == nil {
+		return nil, fmt.Errorf("数据库连接未初始化")
+	}
+
+	jobSyncMu.Lock()
+	defer jobSyncMu.Unlock()
+
+	desired, keep, errs, err := loadJobDefinitions(dir)
+	if err != nil {
+		return nil, err
+	}
+	var existing []Jobs
+	if err := DB.Order("id ASC").Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("查询任务失败: %v", err)
+	}
+	byName := make(map[string]*Jobs)
+	for i := range existing {
+		if _, ok := byName[existing[i].Name]; !ok {
+			byName[existing[i].Name] = &existing[i]
+		}
+	}
+
+	result := &JobSyncResult{
+		DryRun:  dryRun,
+		Dir:     dir,
+		Time:    time.Now().Format("2006-01-02 15:04:05"),
+		Changes: []JobSyncChange{},
+		Errors:  errs,
+	}
+
+	// 按任务名排序，保证结果稳定
+	names := make([]string, 0, len(desired))
+	for name := range desired {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		want := desired[name]
+		cur, ok := byName[name]
+		if !ok {
+			change := JobSyncChange{Action: SyncActionCreate, Name: name, File: want.file}
+			if !dryRun {
+				if err := CreateJob(want.job); err != nil {
+					change.Error = err.Error()
+				}
+				change.JobID = want.job.ID
+			}
+			result.Changes = append(result.Changes, change)
+			continue
+		}
+		fields := diffJobFields(cur, want.job)
+		action := SyncActionUpdate
+		if cur.ManagedBy == "" {
+			action = SyncActionAdopt
+		} else if cur.ManagedBy != want.file {
+			fields = append(fields, "managed_by")
+		}
+		if action == SyncActionUpdate && len(fields) == 0 {
+			result.Changes = append(result.Changes, JobSyncChange{Action: SyncActionUnchanged, Name: name, JobID: cur.ID, File: want.file})
+			continue
+		}
+		change := JobSyncChange{Action: action, Name: name, JobID: cur.ID, File: want.file, Fields: fields}
+		if !dryRun {
+			if err := applyJobDefinition(cur, want.job); err != nil {
+				change.Error = err.Error()
+			}
+		}
+		result.Changes = append(result.Changes, change)
+	}
+
+	// 定义已移除的托管任务
+	for i := range existing {
+		job := &existing[i]
+		if job.ManagedBy == "" {
+			continue
+		}
+		if _, ok := desired[job.Name]; ok {
+			continue
+		}
+		if keep[job.ManagedBy] || keep["name:"+job.Name] {
+			continue
+		}
+		change := JobSyncChange{Action: SyncActionDelete, Name: job.Name, JobID: job.ID, File: job.ManagedBy}
+		if !dryRun {
+			if err := DeleteJob(job); err != nil {
+				change.Error = err.Error()
+			}
+		}
+		result.Changes = append(result.Changes, change)
+	}
+	return result, nil
+}
+
+// applyJobDefinition 将定义写入已有任务并重新调度
+func applyJobDefinition(cur *Jobs, want *Jobs) error {
+	resetBreaker := copyJobDefinition(cur, want)
+	if err := UpdateJob(cur); err != nil {
+		return err
+	}
+	if resetBreaker {
+		ResetBreaker(cur.ID)
+	}
+	return nil
+}
+
+// copyJobDefinition 将定义字段复制到已有任务（保留ID、执行次数等运行数据）
+// 只修改 cur，不触碰内存中的熔断统计；返回值表示写库成功后需要调用 ResetBreaker
+func copyJobDefinition(cur *Jobs, want *Jobs) (resetBreaker bool) {
+	resetBreaker = cur.Breaker != want.Breaker
+	cur.Desc = want.Desc
+	cur.Tags = want.Tags
+	cur.CronExpr = want.CronExpr
+	cur.Mode = want.Mode
+	cur.Command = want.Command
+	cur.Config = want.Config
+	cur.AllowMode = want.AllowMode
+	cur.MaxRunCount = want.MaxRunCount
+	cur.Notify = want.Notify
+	cur.Breaker = want.Breaker
+	if want.PingToken != "" {
+		cur.PingToken = want.PingToken
+	}
+	EnsurePingToken(cur)
+	// 熔断暂停中的任务保持暂停，其余按定义启用/停止
+	if want.State == 2 {
+		cur.State = 2
+	} else if cur.State == 2 {
+		cur.State = 0
+		cur.SuspendReason = ""
+		cur.SuspendedAt = nil
+		resetBreaker = true
+	}
+	cur.ManagedBy = want.ManagedBy
+	return resetBreaker
+}
+
+// logJobSyncResult 记录同步摘要
+func logJobSyncResult(result *JobSyncResult, trigger string) {
+	if ZapLog == nil || result == nil {
+		return
+	}
+	counts := make(map[string]int)
+	failed := 0
+	for _, c := range result.Changes {
+		counts[c.Action]++
+		if c.Error != "" {
+			failed++
+			ZapLog.Error("任务定义同步失败",
+				LogField("name", c.Name),
+				LogField("action", c.Action),
+				LogField("error", c.Error))
+		}
+	}
+	for _, e := range result.Errors {
+		ZapLog.Warn("任务定义文件错误", LogField("error", e))
+	}
+	ZapLog.Info("任务定义同步完成",
+		LogField("trigger", trigger),
+		LogField("create", counts[SyncActionCreate]),
+		LogField("update", counts[SyncActionUpdate]),
+		LogField("adopt", counts[SyncActionAdopt]),
+		LogField("delete", counts[SyncActionDelete]),
+		LogField("failed", failed))
+}
+
+// StartJobDefinitionSync 启动时同步一次，并监听目录变化自动同步（未配置目录时不启用）
+func StartJobDefinitionSync() {
+	dir := getJobDefinitionsDir()
+	if dir == "" {
+		return
+	}
+	jobSyncWatchOnce.Do(func() {
+		result, err := SyncJobDefinitions(false)
+		if err != nil {
+			if ZapLog != nil {
+				ZapLog.Error("任务定义同步失败", LogError(err))
+			}
+			return
+		}
+		logJobSyncResult(result, "startup")
+
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			if ZapLog != nil {
+				ZapLog.Error("创建任务定义目录监听失败", LogError(err))
+			}
+			return
+		}
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			if ZapLog != nil {
+				ZapLog.Error("监听任务定义目录失败", LogField("dir", dir), LogError(err))
+			}
+			return
+		}
+		go watchJobDefinitions(watcher)
+	})
+}
+
+// watchJobDefinitions 文件变化后防抖1秒再同步（git pull 等批量修改只触发一次）
+func watchJobDefinitions(watcher *fsnotify.Watcher) {
+	defer watcher.Close()
+	var timer *time.Timer
+	for {
+		select {
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			ext := strings.ToLower(filepath.Ext(ev.Name))
+			if ext != ".yaml" && ext != ".yml" {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(time.Second, func() {
+				if IsDraining() {
+					return
+				}
+				result, err := SyncJobDefinitions(false)
+				if err != nil {
+					if ZapLog != nil {
+						ZapLog.Error("任务定义同步失败", LogError(err))
+					}
+					return
+				}
+				logJobSyncResult(result, "watch")
+			})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			if ZapLog != nil {
+				ZapLog.Warn("任务定义目录监听错误", LogError(err))
+			}
+		}
+	}
+}
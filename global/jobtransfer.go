@@ -0,0 +1,247 @@
+package global
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// 导入冲突策略（按任务名判断冲突）
+const (
+	ImportSkip      = "skip"      // 跳过已存在的同名任务
+	ImportOverwrite = "overwrite" // 覆盖同名任务
+	ImportRename    = "rename"    // 以新名称导入
+)
+
+// JobImportItem 单个任务的导入结果
+type JobImportItem struct {
+	Index   int    `json:"index"` // 从1开始
+	Name    string `json:"name"`
+	Action  string `json:"action"` // create/overwrite/rename/skip
+	NewName string `json:"new_name,omitempty"`
+	JobID   uint   `json:"job_id,omitempty"`
+	Error   string `json:"error,omitempty"`
+	// Issues 执行配置校验失败时的逐行错误
+	Issues []JobConfigIssue `json:"issues,omitempty"`
+}
+
+// JobImportResult 导入结果
+type JobImportResult struct {
+	Strategy string          `json:"strategy"`
+	DryRun   bool            `json:"dry_run"`
+	Total    int             `json:"total"`
+	Created  int             `json:"created"`
+	Updated  int             `json:"updated"`
+	Skipped  int             `json:"skipped"`
+	Failed   int             `json:"failed"`
+	Items    []JobImportItem `json:"items"`
+}
+
+// ExportJobs 导出任务定义，ids/tags/mode 为空时不过滤；tags 匹配任一标签
+func ExportJobs(ids []uint, tags []string, mode string) ([]JobSpec, error) {
+	query := DB.Order("id ASC")
+	if len(ids) > 0 {
+		query = query.Where("id IN (?)", ids)
+	}
+	if mode != "" {
+		query = query.Where("mode = ?", mode)
+	}
+	var list []Jobs
+	if err := query.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	specs := make([]JobSpec, 0, len(list))
+	for i := range list {
+		if len(tags) > 0 && !jobHasAnyTag(&list[i], tags) {
+			continue
+		}
+		specs = append(specs, JobSpecFromJob(&list[i]))
+	}
+	return specs, nil
+}
+
+// jobHasAnyTag 任务是否包含任一标签
+func jobHasAnyTag(job *Jobs, tags []string) bool {
+	own := SplitJobTags(job.Tags)
+	for _, t := range tags {
+		if containsString(own, strings.TrimSpace(t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImportJobs 批量导入任务
+// 先逐条校验，任一条失败则整体不导入；写库在单个事务中完成，提交后再更新调度器
+func ImportJobs(specs []JobSpec, strategy string, dryRun bool) (*JobImportResult, error) {
+	if strategy == "" {
+		strategy = ImportSkip
+	}
+	if strategy != ImportSkip && strategy != ImportOverwrite && strategy != ImportRename {
+		return nil, fmt.Errorf("不支持的冲突策略: %s（可选 skip/overwrite/rename）", strategy)
+	}
+	result := &JobImportResult{Strategy: strategy, DryRun: dryRun, Total: len(specs), Items: make([]JobImportItem, len(specs))}
+
+	var existing []Jobs
+	if err := DB.Order("id ASC").Find(&existing).Error; err != nil {
+		return nil, fmt.Errorf("查询任务失败: %v", err)
+	}
+	byName := make(map[string]*Jobs)
+	for i := range existing {
+		if _, ok := byName[existing[i].Name]; !ok {
+			byName[existing[i].Name] = &existing[i]
+		}
+	}
+
+	// 第一步：逐条校验并确定动作
+	wants := make([]*Jobs, len(specs))
+	currents := make([]*Jobs, len(specs)) // 覆盖时对应的已有任务
+	usedNames := make(map[string]bool)
+	for i := range specs {
+		item := &result.Items[i]
+		item.Index = i + 1
+		item.Name = specs[i].Name
+		job, err := specs[i].ToJob()
+		if err == nil {
+			err = ValidateJob(job)
+		}
+		if err == nil {
+			err = PrepareJobConfig(job)
+		}
+		if err == nil && usedNames[job.Name] {
+			err = fmt.Errorf("导入数据中任务名重复: %s", job.Name)
+		}
+		if err != nil {
+			item.Error = err.Error()
+			var cfgErr *JobConfigError
+			if errors.As(err, &cfgErr) {
+				item.Issues = cfgErr.Report.Errors
+			}
+			continue
+		}
+		// 报告中使用实际生效的任务名（已去除首尾空白）
+		item.Name = job.Name
+		usedNames[job.Name] = true
+		wants[i] = job
+
+		cur, conflict := byName[job.Name]
+		switch {
+		case !conflict:
+			item.Action = "create"
+		case strategy == ImportSkip:
+			item.Action = ImportSkip
+			item.JobID = cur.ID
+		case strategy == ImportOverwrite:
+			if cur.ManagedBy != "" {
+				item.Error = "同名任务由定义文件 " + cur.ManagedBy + " 管理，不能覆盖"
+				continue
+			}
+			item.Action = ImportOverwrite
+			item.JobID = cur.ID
+			currents[i] = cur
+		default:
+			item.Action = ImportRename
+		}
+	}
+	// 重命名需要避开数据库与本批次中的所有名称
+	for i := range result.Items {
+		item := &result.Items[i]
+		if item.Action != ImportRename || item.Error != "" {
+			continue
+		}
+		for n := 2; ; n++ {
+			candidate := fmt.Sprintf("%s-%d", wants[i].Name, n)
+			if byName[candidate] == nil && !usedNames[candidate] {
+				item.NewName = candidate
+				usedNames[candidate] = true
+				wants[i].Name = candidate
+				break
+			}
+		}
+	}
+	for _, item := range result.Items {
+		if item.Error != "" {
+			result.Failed++
+		}
+	}
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d 个任务校验失败，未导入任何任务", result.Failed)
+	}
+
+	// 第二步：单事务写库
+	touched := make([]*Jobs, 0, len(specs))
+	resetBreakers := make(map[uint]bool) // 提交后再重置内存中的熔断统计，回滚时保持不变
+	if !dryRun {
+		err := DB.Transaction(func(tx *gorm.DB) error {
+			for i := range result.Items {
+				item := &result.Items[i]
+				switch item.Action {
+				case "create", ImportRename:
+					job := wants[i]
+					EnsurePingToken(job)
+					if err := tx.Create(job).Error; err != nil {
+						item.Error = err.Error()
+						return err
+					}
+					item.JobID = job.ID
+					touched = append(touched, job)
+				case ImportOverwrite:
+					cur := currents[i]
+					if copyJobDefinition(cur, wants[i]) {
+						resetBreakers[cur.ID] = true
+					}
+					cur.ManagedBy = ""
+					EnsurePingToken(cur)
+					if err := tx.Save(cur).Error; err != nil {
+						item.Error = err.Error()
+						return err
+					}
+					touched = append(touched, cur)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			result.Failed = 1
+			return result, fmt.Errorf("导入失败，已回滚: %v", err)
+		}
+	}
+
+	for _, item := range result.Items {
+		switch item.Action {
+		case "create", ImportRename:
+			result.Created++
+		case ImportOverwrite:
+			result.Updated++
+		case ImportSkip:
+			result.Skipped++
+		}
+	}
+
+	// 第三步：提交后更新调度器
+	for _, job := range touched {
+		if resetBreakers[job.ID] {
+			ResetBreaker(job.ID)
+		}
+		if err := RescheduleJob(job); err != nil && ZapLog != nil {
+			ZapLog.Error("导入任务调度失败", LogField("id", job.ID), LogField("name", job.Name), LogError(err))
+		}
+	}
+	return result, nil
+}
+
+// RescheduleJob 按任务当前状态重新加入或移出调度器
+func RescheduleJob(job *Jobs) error {
+	taskMu.RLock()
+	_, scheduled := TaskList[job.ID]
+	taskMu.RUnlock()
+	if scheduled {
+		RemoveJob(job.ID)
+	}
+	if job.State == 2 || Timer == nil {
+		return nil
+	}
+	return AddJob(job)
+}
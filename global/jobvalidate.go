@@ -0,0 +1,627 @@
+package global
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 任务配置保存前校验
+// 执行时的解析函数会静默忽略未知标签与非法数值，这里在保存时逐行检查并给出行号，
+// 错误（errors）阻止保存，警告（warnings）仅提示。
+
+// JobConfigIssue 单条校验问题
+type JobConfigIssue struct {
+	Line    int    `json:"line"`           // 行号（从1开始），0表示整体配置
+	Step    string `json:"step,omitempty"` // 工作流步骤名
+	Tag     string `json:"tag,omitempty"`
+	Message string `json:"message"`
+}
+
+// String 生成带行号的描述
+func (i JobConfigIssue) String() string {
+	var b strings.Builder
+	if i.Step != "" {
+		b.WriteString("步骤 " + i.Step + " ")
+	}
+	if i.Line > 0 {
+		fmt.Fprintf(&b, "第%d行 ", i.Line)
+	}
+	if i.Tag != "" {
+		b.WriteString("【" + i.Tag + "】 ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// JobConfigReport 任务配置校验结果
+type JobConfigReport struct {
+	Valid    bool             `json:"valid"`
+	Errors   []JobConfigIssue `json:"errors"`
+	Warnings []JobConfigIssue `json:"warnings"`
+}
+
+func (r *JobConfigReport) errorf(line int, tag, format string, args ...interface{}) {
+	r.Errors = append(r.Errors, JobConfigIssue{Line: line, Tag: tag, Message: fmt.Sprintf(format, args...)})
+	r.Valid = false
+}
+
+func (r *JobConfigReport) warnf(line int, tag, format string, args ...interface{}) {
+	r.Warnings = append(r.Warnings, JobConfigIssue{Line: line, Tag: tag, Message: fmt.Sprintf(format, args...)})
+}
+
+// merge 合并工作流步骤的校验结果
+func (r *JobConfigReport) merge(step string, sub *JobConfigReport) {
+	for _, i := range sub.Errors {
+		i.Step = step
+		r.Errors = append(r.Errors, i)
+		r.Valid = false
+	}
+	for _, i := range sub.Warnings {
+		i.Step = step
+		r.Warnings = append(r.Warnings, i)
+	}
+}
+
+// JobConfigError 配置校验失败，携带完整的校验结果
+type JobConfigError struct {
+	Report *JobConfigReport
+}
+
+func (e *JobConfigError) Error() string {
+	msgs := make([]string, 0, len(e.Report.Errors))
+	for _, i := range e.Report.Errors {
+		msgs = append(msgs, i.String())
+	}
+	return "任务配置校验失败: " + strings.Join(msgs, "；")
+}
+
+// 各模式支持的标签
+var jobConfigTags = map[string][]string{
+	"http":      {"url", "mode", "headers", "data", "body_type", "form", "files", "body_file", "cookies", "cookie_jar", "proxy", "no_proxy", "result", "assert", "auth", "tls", "extract", "extract_metrics", "keep_response", "times", "interval", "max_retry_wait", "timeout", "artifacts"},
+	"command":   {"command", "workdir", "env", "timeout", "times", "interval", "encoding", "success_codes", "warning_codes", "retry_codes", "retries", "stderr_fail", "artifacts"},
+	"script":    {"interpreter", "script", "workdir", "env", "timeout", "times", "interval", "encoding", "success_codes", "warning_codes", "retry_codes", "retries", "stderr_fail", "artifacts"},
+	"ssh":       {"hosts", "port", "user", "password", "key", "passphrase", "known_hosts", "command", "concurrency", "timeout", "policy", "artifacts"},
+	"func":      {"name", "arg", "times", "interval", "timeout", "artifacts"},
+	"heartbeat": {"grace"},
+}
+
+// 可以出现多次并累加的标签，其余标签重复时以最后一行为准
+var repeatableJobTags = map[string]bool{"headers": true, "env": true, "artifacts": true, "form": true, "files": true, "assert": true, "extract": true, "stderr_fail": true, "hosts": true}
+
+// 取值为非负整数的标签
+var intJobTags = map[string]bool{"times": true, "interval": true, "max_retry_wait": true, "retries": true, "timeout": true, "grace": true, "port": true, "concurrency": true}
+
+var (
+	jobTagLineRe = regexp.MustCompile(`^【([^】]*)】(.*)$`)
+	envKeyRe     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	httpMethodRe = regexp.MustCompile(`^[A-Z]+$`)
+)
+
+// 常见的HTTP请求方式，其余方式仅给出警告
+var knownHTTPMethods = []string{"GET", "POST", "PUT", "DELETE", "PATCH", "HEAD", "OPTIONS"}
+
+// 支持的代理协议
+var supportedProxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
+// ValidateJobConfig 校验任务的 mode 与 command 配置
+func ValidateJobConfig(mode, command string) *JobConfigReport {
+	report := &JobConfigReport{Valid: true, Errors: []JobConfigIssue{}, Warnings: []JobConfigIssue{}}
+	if mode == "function" {
+		mode = "func"
+	}
+	switch mode {
+	case "workflow":
+		validateWorkflowConfig(report, command)
+	case "http", "command", "script", "ssh", "func", "heartbeat":
+		validateTaggedConfig(report, mode, command)
+	default:
+		report.errorf(0, "", "不支持的任务模式: %s", mode)
+	}
+	return report
+}
+
+// ValidateJobConfigJSON 校验结构化执行配置（Jobs.Config），问题中的 tag 为字段名
+func ValidateJobConfigJSON(mode, raw string) *JobConfigReport {
+	report := &JobConfigReport{Valid: true, Errors: []JobConfigIssue{}, Warnings: []JobConfigIssue{}}
+	cfg, err := ParseJobConfig(mode, raw)
+	if err != nil {
+		report.errorf(0, "", "%v", err)
+		return report
+	}
+	check := func(tag, value string) {
+		validateJobTagValue(report, mode, 0, tag, value)
+	}
+	checkInts := func(values map[string]int) {
+		for tag, v := range values {
+			check(tag, strconv.Itoa(v))
+		}
+	}
+	switch {
+	case cfg.HTTP != nil:
+		c := cfg.HTTP
+		if strings.TrimSpace(c.URL) == "" {
+			report.errorf(0, "url", "缺少请求地址")
+		} else {
+			check("url", c.URL)
+		}
+		check("mode", c.Mode)
+		check("proxy", c.Proxy)
+		check("no_proxy", c.NoProxy)
+		check("cookie_jar", c.CookieJar)
+		for k := range c.Headers {
+			if strings.TrimSpace(k) == "" {
+				report.errorf(0, "headers", "请求头名称不能为空")
+			}
+		}
+		if c.BodyType != "" {
+			check("body_type", c.BodyType)
+		}
+		for k := range c.Form {
+			if strings.TrimSpace(k) == "" {
+				report.errorf(0, "form", "表单字段名不能为空")
+			}
+		}
+		for k, path := range c.Files {
+			if strings.TrimSpace(k) == "" {
+				report.errorf(0, "files", "文件字段名不能为空")
+			}
+			checkHTTPBodyPath(report, 0, "files", path)
+		}
+		if c.BodyFile != "" {
+			check("body_file", c.BodyFile)
+		}
+		for _, a := range c.Assert {
+			check("assert", a)
+		}
+		if c.Auth != nil {
+			validateHTTPAuth(report, 0, c.Auth)
+		}
+		if c.TLS != nil {
+			validateHTTPTLS(report, 0, c.TLS)
+		}
+		validateHTTPExtract(report, 0, c.Extract)
+		validateHTTPBody(report, c)
+		checkInts(map[string]int{"times": c.Times, "interval": c.Interval, "timeout": c.Timeout, "max_retry_wait": c.MaxRetryWait})
+	case cfg.Command != nil:
+		c := cfg.Command
+		if strings.TrimSpace(c.Command) == "" {
+			report.errorf(0, "command", "命令不能为空")
+		}
+		if c.WorkDir != "" {
+			check("workdir", c.WorkDir)
+		}
+		for _, pair := range c.Env {
+			check("env", pair)
+		}
+		if c.Encoding != "" {
+			check("encoding", c.Encoding)
+		}
+		for _, pattern := range c.StderrFail {
+			check("stderr_fail", pattern)
+		}
+		validateExitPolicy(report, &c.ExitPolicy)
+		checkInts(map[string]int{"times": c.Times, "interval": c.Interval, "timeout": c.Timeout, "retries": c.Retries})
+	case cfg.Script != nil:
+		c := cfg.Script
+		if c.Interpreter != "" {
+			check("interpreter", c.Interpreter)
+		}
+		if c.WorkDir != "" {
+			check("workdir", c.WorkDir)
+		}
+		for _, pair := range c.Env {
+			check("env", pair)
+		}
+		if c.Encoding != "" {
+			check("encoding", c.Encoding)
+		}
+		for _, pattern := range c.StderrFail {
+			check("stderr_fail", pattern)
+		}
+		validateScriptConfig(report, c)
+		validateExitPolicy(report, &c.ExitPolicy)
+		checkInts(map[string]int{"times": c.Times, "interval": c.Interval, "timeout": c.Timeout, "retries": c.Retries})
+	case cfg.SSH != nil:
+		c := cfg.SSH
+		for _, h := range c.Hosts {
+			check("hosts", h)
+		}
+		for tag, value := range map[string]string{"user": c.User, "password": c.Password, "key": c.Key, "passphrase": c.Passphrase, "known_hosts": c.KnownHosts, "policy": c.Policy} {
+			if value != "" {
+				check(tag, value)
+			}
+		}
+		validateSSHConfig(report, c)
+		if c.Concurrency != 0 {
+			check("concurrency", strconv.Itoa(c.Concurrency))
+		}
+		checkInts(map[string]int{"port": c.Port, "timeout": c.Timeout})
+	case cfg.Function != nil:
+		c := cfg.Function
+		check("name", c.Name)
+		checkInts(map[string]int{"times": c.Times, "interval": c.Interval, "timeout": c.Timeout})
+	}
+	sort.SliceStable(report.Errors, func(i, j int) bool { return report.Errors[i].Tag < report.Errors[j].Tag })
+	return report
+}
+
+// validateTaggedConfig 逐行校验【】格式配置
+func validateTaggedConfig(report *JobConfigReport, mode, command string) {
+	known := jobConfigTags[mode]
+	// 命令模式未写【command】时整段内容即命令，不检查无标签的行
+	bareCommand := mode == "command" && !strings.Contains(command, "【command】")
+	seen := make(map[string]int)
+	for i, raw := range strings.Split(command, "\n") {
+		lineNo := i + 1
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+		m := jobTagLineRe.FindStringSubmatch(line)
+		if m == nil {
+			if !bareCommand && mode != "heartbeat" {
+				report.warnf(lineNo, "", "不是【标签】格式，执行时将被忽略")
+			}
+			continue
+		}
+		tag, value := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		if !containsString(known, tag) {
+			msg := fmt.Sprintf("%s 模式不支持该标签", mode)
+			if s := closestJobTag(tag, known); s != "" {
+				msg += fmt.Sprintf("，是否为【%s】？", s)
+			}
+			report.errorf(lineNo, tag, "%s", msg)
+			continue
+		}
+		if prev, ok := seen[tag]; ok && !repeatableJobTags[tag] {
+			report.warnf(lineNo, tag, "与第%d行重复，以本行为准", prev)
+		}
+		seen[tag] = lineNo
+		validateJobTagValue(report, mode, lineNo, tag, value)
+		// 【script】之后为脚本内容，不再按标签校验
+		if mode == "script" && tag == "script" {
+			break
+		}
+	}
+
+	switch mode {
+	case "http":
+		if _, ok := seen["url"]; !ok {
+			report.errorf(0, "url", "缺少请求地址")
+		} else if cfg, err := parseHTTPConfig(command); err == nil {
+			validateHTTPBody(report, cfg)
+		}
+	case "ssh":
+		validateSSHConfig(report, parseSSHTags(command))
+	case "func":
+		if _, ok := seen["name"]; !ok {
+			report.errorf(0, "name", "缺少函数名")
+		}
+	case "command":
+		if strings.TrimSpace(command) == "" {
+			report.errorf(0, "command", "命令不能为空")
+		} else if cfg, err := parseCommandConfig(command); err == nil {
+			validateExitPolicy(report, &cfg.ExitPolicy)
+		}
+	case "script":
+		if _, ok := seen["script"]; !ok {
+			report.errorf(0, "script", "缺少【script】脚本内容")
+		} else if cfg, err := parseScriptConfig(command); err != nil {
+			report.errorf(seen["script"], "script", "%v", err)
+		} else {
+			validateScriptConfig(report, cfg)
+			validateExitPolicy(report, &cfg.ExitPolicy)
+		}
+	}
+}
+
+// validateJobTagValue 校验单个标签的取值
+func validateJobTagValue(report *JobConfigReport, mode string, line int, tag, value string) {
+	if intJobTags[tag] {
+		if value == "" {
+			return
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			report.errorf(line, tag, "应为整数，当前为 %q", value)
+		} else if n < 0 {
+			report.errorf(line, tag, "不能为负数")
+		} else if tag == "timeout" && n == 0 && mode != "http" {
+			report.errorf(line, tag, "超时时间必须大于0")
+		} else if tag == "concurrency" && n == 0 {
+			report.errorf(line, tag, "并发数必须大于0")
+		} else if tag == "port" && (n == 0 || n > 65535) {
+			report.errorf(line, tag, "端口应在 1-65535 之间")
+		}
+		return
+	}
+	// 工作流步骤中含 {{变量}} 的值在执行时才确定
+	if strings.Contains(value, "{{") {
+		return
+	}
+	switch tag {
+	case "url":
+		validateJobURL(report, line, value)
+	case "mode":
+		method := strings.ToUpper(value)
+		if !httpMethodRe.MatchString(method) {
+			report.errorf(line, tag, "请求方式不合法: %s", value)
+		} else if !containsString(knownHTTPMethods, method) {
+			report.warnf(line, tag, "非常见的请求方式: %s", method)
+		}
+	case "headers":
+		for _, h := range strings.Split(value, "|||") {
+			if h = strings.TrimSpace(h); h == "" {
+				continue
+			}
+			if k, _, ok := strings.Cut(h, ":"); !ok || strings.TrimSpace(k) == "" {
+				report.errorf(line, tag, "请求头格式应为 名称:值，当前为 %q", h)
+			}
+		}
+	case "proxy":
+		validateJobProxy(report, line, value)
+	case "cookie_jar":
+		if len(value) > 100 {
+			report.errorf(line, tag, "Cookie罐名称不能超过100个字符")
+		} else if strings.HasPrefix(strings.ToLower(value), "job:") {
+			report.errorf(line, tag, "job: 前缀保留给任务独立的Cookie罐，请使用 %s", CookieJarPerJob)
+		}
+	case "no_proxy":
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if strings.Contains(entry, "/") {
+				if _, _, err := net.ParseCIDR(entry); err != nil {
+					report.errorf(line, tag, "不代理列表中的网段不合法: %s", entry)
+				}
+			}
+		}
+	case "body_type":
+		if !containsString(httpBodyTypes, strings.ToLower(value)) {
+			report.errorf(line, tag, "不支持的请求体类型 %q，可选 %s", value, strings.Join(httpBodyTypes, "/"))
+		}
+	case "form", "files":
+		for _, pair := range strings.Split(value, "|||") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			k, v, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(k) == "" {
+				report.errorf(line, tag, "格式应为 名称=值，当前为 %q", pair)
+			} else if tag == "files" {
+				checkHTTPBodyPath(report, line, tag, strings.TrimSpace(v))
+			}
+		}
+	case "body_file":
+		checkHTTPBodyPath(report, line, tag, value)
+	case "assert":
+		if _, err := parseHTTPAssertion(value); err != nil {
+			report.errorf(line, tag, "%v", err)
+		}
+	case "auth":
+		auth, err := parseHTTPAuth(value)
+		if err != nil {
+			report.errorf(line, tag, "%v", err)
+			return
+		}
+		validateHTTPAuth(report, line, auth)
+	case "tls":
+		tlsCfg, err := parseHTTPTLS(value)
+		if err != nil {
+			report.errorf(line, tag, "%v", err)
+			return
+		}
+		validateHTTPTLS(report, line, tlsCfg)
+	case "extract":
+		extract := make(map[string]string)
+		for _, pair := range strings.Split(value, "|||") {
+			if pair = strings.TrimSpace(pair); pair != "" && !strings.Contains(pair, "=") {
+				report.errorf(line, tag, "格式应为 名称=表达式，当前为 %q", pair)
+			}
+		}
+		parseHTTPPairs(value, extract)
+		validateHTTPExtract(report, line, extract)
+	case "extract_metrics", "keep_response":
+		if _, err := strconv.ParseBool(value); err != nil {
+			report.errorf(line, tag, "应为 true 或 false，当前为 %q", value)
+		}
+	case "env":
+		for _, pair := range strings.Split(value, "|||") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			k, _, ok := strings.Cut(pair, "=")
+			if !ok || !envKeyRe.MatchString(k) {
+				report.errorf(line, tag, "环境变量格式应为 KEY=VALUE，当前为 %q", pair)
+			}
+		}
+	case "workdir":
+		if info, err := os.Stat(value); err != nil {
+			report.warnf(line, tag, "工作目录在当前主机不存在: %s", value)
+		} else if !info.IsDir() {
+			report.errorf(line, tag, "不是目录: %s", value)
+		}
+	case "command":
+		if value == "" {
+			report.errorf(line, tag, "命令不能为空")
+		}
+	case "success_codes", "warning_codes", "retry_codes", "stderr_fail":
+		validateExitPolicyTag(report, line, tag, value)
+	case "hosts", "user", "password", "key", "passphrase", "known_hosts", "policy":
+		validateSSHTagValue(report, line, tag, value)
+	case "interpreter":
+		if _, _, _, err := scriptCommand(&ScriptConfig{Interpreter: value}); err != nil {
+			report.errorf(line, tag, "%v", err)
+		}
+	case "encoding":
+		if _, err := lookupEncoding(value); err != nil {
+			report.errorf(line, tag, "%v，可选 gbk/gb18030/big5/shift_jis/euc-kr/utf-16le 等", err)
+		}
+	case "name":
+		if value == "" {
+			report.errorf(line, tag, "函数名不能为空")
+		} else if _, ok := FuncMap[value]; !ok {
+			names := make([]string, 0, len(FuncMap))
+			for name := range FuncMap {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			msg := "函数不存在: " + value
+			if s := closestJobTag(value, names); s != "" {
+				msg += fmt.Sprintf("，是否为 %s？", s)
+			}
+			report.errorf(line, tag, "%s", msg)
+		}
+	case "arg":
+		if (strings.Count(value, `"`)-strings.Count(value, `\"`))%2 != 0 {
+			report.warnf(line, tag, "引号未闭合")
+		}
+	}
+}
+
+// validateHTTPBody 校验请求体相关标签的组合
+func validateHTTPBody(report *JobConfigReport, c *HTTPConfig) {
+	if !httpConfigHasBody(c) {
+		if c.BodyType != "" {
+			report.warnf(0, "body_type", "未配置请求体内容，body_type 不生效")
+		}
+		return
+	}
+	method := strings.ToUpper(c.Mode)
+	if !httpMethodSendsBody(method) {
+		report.warnf(0, "mode", "%s 请求不发送请求体，已配置的请求体将被忽略", method)
+		return
+	}
+	switch httpBodyType(c) {
+	case HTTPBodyJSON:
+		if !strings.Contains(c.Data, "{{") && !json.Valid([]byte(c.Data)) {
+			report.errorf(0, "data", "body_type 为 json，但请求体不是合法的JSON")
+		}
+	case HTTPBodyFile:
+		if c.BodyFile == "" {
+			report.errorf(0, "body_file", "body_type 为 file，需配置 body_file")
+		}
+	case HTTPBodyMultipart:
+		if c.Data != "" {
+			report.warnf(0, "data", "multipart 请求体只包含 form 与 files，data 将被忽略")
+		}
+	}
+	if httpBodyType(c) != HTTPBodyMultipart && len(c.Files) > 0 {
+		report.warnf(0, "files", "仅 multipart 请求体上传文件，files 将被忽略")
+	}
+}
+
+// checkHTTPBodyPath 检查请求体文件是否存在（文件在执行主机上读取，仅警告）
+func checkHTTPBodyPath(report *JobConfigReport, line int, tag, path string) {
+	if path == "" {
+		report.errorf(line, tag, "文件路径不能为空")
+		return
+	}
+	if info, err := os.Stat(path); err != nil {
+		report.warnf(line, tag, "文件在当前主机不存在: %s", path)
+	} else if info.IsDir() {
+		report.errorf(line, tag, "不是文件: %s", path)
+	}
+}
+
+// validateJobURL 校验请求地址
+func validateJobURL(report *JobConfigReport, line int, value string) {
+	if value == "" {
+		report.errorf(line, "url", "请求地址不能为空")
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		report.errorf(line, "url", "请求地址不合法: %v", err)
+		return
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		report.errorf(line, "url", "请求地址需以 http:// 或 https:// 开头")
+		return
+	}
+	if u.Host == "" {
+		report.errorf(line, "url", "请求地址缺少主机名")
+	}
+}
+
+// validateJobProxy 校验代理地址
+func validateJobProxy(report *JobConfigReport, line int, value string) {
+	if value == "" || strings.EqualFold(value, ProxyDirect) {
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		report.errorf(line, "proxy", "代理地址不合法: %v", err)
+		return
+	}
+	if !containsString(supportedProxySchemes, strings.ToLower(u.Scheme)) {
+		report.errorf(line, "proxy", "不支持的代理协议 %q，可选 %s", u.Scheme, strings.Join(supportedProxySchemes, "/"))
+		return
+	}
+	if u.Host == "" {
+		report.errorf(line, "proxy", "代理地址缺少主机名")
+		return
+	}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			checkSecretRef(report, line, "proxy", "代理密码", pass)
+		}
+	}
+}
+
+// validateWorkflowConfig 校验工作流配置及各步骤的【】配置
+func validateWorkflowConfig(report *JobConfigReport, command string) {
+	cfg, err := parseWorkflowConfig(command)
+	if err != nil {
+		report.errorf(0, "", "%v", err)
+		return
+	}
+	for _, step := range cfg.Steps {
+		mode := step.Type
+		if mode == "function" {
+			mode = "func"
+		}
+		sub := &JobConfigReport{Valid: true}
+		validateTaggedConfig(sub, mode, step.Config)
+		report.merge(step.Name, sub)
+	}
+}
+
+// closestJobTag 查找编辑距离不超过2的候选项，用于提示拼写错误
+func closestJobTag(s string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(strings.ToLower(s), strings.ToLower(c)); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance 计算编辑距离
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
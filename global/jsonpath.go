@@ -0,0 +1,112 @@
+package global
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathLookup 在JSON文本中按路径取值
+// 支持 $.a.b[0].c、a.b.0.c 两种写法，返回值为 interface{}（对象/数组/字符串/数字/布尔/nil）
+func jsonPathLookup(body string, path string) (interface{}, error) {
+	var data interface{}
+	decoder := json.NewDecoder(strings.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("响应不是合法JSON: %v", err)
+	}
+	return jsonPathValue(data, path)
+}
+
+// jsonPathValue 在已解析的JSON数据中按路径取值
+func jsonPathValue(data interface{}, path string) (interface{}, error) {
+	segments, err := splitJSONPath(path)
+	if err != nil {
+		return nil, err
+	}
+	cur := data
+	for _, seg := range segments {
+		switch node := cur.(type) {
+		case map[string]interface{}:
+			v, ok := node[seg]
+			if !ok {
+				return nil, fmt.Errorf("路径 %s 不存在: %s", path, seg)
+			}
+			cur = v
+		case []interface{}:
+			idx, err := strconv.Atoi(seg)
+			if err != nil {
+				return nil, fmt.Errorf("路径 %s 中数组下标无效: %s", path, seg)
+			}
+			if idx < 0 {
+				idx += len(node)
+			}
+			if idx < 0 || idx >= len(node) {
+				return nil, fmt.Errorf("路径 %s 数组下标越界: %s", path, seg)
+			}
+			cur = node[idx]
+		default:
+			return nil, fmt.Errorf("路径 %s 无法继续解析: %s", path, seg)
+		}
+	}
+	return cur, nil
+}
+
+// splitJSONPath 将路径拆分为段：$.data.items[0].name -> [data items 0 name]
+func splitJSONPath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return nil, nil
+	}
+	var segments []string
+	var cur strings.Builder
+	flush := func() {
+		if cur.Len() > 0 {
+			segments = append(segments, cur.String())
+			cur.Reset()
+		}
+	}
+	for i := 0; i < len(path); i++ {
+		ch := path[i]
+		switch ch {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("路径 %s 缺少 ]", path)
+			}
+			key := strings.Trim(path[i+1:i+end], `'"`)
+			segments = append(segments, key)
+			i += end
+		default:
+			cur.WriteByte(ch)
+		}
+	}
+	flush()
+	return segments, nil
+}
+
+// jsonValueString 将JSON值转为字符串（对象/数组返回紧凑JSON）
+func jsonValueString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case json.Number:
+		return val.String()
+	case bool:
+		return strconv.FormatBool(val)
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+		return string(b)
+	}
+}
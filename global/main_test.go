@@ -0,0 +1,68 @@
+package global
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"xiaohuAdmin/models/jobs"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestMain 使用默认配置运行测试，不读取 config.yaml；需要数据库的测试通过 newTestDB 使用内存 SQLite
+func TestMain(m *testing.M) {
+	Viper = viper.New()
+	setDefaultValues()
+	ZapLog = zap.NewNop()
+	os.Exit(m.Run())
+}
+
+// newTestDB 以内存 SQLite 替换 DB（每个测试独立），并切换到临时目录，任务日志写入其中的 runtime/
+func newTestDB(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	dsn := "file:" + strings.ReplaceAll(t.Name(), "/", "_") + "?mode=memory&cache=shared"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&jobs.Jobs{}, &jobs.JobExec{}); err != nil {
+		t.Fatal(err)
+	}
+	prev := DB
+	DB = db
+	t.Cleanup(func() {
+		DB = prev
+		sqlDB.Close()
+	})
+}
+
+// createTestJob 写入任务并在测试结束时清空其熔断统计
+func createTestJob(t *testing.T, job *Jobs) *Jobs {
+	t.Helper()
+	if err := DB.Create(job).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ResetBreaker(job.ID) })
+	return job
+}
+
+// reloadTestJob 读取任务在数据库中的最新状态
+func reloadTestJob(t *testing.T, id uint) *Jobs {
+	t.Helper()
+	var job Jobs
+	if err := DB.First(&job, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return &job
+}
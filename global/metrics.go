@@ -1,6 +1,9 @@
 package global
 
 import (
+	"strconv"
+	"strings"
+
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -19,6 +22,13 @@ var (
 		},
 		[]string{"job_id", "job_name", "mode"},
 	)
+	jobExecWarningTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jobs_exec_warning_total",
+			Help: "Total number of job executions that succeeded with a warning exit code",
+		},
+		[]string{"job_id", "job_name", "mode"},
+	)
 	jobExecDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "jobs_exec_duration_seconds",
@@ -33,14 +43,47 @@ var (
 			Help: "Current number of running jobs",
 		},
 	)
+	httpTransportsGauge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "jobs_http_transports",
+			Help: "Number of cached HTTP transports",
+		},
+		func() float64 { return float64(httpTransports.size()) },
+	)
+	httpOpenConnsGauge = prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Name: "jobs_http_open_conns",
+			Help: "Number of open connections held by HTTP job transports",
+		},
+		func() float64 { return float64(httpPoolCounters.openConns.Load()) },
+	)
+	httpExtractValue = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "jobs_http_extract_value",
+			Help: "Latest numeric value extracted from HTTP job responses",
+		},
+		[]string{"job_id", "job_name", "name"},
+	)
+	httpPoolEventsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "jobs_http_pool_events_total",
+			Help: "HTTP job connection pool events (transport_hit/transport_miss/transport_evict/dial/conn_reused/tls_handshake)",
+		},
+		[]string{"event"},
+	)
 )
 
 func InitMetrics() {
 	// 注册指标（多次调用也安全，Prometheus会去重）
 	prometheus.MustRegister(jobExecTotal)
 	prometheus.MustRegister(jobExecFailTotal)
+	prometheus.MustRegister(jobExecWarningTotal)
 	prometheus.MustRegister(jobExecDuration)
 	prometheus.MustRegister(jobRunningGauge)
+	prometheus.MustRegister(httpTransportsGauge)
+	prometheus.MustRegister(httpOpenConnsGauge)
+	prometheus.MustRegister(httpPoolEventsTotal)
+	prometheus.MustRegister(httpExtractValue)
 }
 
 func MetricsIncExec(jobID, jobName, mode string) {
@@ -51,8 +94,23 @@ func MetricsIncFail(jobID, jobName, mode string) {
 	jobExecFailTotal.WithLabelValues(jobID, jobName, mode).Inc()
 }
 
+func MetricsIncWarning(jobID, jobName, mode string) {
+	jobExecWarningTotal.WithLabelValues(jobID, jobName, mode).Inc()
+}
+
 func MetricsObserveDuration(jobID, jobName, mode string, seconds float64) {
 	jobExecDuration.WithLabelValues(jobID, jobName, mode).Observe(seconds)
 }
 
-func MetricsSetRunning(n float64) { jobRunningGauge.Set(n) }
+func MetricsIncRunning() { jobRunningGauge.Inc() }
+
+func MetricsDecRunning() { jobRunningGauge.Dec() }
+
+// MetricsSetExtractValues 记录HTTP任务提取的数值输出（非数值忽略）
+func MetricsSetExtractValues(jobID, jobName string, outputs map[string]string) {
+	for name, v := range outputs {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			httpExtractValue.WithLabelValues(jobID, jobName, name).Set(f)
+		}
+	}
+}
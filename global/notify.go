@@ -0,0 +1,571 @@
+package global
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// 通知事件类型
+const (
+	NotifyOnFailure   = "failure"   // 执行失败
+	NotifyOnRecovery  = "recovery"  // 失败后恢复成功
+	NotifyOnSuccess   = "success"   // 执行成功
+	NotifyOnWarning   = "warning"   // 执行成功但被标记为警告
+	NotifyOnSlow      = "slow"      // 执行耗时超过阈值
+	NotifyOnSuspended = "suspended" // 任务被熔断暂停
+	NotifyOnMissed    = "missed"    // 心跳监控未按时收到ping
+)
+
+// NotifyChannelConfig 通知渠道配置（config.yaml 中 notify.channels.渠道名）
+type NotifyChannelConfig struct {
+	Type          string            `mapstructure:"type" json:"type"`         // smtp/webhook/dingtalk/wecom/feishu/slack
+	URL           string            `mapstructure:"url" json:"url"`           // Webhook地址
+	Secret        string            `mapstructure:"secret" json:"-"`          // 钉钉/飞书加签密钥
+	Headers       map[string]string `mapstructure:"headers" json:"headers"`   // 通用Webhook附加请求头
+	Host          string            `mapstructure:"host" json:"host"`         // SMTP服务器
+	Port          int               `mapstructure:"port" json:"port"`         // SMTP端口
+	Username      string            `mapstructure:"username" json:"username"` // SMTP用户名
+	Password      string            `mapstructure:"password" json:"-"`        // SMTP密码
+	From          string            `mapstructure:"from" json:"from"`         // 发件人
+	To            []string          `mapstructure:"to" json:"to"`             // 收件人
+	TLS           string            `mapstructure:"tls" json:"tls"`           // SMTP加密：空/starttls（自动）、ssl（隐式TLS）、none
+	CA            string            `mapstructure:"ca" json:"ca"`             // SMTP服务器CA证书（PEM内容、文件路径或 secret: 引用），为空使用系统根证书
+	TitleTemplate string            `mapstructure:"title_template" json:"title_template"`
+	BodyTemplate  string            `mapstructure:"body_template" json:"body_template"`
+}
+
+// NotifyRule 任务订阅规则（Jobs.Notify 字段为 JSON 数组）
+// 示例：[{"channel":"ops","on":["failure","recovery"]},{"channel":"slack","on":["slow"],"slow_seconds":30}]
+type NotifyRule struct {
+	Channel     string   `json:"channel"`
+	On          []string `json:"on"`
+	SlowSeconds int      `json:"slow_seconds,omitempty"`
+}
+
+// NotifyEvent 通知模板数据
+type NotifyEvent struct {
+	Event      string `json:"event"`
+	JobID      uint   `json:"job_id"`
+	JobName    string `json:"job_name"`
+	Mode       string `json:"mode"`
+	ExecID     string `json:"exec_id"`
+	Source     string `json:"source"`
+	Status     string `json:"status"`
+	DurationMs int64  `json:"duration_ms"`
+	Time       string `json:"time"`
+	Host       string `json:"host"`
+	Output     string `json:"output"`
+	Error      string `json:"error"`
+	Warning    string `json:"warning,omitempty"` // 警告原因
+
+	Outputs map[string]string `json:"outputs,omitempty"` // HTTP任务提取的输出，模板中以 {{.Outputs.名称}} 引用
+}
+
+// NotifyMessage 渲染后的通知内容
+type NotifyMessage struct {
+	Title string
+	Body  string
+	Event *NotifyEvent
+}
+
+const (
+	defaultNotifyTitle = `[{{.EventName}}] 任务 {{.JobName}}(#{{.JobID}}) {{.Status}}`
+	defaultNotifyBody  = `任务: {{.JobName}} (#{{.JobID}})
+事件: {{.EventName}}
+状态: {{.Status}}
+执行ID: {{.ExecID}}
+来源: {{.Source}}
+耗时: {{.DurationMs}}ms
+时间: {{.Time}}
+主机: {{.Host}}{{if .Warning}}
+警告: {{.Warning}}{{end}}{{if .Error}}
+错误: {{.Error}}{{end}}{{if .Outputs}}
+提取结果:{{range $name, $value := .Outputs}}
+  {{$name}}: {{$value}}{{end}}{{end}}{{if .Output}}
+输出摘要:
+{{.Output}}{{end}}`
+)
+
+var notifyEventNames = map[string]string{
+	NotifyOnFailure:   "失败",
+	NotifyOnRecovery:  "恢复",
+	NotifyOnSuccess:   "成功",
+	NotifyOnWarning:   "警告",
+	NotifyOnSlow:      "慢执行",
+	NotifyOnSuspended: "熔断暂停",
+	NotifyOnMissed:    "心跳丢失",
+}
+
+// EventName 事件中文名（供模板使用）
+func (e *NotifyEvent) EventName() string {
+	if name, ok := notifyEventNames[e.Event]; ok {
+		return name
+	}
+	return e.Event
+}
+
+// 记录每个任务上一次执行结果，用于判断“恢复”事件
+var jobLastSuccess sync.Map // map[uint]bool
+
+// ParseNotifyRules 解析任务通知规则，空字符串表示不订阅
+func ParseNotifyRules(raw string) ([]NotifyRule, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var rules []NotifyRule
+	if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+		return nil, fmt.Errorf("通知规则不是合法JSON数组: %v", err)
+	}
+	return rules, nil
+}
+
+// ValidateNotifyRules 校验通知规则：渠道必须已配置、事件必须受支持
+func ValidateNotifyRules(raw string) error {
+	rules, err := ParseNotifyRules(raw)
+	if err != nil {
+		return err
+	}
+	for i, r := range rules {
+		if r.Channel == "" {
+			return fmt.Errorf("第%d条通知规则缺少 channel", i+1)
+		}
+		if _, ok := GetNotifyChannel(r.Channel); !ok {
+			return fmt.Errorf("第%d条通知规则的渠道未配置: %s", i+1, r.Channel)
+		}
+		if len(r.On) == 0 {
+			return fmt.Errorf("第%d条通知规则缺少 on", i+1)
+		}
+		for _, on := range r.On {
+			if _, ok := notifyEventNames[on]; !ok {
+				return fmt.Errorf("第%d条通知规则的事件不支持: %s", i+1, on)
+			}
+		}
+		if containsString(r.On, NotifyOnSlow) && r.SlowSeconds <= 0 {
+			return fmt.Errorf("第%d条通知规则订阅了 slow 但未设置 slow_seconds", i+1)
+		}
+	}
+	return nil
+}
+
+// GetNotifyChannel 获取通知渠道配置（渠道名不区分大小写）
+func GetNotifyChannel(name string) (NotifyChannelConfig, bool) {
+	var ch NotifyChannelConfig
+	key := "notify.channels." + strings.ToLower(name)
+	if Viper == nil || !Viper.IsSet(key) {
+		return ch, false
+	}
+	if err := Viper.UnmarshalKey(key, &ch); err != nil {
+		return ch, false
+	}
+	return ch, true
+}
+
+// ListNotifyChannels 列出已配置的通知渠道
+func ListNotifyChannels() map[string]NotifyChannelConfig {
+	result := make(map[string]NotifyChannelConfig)
+	if Viper == nil {
+		return result
+	}
+	for name := range Viper.GetStringMap("notify.channels") {
+		if ch, ok := GetNotifyChannel(name); ok {
+			result[name] = ch
+		}
+	}
+	return result
+}
+
+// newNotifyEvent 根据执行日志构造通知事件
+func newNotifyEvent(event string, log *JobExecLog) *NotifyEvent {
+	host, _ := os.Hostname()
+	output := log.Stdout
+	if output == "" {
+		output = log.HttpResp
+	}
+	if output == "" {
+		output = log.FuncResult
+	}
+	if stderr := strings.TrimSpace(log.Stderr); stderr != "" {
+		output = strings.TrimSpace(output) + "\n[stderr]\n" + stderr
+	}
+	excerpt := GetJobsConfigInt("notify.output_excerpt_bytes", 500)
+	if len(output) > excerpt {
+		output = previewText(output, excerpt) + "..."
+	}
+	return &NotifyEvent{
+		Event:      event,
+		JobID:      log.JobID,
+		JobName:    log.JobName,
+		Mode:       log.Mode,
+		ExecID:     log.ExecID,
+		Source:     log.Source,
+		Status:     log.Status,
+		DurationMs: log.DurationMs,
+		Time:       log.Time,
+		Host:       host,
+		Output:     strings.TrimSpace(output),
+		Error:      log.ErrorMsg,
+		Warning:    log.Warning,
+		Outputs:    log.Outputs,
+	}
+}
+
+// dispatchJobNotifications 根据任务订阅规则发送执行结果通知（异步）
+func dispatchJobNotifications(job *Jobs, log *JobExecLog, success bool) {
+	prev, hasPrev := jobLastSuccess.Load(job.ID)
+	jobLastSuccess.Store(job.ID, success)
+
+	rules, err := ParseNotifyRules(job.Notify)
+	if err != nil || len(rules) == 0 {
+		return
+	}
+	for _, rule := range rules {
+		var events []string
+		if success {
+			// 警告执行发送 warning 事件，不再发送 success
+			if log.Warning != "" {
+				if containsString(rule.On, NotifyOnWarning) {
+					events = append(events, NotifyOnWarning)
+				}
+			} else if containsString(rule.On, NotifyOnSuccess) {
+				events = append(events, NotifyOnSuccess)
+			}
+			if containsString(rule.On, NotifyOnRecovery) && hasPrev && !prev.(bool) {
+				events = append(events, NotifyOnRecovery)
+			}
+		} else if containsString(rule.On, NotifyOnFailure) {
+			events = append(events, NotifyOnFailure)
+		}
+		if containsString(rule.On, NotifyOnSlow) && rule.SlowSeconds > 0 && log.DurationMs >= int64(rule.SlowSeconds)*1000 {
+			events = append(events, NotifyOnSlow)
+		}
+		for _, ev := range events {
+			SendNotifyAsync(rule.Channel, newNotifyEvent(ev, log))
+		}
+	}
+}
+
+// NotifyJobEvent 发送非执行结果类事件（熔断、心跳丢失等），按任务订阅了该事件的规则发送
+func NotifyJobEvent(job *Jobs, event string, log *JobExecLog) {
+	rules, err := ParseNotifyRules(job.Notify)
+	if err != nil {
+		return
+	}
+	for _, rule := range rules {
+		if containsString(rule.On, event) {
+			SendNotifyAsync(rule.Channel, newNotifyEvent(event, log))
+		}
+	}
+}
+
+// SendNotifyAsync 异步发送通知，失败只记录日志
+func SendNotifyAsync(channel string, ev *NotifyEvent) {
+	go func() {
+		if err := SendNotify(channel, ev); err != nil && ZapLog != nil {
+			ZapLog.Error("发送通知失败",
+				LogField("channel", channel),
+				LogField("job_id", ev.JobID),
+				LogField("event", ev.Event),
+				LogError(err))
+		}
+	}()
+}
+
+// SendNotify 同步发送一条通知
+func SendNotify(channel string, ev *NotifyEvent) error {
+	ch, ok := GetNotifyChannel(channel)
+	if !ok {
+		return fmt.Errorf("通知渠道未配置: %s", channel)
+	}
+	msg, err := renderNotifyMessage(ch, ev)
+	if err != nil {
+		return err
+	}
+	return sendToChannel(ch, msg)
+}
+
+// renderNotifyMessage 渲染通知标题与正文
+func renderNotifyMessage(ch NotifyChannelConfig, ev *NotifyEvent) (*NotifyMessage, error) {
+	titleTpl := ch.TitleTemplate
+	if titleTpl == "" {
+		titleTpl = GetConfigString("notify.title_template")
+	}
+	if titleTpl == "" {
+		titleTpl = defaultNotifyTitle
+	}
+	bodyTpl := ch.BodyTemplate
+	if bodyTpl == "" {
+		bodyTpl = GetConfigString("notify.body_template")
+	}
+	if bodyTpl == "" {
+		bodyTpl = defaultNotifyBody
+	}
+	title, err := renderNotifyTemplate("title", titleTpl, ev)
+	if err != nil {
+		return nil, err
+	}
+	body, err := renderNotifyTemplate("body", bodyTpl, ev)
+	if err != nil {
+		return nil, err
+	}
+	return &NotifyMessage{Title: title, Body: body, Event: ev}, nil
+}
+
+func renderNotifyTemplate(name, text string, ev *NotifyEvent) (string, error) {
+	tpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("通知模板解析失败: %v", err)
+	}
+	var b bytes.Buffer
+	if err := tpl.Execute(&b, ev); err != nil {
+		return "", fmt.Errorf("通知模板渲染失败: %v", err)
+	}
+	return b.String(), nil
+}
+
+// sendToChannel 按渠道类型发送
+func sendToChannel(ch NotifyChannelConfig, msg *NotifyMessage) error {
+	switch strings.ToLower(ch.Type) {
+	case "smtp", "email":
+		return sendSMTP(ch, ch.To, msg.Title, msg.Body)
+	case "webhook":
+		payload := map[string]interface{}{
+			"title": msg.Title,
+			"text":  msg.Body,
+			"event": msg.Event,
+		}
+		return postNotifyJSON(ch.URL, ch.Headers, payload)
+	case "dingtalk":
+		target, err := dingTalkSignedURL(ch.URL, ch.Secret)
+		if err != nil {
+			return err
+		}
+		payload := map[string]interface{}{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"title": msg.Title, "text": "### " + msg.Title + "\n\n" + markdownLines(msg.Body)},
+		}
+		return postNotifyJSON(target, nil, payload)
+	case "wecom", "wechat":
+		payload := map[string]interface{}{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": "### " + msg.Title + "\n" + markdownLines(msg.Body)},
+		}
+		return postNotifyJSON(ch.URL, nil, payload)
+	case "feishu", "lark":
+		payload := map[string]interface{}{
+			"msg_type": "text",
+			"content":  map[string]string{"text": msg.Title + "\n" + msg.Body},
+		}
+		if ch.Secret != "" {
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			payload["timestamp"] = ts
+			payload["sign"] = feishuSign(ts, ch.Secret)
+		}
+		return postNotifyJSON(ch.URL, nil, payload)
+	case "slack":
+		payload := map[string]interface{}{
+			"text": "*" + msg.Title + "*\n```" + msg.Body + "```",
+		}
+		return postNotifyJSON(ch.URL, nil, payload)
+	}
+	return fmt.Errorf("不支持的通知渠道类型: %s", ch.Type)
+}
+
+// markdownLines 保留换行（钉钉/企业微信 markdown 需要两个空格换行）
+func markdownLines(text string) string {
+	return strings.ReplaceAll(text, "\n", "  \n")
+}
+
+// dingTalkSignedURL 钉钉加签：timestamp + "\n" + secret 做 HmacSHA256 后 Base64
+func dingTalkSignedURL(rawURL, secret string) (string, error) {
+	if secret == "" {
+		return rawURL, nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("钉钉地址无效: %v", err)
+	}
+	ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+	q := u.Query()
+	q.Set("timestamp", ts)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// feishuSign 飞书加签：以 timestamp + "\n" + secret 为密钥对空串做 HmacSHA256 后 Base64
+func feishuSign(ts, secret string) string {
+	mac := hmac.New(sha256.New, []byte(ts+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// postNotifyJSON 发送JSON请求并检查返回（非2xx或 errcode/code 非0 视为失败）
+func postNotifyJSON(target string, headers map[string]string, payload interface{}) error {
+	if target == "" {
+		return fmt.Errorf("通知地址为空")
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	timeout := time.Duration(GetJobsConfigInt("notify.timeout_seconds", 10)) * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("通知接口返回 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var result struct {
+		ErrCode *int   `json:"errcode"`
+		Code    *int   `json:"code"`
+		ErrMsg  string `json:"errmsg"`
+		Msg     string `json:"msg"`
+	}
+	if json.Unmarshal(body, &result) == nil {
+		if result.ErrCode != nil && *result.ErrCode != 0 {
+			return fmt.Errorf("通知接口返回错误 %d: %s", *result.ErrCode, result.ErrMsg)
+		}
+		if result.Code != nil && *result.Code != 0 {
+			return fmt.Errorf("通知接口返回错误 %d: %s", *result.Code, result.Msg)
+		}
+	}
+	return nil
+}
+
+// sendSMTP 发送邮件，支持 STARTTLS（默认，服务器支持时自动启用）与隐式TLS（tls: ssl）
+func sendSMTP(ch NotifyChannelConfig, to []string, subject, body string) error {
+	if ch.Host == "" || len(to) == 0 {
+		return fmt.Errorf("SMTP配置不完整：需要 host 与收件人")
+	}
+	port := ch.Port
+	if port == 0 {
+		port = 25
+		if strings.EqualFold(ch.TLS, "ssl") {
+			port = 465
+		}
+	}
+	from := ch.From
+	if from == "" {
+		from = ch.Username
+	}
+	addr := net.JoinHostPort(ch.Host, strconv.Itoa(port))
+
+	var msg bytes.Buffer
+	msg.WriteString("From: " + from + "\r\n")
+	msg.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	msg.WriteString("Subject: =?UTF-8?B?" + base64.StdEncoding.EncodeToString([]byte(subject)) + "?=\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: base64\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n\r\n")
+	msg.WriteString(base64.StdEncoding.EncodeToString([]byte(body)) + "\r\n")
+
+	tlsConfig, err := buildTLSClientConfig(&HTTPTLSConfig{CA: ch.CA, ServerName: ch.Host})
+	if err != nil {
+		return fmt.Errorf("SMTP TLS配置无效: %v", err)
+	}
+	timeout := time.Duration(GetJobsConfigInt("notify.timeout_seconds", 10)) * time.Second
+	var conn net.Conn
+	if strings.EqualFold(ch.TLS, "ssl") {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", addr, timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	client, err := smtp.NewClient(conn, ch.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("SMTP握手失败: %v", err)
+	}
+	defer client.Close()
+
+	if !strings.EqualFold(ch.TLS, "ssl") && !strings.EqualFold(ch.TLS, "none") {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("SMTP STARTTLS失败: %v", err)
+			}
+		}
+	}
+	if ch.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(smtp.PlainAuth("", ch.Username, ch.Password, ch.Host)); err != nil {
+				return fmt.Errorf("SMTP认证失败: %v", err)
+			}
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM失败: %v", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("SMTP RCPT TO失败(%s): %v", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA失败: %v", err)
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return fmt.Errorf("写入邮件内容失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("提交邮件失败: %v", err)
+	}
+	return client.Quit()
+}
+
+// firstChannelOfType 查找第一个指定类型的渠道（供函数任务使用）
+func firstChannelOfType(types ...string) (string, NotifyChannelConfig, bool) {
+	for name, ch := range ListNotifyChannels() {
+		for _, t := range types {
+			if strings.EqualFold(ch.Type, t) {
+				return name, ch, true
+			}
+		}
+	}
+	return "", NotifyChannelConfig{}, false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,577 @@
+package global
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// notifyRequest 接收到的通知请求
+type notifyRequest struct {
+	Path    string
+	Query   url.Values
+	Header  http.Header
+	Payload map[string]any
+}
+
+// testNotifyReceiver 记录通知请求，默认返回 {"errcode":0}
+type testNotifyReceiver struct {
+	*httptest.Server
+	requests chan notifyRequest
+
+	mu     sync.Mutex
+	status int
+	reply  string
+}
+
+func newTestNotifyReceiver(t *testing.T) *testNotifyReceiver {
+	t.Helper()
+	r := &testNotifyReceiver{requests: make(chan notifyRequest, 16), status: http.StatusOK, reply: `{"errcode":0}`}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var payload map[string]any
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("通知内容不是合法JSON: %s", body)
+		}
+		r.requests <- notifyRequest{Path: req.URL.Path, Query: req.URL.Query(), Header: req.Header, Payload: payload}
+		r.mu.Lock()
+		status, reply := r.status, r.reply
+		r.mu.Unlock()
+		w.WriteHeader(status)
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *testNotifyReceiver) respond(status int, reply string) {
+	r.mu.Lock()
+	r.status, r.reply = status, reply
+	r.mu.Unlock()
+}
+
+// addChannel 注册指向接收端的通知渠道
+func (r *testNotifyReceiver) addChannel(name, typ string, extra map[string]any) {
+	ch := map[string]any{"type": typ, "url": r.URL + "/" + name}
+	for k, v := range extra {
+		ch[k] = v
+	}
+	Viper.Set("notify.channels."+name, ch)
+}
+
+// expectEvents 等待收到给定事件（不计顺序），并确认没有多余的通知
+func (r *testNotifyReceiver) expectEvents(t *testing.T, want ...string) {
+	t.Helper()
+	var got []string
+	timeout := time.After(2 * time.Second)
+	for len(got) < len(want) {
+		select {
+		case req := <-r.requests:
+			ev, _ := req.Payload["event"].(map[string]any)
+			got = append(got, ev["event"].(string))
+		case <-timeout:
+			t.Fatalf("应收到通知 %v，实际只收到 %v", want, got)
+		}
+	}
+	select {
+	case req := <-r.requests:
+		t.Fatalf("收到多余的通知: %v", req.Payload["event"])
+	case <-time.After(100 * time.Millisecond):
+	}
+	sort.Strings(got)
+	want = append([]string(nil), want...)
+	sort.Strings(want)
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("通知事件不符: 期望 %v，实际 %v", want, got)
+	}
+}
+
+func testNotifyLog(job *Jobs, status string) *JobExecLog {
+	return &JobExecLog{JobID: job.ID, JobName: job.Name, Mode: "command", ExecID: "exec-1", Source: "cron",
+		Status: status, Time: "2026-01-02 03:04:05", Stdout: "done"}
+}
+
+func TestNotifyWebhookPayload(t *testing.T) {
+	r := newTestNotifyReceiver(t)
+	r.addChannel("hook", "webhook", map[string]any{"headers": map[string]any{"X-Token": "abc"}})
+
+	ev := &NotifyEvent{Event: NotifyOnFailure, JobID: 7, JobName: "backup", Status: "失败", Error: "exit 1",
+		Outputs: map[string]string{"size": "42"}}
+	if err := SendNotify("hook", ev); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	req := <-r.requests
+	if req.Path != "/hook" || req.Header.Get("X-Token") != "abc" || !strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		t.Fatalf("请求不符: %s %v", req.Path, req.Header)
+	}
+	if title := req.Payload["title"]; title != "[失败] 任务 backup(#7) 失败" {
+		t.Fatalf("标题不符: %v", title)
+	}
+	text, _ := req.Payload["text"].(string)
+	for _, want := range []string{"事件: 失败", "错误: exit 1", "size: 42"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("正文缺少 %q:\n%s", want, text)
+		}
+	}
+	event, _ := req.Payload["event"].(map[string]any)
+	if event["event"] != NotifyOnFailure || event["job_id"] != float64(7) || event["job_name"] != "backup" {
+		t.Fatalf("事件数据不符: %v", event)
+	}
+
+	// 渠道模板覆盖默认模板
+	r.addChannel("hook2", "webhook", map[string]any{"title_template": "{{.JobName}} {{.EventName}}", "body_template": "{{.Error}}"})
+	if err := SendNotify("hook2", ev); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	req = <-r.requests
+	if req.Payload["title"] != "backup 失败" || req.Payload["text"] != "exit 1" {
+		t.Fatalf("模板渲染不符: %v", req.Payload)
+	}
+}
+
+func TestNotifySlackPayload(t *testing.T) {
+	r := newTestNotifyReceiver(t)
+	r.addChannel("slack", "slack", nil)
+	ev := &NotifyEvent{Event: NotifyOnRecovery, JobID: 3, JobName: "sync", Status: "成功"}
+	if err := SendNotify("slack", ev); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	req := <-r.requests
+	text, _ := req.Payload["text"].(string)
+	if !strings.HasPrefix(text, "*[恢复] 任务 sync(#3) 成功*\n```") || !strings.HasSuffix(text, "```") {
+		t.Fatalf("Slack 消息格式不符: %q", text)
+	}
+	if len(req.Payload) != 1 {
+		t.Fatalf("Slack 消息只应包含 text: %v", req.Payload)
+	}
+}
+
+func TestNotifyReceiverErrors(t *testing.T) {
+	r := newTestNotifyReceiver(t)
+	r.addChannel("errs", "webhook", nil)
+	ev := &NotifyEvent{Event: NotifyOnFailure, JobName: "x"}
+
+	r.respond(http.StatusInternalServerError, "boom")
+	if err := SendNotify("errs", ev); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("非2xx应返回错误: %v", err)
+	}
+	<-r.requests
+
+	r.respond(http.StatusOK, `{"errcode":310000,"errmsg":"sign not match"}`)
+	if err := SendNotify("errs", ev); err == nil || !strings.Contains(err.Error(), "sign not match") {
+		t.Fatalf("errcode 非0应返回错误: %v", err)
+	}
+	<-r.requests
+
+	if err := SendNotify("missing", ev); err == nil {
+		t.Fatal("未配置的渠道应返回错误")
+	}
+}
+
+func TestNotifyEventFiltering(t *testing.T) {
+	r := newTestNotifyReceiver(t)
+	r.addChannel("ops", "webhook", nil)
+	job := &Jobs{ID: 9101, Name: "filter", Notify: `[{"channel":"ops","on":["failure","recovery"]}]`}
+
+	// 首次成功：没有之前的结果，不算恢复
+	dispatchJobNotifications(job, testNotifyLog(job, "成功"), true)
+	r.expectEvents(t)
+
+	// 连续失败每次都通知
+	dispatchJobNotifications(job, testNotifyLog(job, "失败"), false)
+	r.expectEvents(t, NotifyOnFailure)
+	dispatchJobNotifications(job, testNotifyLog(job, "失败"), false)
+	r.expectEvents(t, NotifyOnFailure)
+
+	// 失败后成功：只发一次恢复
+	dispatchJobNotifications(job, testNotifyLog(job, "成功"), true)
+	r.expectEvents(t, NotifyOnRecovery)
+	dispatchJobNotifications(job, testNotifyLog(job, "成功"), true)
+	r.expectEvents(t)
+}
+
+func TestNotifyWarningReplacesSuccess(t *testing.T) {
+	r := newTestNotifyReceiver(t)
+	r.addChannel("warn", "webhook", nil)
+	job := &Jobs{ID: 9102, Name: "warn", Notify: `[{"channel":"warn","on":["success","warning","recovery"]}]`}
+
+	warned := testNotifyLog(job, "成功")
+	warned.Warning = "退出码 2 按警告处理"
+
+	// 警告执行只发 warning，不再发 success
+	dispatchJobNotifications(job, warned, true)
+	r.expectEvents(t, NotifyOnWarning)
+	dispatchJobNotifications(job, testNotifyLog(job, "成功"), true)
+	r.expectEvents(t, NotifyOnSuccess)
+
+	// 失败未订阅；之后的警告执行同时算恢复
+	dispatchJobNotifications(job, testNotifyLog(job, "失败"), false)
+	r.expectEvents(t)
+	dispatchJobNotifications(job, warned, true)
+	r.expectEvents(t, NotifyOnWarning, NotifyOnRecovery)
+
+	// 只订阅 success 的规则收不到警告执行
+	job.ID = 9103
+	job.Notify = `[{"channel":"warn","on":["success"]}]`
+	dispatchJobNotifications(job, warned, true)
+	r.expectEvents(t)
+}
+
+func TestNotifySlowAndJobEvents(t *testing.T) {
+	r := newTestNotifyReceiver(t)
+	r.addChannel("slow", "webhook", nil)
+	job := &Jobs{ID: 9104, Name: "slow", Notify: `[{"channel":"slow","on":["slow","suspended"],"slow_seconds":2}]`}
+
+	fast := testNotifyLog(job, "成功")
+	fast.DurationMs = 1999
+	dispatchJobNotifications(job, fast, true)
+	r.expectEvents(t)
+
+	slow := testNotifyLog(job, "失败")
+	slow.DurationMs = 2000
+	dispatchJobNotifications(job, slow, false)
+	r.expectEvents(t, NotifyOnSlow)
+
+	// 非执行结果事件只发给订阅了该事件的规则
+	NotifyJobEvent(job, NotifyOnSuspended, testNotifyLog(job, "熔断暂停"))
+	r.expectEvents(t, NotifyOnSuspended)
+	NotifyJobEvent(job, NotifyOnMissed, testNotifyLog(job, "失败"))
+	r.expectEvents(t)
+}
+
+// testHMAC HmacSHA256 后 Base64
+func testHMAC(key, msg string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(msg))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestNotifyDingTalkPayload(t *testing.T) {
+	r := newTestNotifyReceiver(t)
+	r.addChannel("ding", "dingtalk", map[string]any{"secret": "SEC-ding", "body_template": "第一行\n第二行"})
+	ev := &NotifyEvent{Event: NotifyOnFailure, JobID: 5, JobName: "report", Status: "失败"}
+	before := time.Now().UnixMilli()
+	if err := SendNotify("ding", ev); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	req := <-r.requests
+
+	// 加签：timestamp 为毫秒时间戳，sign = Base64(HmacSHA256(secret, timestamp+"\n"+secret))
+	ts := req.Query.Get("timestamp")
+	ms, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || ms < before || ms > time.Now().UnixMilli() {
+		t.Fatalf("timestamp 不符: %q", ts)
+	}
+	if sign := req.Query.Get("sign"); sign != testHMAC("SEC-ding", ts+"\n"+"SEC-ding") {
+		t.Fatalf("签名不符: %q", sign)
+	}
+	if req.Payload["msgtype"] != "markdown" {
+		t.Fatalf("消息类型不符: %v", req.Payload)
+	}
+	md, _ := req.Payload["markdown"].(map[string]any)
+	if md["title"] != "[失败] 任务 report(#5) 失败" || md["text"] != "### [失败] 任务 report(#5) 失败\n\n第一行  \n第二行" {
+		t.Fatalf("markdown 内容不符: %v", md)
+	}
+
+	// 未配置 secret 时地址不变
+	if u, err := dingTalkSignedURL("https://oapi.dingtalk.com/robot/send?access_token=x", ""); err != nil || u != "https://oapi.dingtalk.com/robot/send?access_token=x" {
+		t.Fatalf("未加签地址不应改变: %s %v", u, err)
+	}
+	u, err := dingTalkSignedURL("https://oapi.dingtalk.com/robot/send?access_token=x", "s")
+	if err != nil || !strings.Contains(u, "access_token=x") || !strings.Contains(u, "sign=") {
+		t.Fatalf("加签应保留原有参数: %s %v", u, err)
+	}
+}
+
+func TestNotifyWeComPayload(t *testing.T) {
+	r := newTestNotifyReceiver(t)
+	r.addChannel("wecom", "wecom", map[string]any{"body_template": "状态: {{.Status}}\n耗时: {{.DurationMs}}ms"})
+	ev := &NotifyEvent{Event: NotifyOnSlow, JobID: 8, JobName: "etl", Status: "成功", DurationMs: 1500}
+	if err := SendNotify("wecom", ev); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	req := <-r.requests
+	if req.Payload["msgtype"] != "markdown" || len(req.Payload) != 2 {
+		t.Fatalf("消息格式不符: %v", req.Payload)
+	}
+	md, _ := req.Payload["markdown"].(map[string]any)
+	if md["content"] != "### [慢执行] 任务 etl(#8) 成功\n状态: 成功  \n耗时: 1500ms" {
+		t.Fatalf("markdown 内容不符: %q", md["content"])
+	}
+}
+
+func TestNotifyFeishuPayload(t *testing.T) {
+	r := newTestNotifyReceiver(t)
+	r.addChannel("feishu", "feishu", map[string]any{"secret": "SEC-feishu", "body_template": "错误: {{.Error}}"})
+	ev := &NotifyEvent{Event: NotifyOnFailure, JobID: 2, JobName: "sync", Status: "失败", Error: "timeout"}
+	before := time.Now().Unix()
+	if err := SendNotify("feishu", ev); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	req := <-r.requests
+	if req.Payload["msg_type"] != "text" {
+		t.Fatalf("消息类型不符: %v", req.Payload)
+	}
+	content, _ := req.Payload["content"].(map[string]any)
+	if content["text"] != "[失败] 任务 sync(#2) 失败\n错误: timeout" {
+		t.Fatalf("消息内容不符: %q", content["text"])
+	}
+
+	// 加签：timestamp 为秒级时间戳，以 timestamp+"\n"+secret 为密钥对空串签名
+	ts, _ := req.Payload["timestamp"].(string)
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sec < before || sec > time.Now().Unix() {
+		t.Fatalf("timestamp 不符: %q", ts)
+	}
+	if sign := req.Payload["sign"]; sign != testHMAC(ts+"\n"+"SEC-feishu", "") || sign != feishuSign(ts, "SEC-feishu") {
+		t.Fatalf("签名不符: %v", sign)
+	}
+
+	// 未配置 secret 时不带签名字段
+	r.addChannel("feishu2", "lark", nil)
+	if err := SendNotify("feishu2", ev); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	req = <-r.requests
+	if _, ok := req.Payload["sign"]; ok || req.Payload["timestamp"] != nil {
+		t.Fatalf("未配置 secret 不应加签: %v", req.Payload)
+	}
+}
+
+// smtpSession SMTP 替身服务记录的一次会话
+type smtpSession struct {
+	TLS      bool // 发送 MAIL FROM 时连接是否已加密
+	AuthUser string
+	AuthPass string
+	From     string
+	To       []string
+	Data     string
+}
+
+// testSMTPServer 基于 net.Listener 的 SMTP 替身：
+// 支持 STARTTLS（starttls 为 true 时通告）、隐式TLS（ssl）、AUTH PLAIN，记录信封与邮件内容
+type testSMTPServer struct {
+	net.Listener
+	sessions chan smtpSession
+	tlsConf  *tls.Config
+	starttls bool
+	caPEM    string
+}
+
+func newTestSMTPServer(t *testing.T, ssl, starttls bool) *testSMTPServer {
+	t.Helper()
+	// 借用 httptest 的自签名证书（包含 127.0.0.1 的 IP SAN）
+	certSrv := httptest.NewTLSServer(http.NotFoundHandler())
+	cert := certSrv.TLS.Certificates[0]
+	caPEM := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certSrv.Certificate().Raw}))
+	certSrv.Close()
+
+	s := &testSMTPServer{
+		sessions: make(chan smtpSession, 4),
+		tlsConf:  &tls.Config{Certificates: []tls.Certificate{cert}},
+		starttls: starttls,
+		caPEM:    caPEM,
+	}
+	var err error
+	if ssl {
+		s.Listener, err = tls.Listen("tcp", "127.0.0.1:0", s.tlsConf)
+	} else {
+		s.Listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	go func() {
+		for {
+			conn, err := s.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn, ssl)
+		}
+	}()
+	return s
+}
+
+func (s *testSMTPServer) port() int {
+	return s.Addr().(*net.TCPAddr).Port
+}
+
+func (s *testSMTPServer) serve(conn net.Conn, encrypted bool) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	tp := textproto.NewConn(conn)
+	var sess smtpSession
+	tp.PrintfLine("220 localhost ESMTP test")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			lines := []string{"localhost"}
+			if s.starttls && !encrypted {
+				lines = append(lines, "STARTTLS")
+			}
+			lines = append(lines, "AUTH PLAIN")
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, l)
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 ready to start TLS")
+			tlsConn := tls.Server(conn, s.tlsConf)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tp, encrypted = tlsConn, textproto.NewConn(tlsConn), true
+		case "AUTH":
+			mech, initial, _ := strings.Cut(arg, " ")
+			raw, err := base64.StdEncoding.DecodeString(initial)
+			parts := strings.Split(string(raw), "\x00")
+			if mech != "PLAIN" || err != nil || len(parts) != 3 {
+				tp.PrintfLine("535 bad auth")
+				continue
+			}
+			sess.AuthUser, sess.AuthPass = parts[1], parts[2]
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			sess.TLS = encrypted
+			sess.From = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			sess.To = append(sess.To, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			sess.Data = string(data)
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			s.sessions <- sess
+			return
+		default:
+			tp.PrintfLine("502 unknown command")
+		}
+	}
+}
+
+// parseTestMail 解析邮件，返回解码后的主题与正文
+func parseTestMail(t *testing.T, data string) (*mail.Message, string, string) {
+	t.Helper()
+	m, err := mail.ReadMessage(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("邮件格式错误: %v\n%s", err, data)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("主题解码失败: %v", err)
+	}
+	raw, _ := io.ReadAll(m.Body)
+	body, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(raw)))
+	if err != nil {
+		t.Fatalf("正文不是base64: %v", err)
+	}
+	return m, subject, string(body)
+}
+
+func TestNotifySMTP(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		tls      string
+		ssl      bool
+		starttls bool // 服务器是否通告 STARTTLS
+		wantTLS  bool
+		username string
+	}{
+		{name: "plain", tls: "none", starttls: true, username: "mailer@example.com"},
+		{name: "starttls", tls: "", starttls: true, wantTLS: true, username: "mailer@example.com"},
+		{name: "ssl", tls: "ssl", ssl: true, wantTLS: true, username: "mailer@example.com"},
+		{name: "no_starttls_no_auth", tls: "starttls"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := newTestSMTPServer(t, tc.ssl, tc.starttls)
+			ch := NotifyChannelConfig{
+				Type: "smtp", Host: "127.0.0.1", Port: srv.port(), TLS: tc.tls, CA: srv.caPEM,
+				Username: tc.username, Password: "p@ss", From: "jobs@example.com",
+				To: []string{"ops@example.com", "dev@example.com"},
+			}
+			msg := &NotifyMessage{Title: "[失败] 任务 备份(#1) 失败", Body: "错误: exit 1\n输出: 无"}
+			if err := sendToChannel(ch, msg); err != nil {
+				t.Fatalf("发送邮件失败: %v", err)
+			}
+			var sess smtpSession
+			select {
+			case sess = <-srv.sessions:
+			case <-time.After(3 * time.Second):
+				t.Fatal("未收到SMTP会话")
+			}
+
+			if sess.TLS != tc.wantTLS {
+				t.Fatalf("加密状态不符: 期望 %v，实际 %v", tc.wantTLS, sess.TLS)
+			}
+			if sess.AuthUser != tc.username || (tc.username != "" && sess.AuthPass != "p@ss") {
+				t.Fatalf("认证信息不符: %q/%q", sess.AuthUser, sess.AuthPass)
+			}
+			if sess.From != "jobs@example.com" || strings.Join(sess.To, ",") != "ops@example.com,dev@example.com" {
+				t.Fatalf("信封不符: from=%s to=%v", sess.From, sess.To)
+			}
+			m, subject, body := parseTestMail(t, sess.Data)
+			if m.Header.Get("From") != "jobs@example.com" || m.Header.Get("To") != "ops@example.com, dev@example.com" {
+				t.Fatalf("邮件头不符: %v", m.Header)
+			}
+			if subject != msg.Title || body != msg.Body {
+				t.Fatalf("邮件内容不符: %q / %q", subject, body)
+			}
+		})
+	}
+}
+
+func TestNotifySMTPErrors(t *testing.T) {
+	// 未指定 CA 时自签名证书校验失败
+	srv := newTestSMTPServer(t, false, true)
+	ch := NotifyChannelConfig{Type: "smtp", Host: "127.0.0.1", Port: srv.port(), From: "jobs@example.com", To: []string{"ops@example.com"}}
+	if err := sendSMTP(ch, ch.To, "t", "b"); err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("证书不受信任时应失败: %v", err)
+	}
+
+	// 未设置 from 时使用用户名作为发件人
+	ch.CA, ch.From, ch.Username, ch.Password = srv.caPEM, "", "mailer@example.com", "x"
+	if err := sendSMTP(ch, ch.To, "t", "b"); err != nil {
+		t.Fatalf("发送邮件失败: %v", err)
+	}
+	if sess := <-srv.sessions; sess.From != "mailer@example.com" {
+		t.Fatalf("发件人应为用户名: %s", sess.From)
+	}
+
+	if err := sendSMTP(NotifyChannelConfig{Host: "127.0.0.1"}, nil, "t", "b"); err == nil {
+		t.Fatal("缺少收件人应返回错误")
+	}
+}
@@ -0,0 +1,255 @@
+package global
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 脚本任务（mode: "script"）
+//
+//	【interpreter】python3
+//	【timeout】120
+//	【env】API_BASE=https://example.com
+//	【script】
+//	import os
+//	print(os.environ["API_BASE"])
+//
+// 【script】之后的所有行原样作为脚本内容（不去除缩进与空行），其余标签与命令模式相同。
+// 解释器可选 sh、bash、python3、node，或写成 #!/usr/bin/env ruby 形式的 shebang；
+// 未配置【interpreter】时使用脚本首行的 shebang，都没有时使用 sh。
+// 执行时脚本写入仅当前用户可访问的临时目录（目录 0700、文件 0600），结束后删除；
+// 输出中的临时路径替换为 script.扩展名，报错行号即保存的脚本行号，并在 stderr 末尾附上对应的脚本行。
+
+// ScriptConfig 脚本任务配置结构
+type ScriptConfig struct {
+	Interpreter string   `json:"interpreter,omitempty"` // 解释器或 shebang，为空时使用脚本首行 shebang
+	Script      string   `json:"script"`                // 脚本内容
+	WorkDir     string   `json:"work_dir,omitempty"`    // 工作目录
+	Env         []string `json:"env,omitempty"`         // 环境变量
+	Timeout     int      `json:"timeout"`               // 超时时间（秒）
+	Times       int      `json:"times,omitempty"`
+	Interval    int      `json:"interval,omitempty"`
+	Encoding    string   `json:"encoding,omitempty"` // 输出编码，为空时自动识别
+	ExitPolicy
+}
+
+// 支持的解释器及脚本文件扩展名
+var scriptInterpreters = map[string]string{"sh": ".sh", "bash": ".sh", "python3": ".py", "node": ".js"}
+
+// parseScriptConfig 解析脚本任务配置，【script】之后的内容原样作为脚本
+func parseScriptConfig(command string) (*ScriptConfig, error) {
+	config := &ScriptConfig{
+		Timeout: GetJobsConfigInt("jobs.default_timeout_seconds", 30),
+		Env:     make([]string, 0),
+	}
+	header, script, ok := splitScriptCommand(command)
+	if !ok {
+		return nil, fmt.Errorf("缺少【script】脚本内容")
+	}
+	config.Script = script
+	for _, line := range strings.Split(header, "\n") {
+		m := jobTagLineRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		tag, value := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		switch tag {
+		case "interpreter":
+			config.Interpreter = value
+		case "workdir":
+			config.WorkDir = value
+		case "env":
+			for _, envVar := range strings.Split(value, "|||") {
+				if envVar = strings.TrimSpace(envVar); envVar != "" {
+					config.Env = append(config.Env, envVar)
+				}
+			}
+		case "timeout", "times", "interval":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			switch tag {
+			case "timeout":
+				config.Timeout = n
+			case "times":
+				config.Times = n
+			case "interval":
+				config.Interval = n
+			}
+		case "encoding":
+			config.Encoding = strings.ToLower(value)
+		default:
+			parseExitPolicyTag(&config.ExitPolicy, tag, value)
+		}
+	}
+	if strings.TrimSpace(config.Script) == "" {
+		return nil, fmt.Errorf("脚本内容不能为空")
+	}
+	return config, nil
+}
+
+// splitScriptCommand 拆分【script】之前的标签行与之后的脚本内容
+// 【script】同一行的内容作为脚本第一行
+func splitScriptCommand(command string) (header, script string, ok bool) {
+	lines := strings.Split(strings.ReplaceAll(command, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "【script】") {
+			continue
+		}
+		body := lines[i+1:]
+		if first := strings.TrimSpace(strings.TrimPrefix(trimmed, "【script】")); first != "" {
+			body = append([]string{first}, body...)
+		}
+		return strings.Join(lines[:i], "\n"), strings.Join(body, "\n"), true
+	}
+	return command, "", false
+}
+
+// loadScriptConfig 读取脚本任务配置
+func loadScriptConfig(job *Jobs) (*ScriptConfig, error) {
+	if strings.TrimSpace(job.Config) == "" {
+		return parseScriptConfig(job.Command)
+	}
+	cfg, err := ParseJobConfig("script", job.Config)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.Script, nil
+}
+
+func applyScriptConfigDefaults(c *ScriptConfig) {
+	if c.Env == nil {
+		c.Env = make([]string, 0)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = GetJobsConfigInt("jobs.default_timeout_seconds", 30)
+	}
+	c.Script = strings.ReplaceAll(c.Script, "\r\n", "\n")
+}
+
+// scriptCommand 确定执行脚本的程序与参数，返回程序、参数与脚本文件扩展名
+func scriptCommand(c *ScriptConfig) (name string, args []string, ext string, err error) {
+	interp := strings.TrimSpace(c.Interpreter)
+	if interp == "" {
+		if first, _, _ := strings.Cut(c.Script, "\n"); strings.HasPrefix(first, "#!") {
+			interp = strings.TrimSpace(first)
+		} else {
+			interp = "sh"
+		}
+	}
+	if strings.HasPrefix(interp, "#!") {
+		fields := strings.Fields(strings.TrimPrefix(interp, "#!"))
+		if len(fields) == 0 {
+			return "", nil, "", fmt.Errorf("shebang 缺少解释器: %s", interp)
+		}
+		// 按 shebang 中的解释器名称选择扩展名，如 #!/usr/bin/env python3
+		for _, f := range fields {
+			if e, ok := scriptInterpreters[filepath.Base(f)]; ok {
+				ext = e
+			}
+		}
+		return fields[0], fields[1:], ext, nil
+	}
+	ext, ok := scriptInterpreters[interp]
+	if !ok {
+		return "", nil, "", fmt.Errorf("不支持的解释器 %q，可选 sh/bash/python3/node 或 #!shebang", interp)
+	}
+	return interp, nil, ext, nil
+}
+
+// executeScriptJob 将脚本写入私有临时目录后执行
+// ctx 用于外部取消（如工作流步骤超时），脚本自身超时由【timeout】控制
+func executeScriptJob(ctx context.Context, job *Jobs, sink *artifactSink) (success bool, command string, exitCode int, stdout string, stderr string, err error) {
+	config, err := loadScriptConfig(job)
+	if err != nil {
+		return false, "", 0, "", "", fmt.Errorf("解析脚本配置失败: %v", err)
+	}
+	name, args, ext, err := scriptCommand(config)
+	if err != nil {
+		return false, "", 0, "", "", err
+	}
+	// MkdirTemp 创建的目录权限为 0700，其他用户无法读取或替换脚本
+	dir, err := os.MkdirTemp("", "xiaohu-script-")
+	if err != nil {
+		return false, "", 0, "", "", fmt.Errorf("创建脚本临时目录失败: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	base := "script" + ext
+	path := filepath.Join(dir, base)
+	content := config.Script
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return false, "", 0, "", "", fmt.Errorf("写入脚本文件失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(config.Timeout)*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, name, append(args, path)...)
+	command = strings.Join(append(append([]string{name}, args...), base), " ")
+	exitCode, stdout, stderr, err = runJobProcess(ctx, cmd, config.WorkDir, config.Env, config.Encoding, sink)
+	// 临时路径替换为脚本名，行号与保存的脚本一致
+	stdout = strings.ReplaceAll(stdout, path, base)
+	stderr = strings.ReplaceAll(stderr, path, base)
+	success, err = config.ExitPolicy.apply(exitCode, stderr, err)
+	if !success {
+		stderr += scriptErrorLines(stderr, base, config.Script)
+	}
+	return success, command, exitCode, stdout, stderr, err
+}
+
+// scriptErrorLines 从报错中找出引用的脚本行号，附上对应的脚本内容
+// 兼容 bash（script.sh: line 3:）、dash（script.sh: 3:）、python（File "script.py", line 3）与 node（script.js:3）
+func scriptErrorLines(stderr, base, script string) string {
+	re := regexp.MustCompile(regexp.QuoteMeta(base) + `(?:", line |: line |:\s*)(\d+)`)
+	lines := strings.Split(script, "\n")
+	seen := make(map[int]bool)
+	var nums []int
+	for _, m := range re.FindAllStringSubmatch(stderr, -1) {
+		n, err := strconv.Atoi(m[1])
+		if err != nil || n < 1 || n > len(lines) || seen[n] {
+			continue
+		}
+		seen[n] = true
+		nums = append(nums, n)
+	}
+	if len(nums) == 0 {
+		return ""
+	}
+	sort.Ints(nums)
+	var b strings.Builder
+	b.WriteString("\n[脚本出错位置]")
+	for _, n := range nums {
+		b.WriteString(fmt.Sprintf("\n  第%d行: %s", n, strings.TrimRight(lines[n-1], " \t")))
+	}
+	return b.String()
+}
+
+// validateScriptConfig 校验脚本内容与解释器（【interpreter】的取值已单独校验）
+func validateScriptConfig(report *JobConfigReport, c *ScriptConfig) {
+	if strings.TrimSpace(c.Script) == "" {
+		report.errorf(0, "script", "脚本内容不能为空")
+		return
+	}
+	name, _, _, err := scriptCommand(c)
+	if err != nil {
+		if c.Interpreter == "" {
+			report.errorf(0, "script", "%v", err)
+		}
+		return
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		report.warnf(0, "interpreter", "解释器在当前主机不存在: %s", name)
+	}
+}
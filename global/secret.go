@@ -0,0 +1,81 @@
+package global
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 密钥引用
+// 认证、证书等敏感配置可写为引用，执行时再解析，避免明文保存在任务配置中：
+//
+//	env:API_TOKEN        环境变量
+//	file:/etc/app/token  文件内容（去除末尾换行）
+//	secret:api_token     配置文件 secrets.api_token
+//
+// 不带以上前缀的值按原文使用。
+
+// secretRefPrefixes 支持的引用前缀
+var secretRefPrefixes = []string{"env:", "file:", "secret:"}
+
+// IsSecretRef 是否为密钥引用
+func IsSecretRef(value string) bool {
+	for _, p := range secretRefPrefixes {
+		if strings.HasPrefix(value, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveSecret 解析密钥引用，非引用原样返回
+func ResolveSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env:"):
+		name := strings.TrimPrefix(value, "env:")
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("环境变量 %s 未设置", name)
+		}
+		return v, nil
+	case strings.HasPrefix(value, "file:"):
+		path := strings.TrimPrefix(value, "file:")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("读取密钥文件失败: %v", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, "secret:"):
+		name := strings.TrimPrefix(value, "secret:")
+		v, ok := lookupConfigSecret(name)
+		if !ok {
+			return "", fmt.Errorf("密钥 %s 未在配置 secrets 中定义", name)
+		}
+		return v, nil
+	}
+	return value, nil
+}
+
+// lookupConfigSecret 查找配置文件中的密钥
+func lookupConfigSecret(name string) (string, bool) {
+	cfg := GetGlobalConfig()
+	if cfg == nil {
+		return "", false
+	}
+	v, ok := cfg.Secrets[strings.ToLower(name)]
+	return v, ok
+}
+
+// checkSecretRef 校验密钥引用（保存时调用）：引用不存在给出警告，明文给出提示
+func checkSecretRef(report *JobConfigReport, line int, tag, field, value string) {
+	if value == "" {
+		return
+	}
+	if !IsSecretRef(value) {
+		report.warnf(line, tag, "%s 为明文，建议使用 env:/file:/secret: 引用", field)
+		return
+	}
+	if _, err := ResolveSecret(value); err != nil {
+		report.warnf(line, tag, "%s 当前无法解析: %v", field, err)
+	}
+}
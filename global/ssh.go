@@ -0,0 +1,540 @@
+package global
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SSH远程执行任务（mode: "ssh"）
+//
+//	【hosts】10.0.0.11,10.0.0.12:2222
+//	【user】deploy
+//	【key】secret:deploy_key
+//	【command】systemctl restart app
+//	【concurrency】2
+//	【timeout】60
+//	【policy】all
+//
+// 【hosts】可用逗号或 ||| 分隔、可写多行，主机可带端口，未带端口时使用【port】（默认22）。
+// 认证使用【key】私钥（加密私钥配合【passphrase】）或【password】，均支持 env:/file:/secret: 引用；
+// PEM 私钥为多行内容，无法写在单行标签中，【key】必须使用引用；
+// 主机密钥按【known_hosts】（默认 jobs.ssh_known_hosts，再默认 ~/.ssh/known_hosts）校验，未登记或不一致时拒绝连接。
+// 各主机并发执行（【concurrency】，默认 jobs.ssh_concurrency），【timeout】为单台主机的连接与执行超时；
+// 【policy】all 要求全部主机成功，any 只要求任一主机成功。每台主机的输出、退出码与耗时记录在聚合日志的 hosts 中。
+
+// 聚合日志中单台主机输出的保留长度，完整输出见 stdout
+const sshHostOutputPreview = 4096
+
+// 主机成功策略
+const (
+	SSHPolicyAll = "all"
+	SSHPolicyAny = "any"
+)
+
+// SSHConfig SSH任务配置结构
+type SSHConfig struct {
+	Hosts       []string `json:"hosts"`                 // 目标主机，可带端口
+	Port        int      `json:"port,omitempty"`        // 默认端口，为0时使用22
+	User        string   `json:"user"`                  // 登录用户
+	Password    string   `json:"password,omitempty"`    // 密码，支持密钥引用
+	Key         string   `json:"key,omitempty"`         // PEM私钥的引用（file:/env:/secret:）
+	Passphrase  string   `json:"passphrase,omitempty"`  // 私钥口令，支持密钥引用
+	KnownHosts  string   `json:"known_hosts,omitempty"` // known_hosts 文件，为空时使用全局配置
+	Command     string   `json:"command"`               // 远端执行的命令
+	Concurrency int      `json:"concurrency,omitempty"` // 同时连接的主机数，为0时使用全局配置
+	Timeout     int      `json:"timeout"`               // 单台主机超时（秒）
+	Policy      string   `json:"policy,omitempty"`      // all/any，默认 all
+}
+
+// SSHHostResult 单台主机的执行结果（写入聚合日志）
+type SSHHostResult struct {
+	Host       string `json:"host"`
+	Success    bool   `json:"success"`
+	ExitCode   int    `json:"exit_code"` // 未拿到退出码时为 -1
+	Stdout     string `json:"stdout,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// sshExecResult SSH任务执行结果
+type sshExecResult struct {
+	Success  bool
+	Command  string
+	ExitCode int // 第一台失败主机的退出码，全部成功时为0
+	Summary  string
+	Hosts    []SSHHostResult
+}
+
+// parseSSHConfig 解析SSH任务配置
+func parseSSHConfig(command string) (*SSHConfig, error) {
+	config := parseSSHTags(command)
+	if len(config.Hosts) == 0 {
+		return nil, fmt.Errorf("缺少【hosts】目标主机")
+	}
+	if config.Command == "" {
+		return nil, fmt.Errorf("缺少【command】远端命令")
+	}
+	return config, nil
+}
+
+// parseSSHTags 按标签读取配置并补全默认值，不检查必填项（供配置校验使用）
+func parseSSHTags(command string) *SSHConfig {
+	config := &SSHConfig{
+		Timeout: GetJobsConfigInt("jobs.default_timeout_seconds", 30),
+		Hosts:   make([]string, 0),
+	}
+	for _, line := range strings.Split(command, "\n") {
+		m := jobTagLineRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		tag, value := strings.TrimSpace(m[1]), strings.TrimSpace(m[2])
+		switch tag {
+		case "hosts":
+			config.Hosts = append(config.Hosts, splitSSHHosts(value)...)
+		case "user":
+			config.User = value
+		case "password":
+			config.Password = value
+		case "key":
+			config.Key = value
+		case "passphrase":
+			config.Passphrase = value
+		case "known_hosts":
+			config.KnownHosts = value
+		case "command":
+			config.Command = value
+		case "policy":
+			config.Policy = strings.ToLower(value)
+		case "port", "concurrency", "timeout":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			switch tag {
+			case "port":
+				config.Port = n
+			case "concurrency":
+				config.Concurrency = n
+			case "timeout":
+				config.Timeout = n
+			}
+		}
+	}
+	applySSHConfigDefaults(config)
+	return config
+}
+
+// splitSSHHosts 拆分主机列表，支持逗号与 ||| 分隔
+func splitSSHHosts(value string) []string {
+	var hosts []string
+	for _, part := range strings.Split(strings.ReplaceAll(value, "|||", ","), ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			hosts = append(hosts, part)
+		}
+	}
+	return hosts
+}
+
+// loadSSHConfig 读取SSH任务配置
+func loadSSHConfig(job *Jobs) (*SSHConfig, error) {
+	if strings.TrimSpace(job.Config) == "" {
+		return parseSSHConfig(job.Command)
+	}
+	cfg, err := ParseJobConfig("ssh", job.Config)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.SSH, nil
+}
+
+func applySSHConfigDefaults(c *SSHConfig) {
+	if c.Hosts == nil {
+		c.Hosts = make([]string, 0)
+	}
+	if c.Port == 0 {
+		c.Port = 22
+	}
+	if c.Timeout == 0 {
+		c.Timeout = GetJobsConfigInt("jobs.default_timeout_seconds", 30)
+	}
+	c.Policy = strings.ToLower(strings.TrimSpace(c.Policy))
+	if c.Policy == "" {
+		c.Policy = SSHPolicyAll
+	}
+}
+
+// sshHostAddr 补全主机端口，支持 host、host:port、[ipv6]:port 与不带方括号的 IPv6 地址
+func sshHostAddr(host string, port int) (string, error) {
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		n, err := strconv.Atoi(p)
+		if err != nil || n <= 0 || n > 65535 || h == "" {
+			return "", fmt.Errorf("主机地址不合法: %s", host)
+		}
+		return host, nil
+	}
+	h := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if h == "" || strings.ContainsAny(h, " /@") {
+		return "", fmt.Errorf("主机地址不合法: %s", host)
+	}
+	return net.JoinHostPort(h, strconv.Itoa(port)), nil
+}
+
+// errSSHKeyNotRef 私钥未使用引用（PEM 为多行内容，无法写在配置中）
+var errSSHKeyNotRef = errors.New("私钥需使用 file:/env:/secret: 引用，不能直接写在配置中")
+
+// sshAuthMethods 解析私钥与密码，两者都配置时先尝试私钥
+func sshAuthMethods(c *SSHConfig) ([]ssh.AuthMethod, error) {
+	var methods []ssh.AuthMethod
+	if c.Key != "" {
+		if !IsSecretRef(c.Key) {
+			return nil, errSSHKeyNotRef
+		}
+		pemText, err := ResolveSecret(c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥失败: %v", err)
+		}
+		signer, err := parseSSHKey(pemText, c.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, ssh.PublicKeys(signer))
+	}
+	if c.Password != "" {
+		password, err := ResolveSecret(c.Password)
+		if err != nil {
+			return nil, fmt.Errorf("读取密码失败: %v", err)
+		}
+		// 部分服务端只开放 keyboard-interactive，所有提问都以密码作答
+		answer := func(user, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range answers {
+				answers[i] = password
+			}
+			return answers, nil
+		}
+		methods = append(methods, ssh.Password(password), ssh.KeyboardInteractive(answer))
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("未配置【key】或【password】")
+	}
+	return methods, nil
+}
+
+// parseSSHKey 解析PEM私钥，加密私钥需提供口令
+func parseSSHKey(pemText, passphrase string) (ssh.Signer, error) {
+	if passphrase != "" {
+		phrase, err := ResolveSecret(passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("读取私钥口令失败: %v", err)
+		}
+		signer, err := ssh.ParsePrivateKeyWithPassphrase([]byte(pemText), []byte(phrase))
+		if err != nil {
+			return nil, fmt.Errorf("私钥解析失败: %v", err)
+		}
+		return signer, nil
+	}
+	signer, err := ssh.ParsePrivateKey([]byte(pemText))
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		if errors.As(err, &missing) {
+			return nil, fmt.Errorf("私钥已加密，需配置【passphrase】")
+		}
+		return nil, fmt.Errorf("私钥解析失败: %v", err)
+	}
+	return signer, nil
+}
+
+// sshKnownHostsPath known_hosts 文件路径：任务配置、全局配置、~/.ssh/known_hosts
+func sshKnownHostsPath(c *SSHConfig) string {
+	if c.KnownHosts != "" {
+		return c.KnownHosts
+	}
+	if path := GetConfigString("jobs.ssh_known_hosts"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "known_hosts")
+}
+
+// describeSSHHostKeyError 将主机密钥校验失败转换为可读的原因，附上服务端密钥指纹便于登记
+func describeSSHHostKeyError(err error, fingerprint string) string {
+	var keyErr *knownhosts.KeyError
+	if !errors.As(err, &keyErr) {
+		return ""
+	}
+	if len(keyErr.Want) == 0 {
+		return fmt.Sprintf("主机密钥未登记在 known_hosts 中（%s）", fingerprint)
+	}
+	return fmt.Sprintf("主机密钥与 known_hosts 不一致，可能存在中间人攻击（%s）", fingerprint)
+}
+
+// runSSHHost 在单台主机上执行命令，超时同时覆盖连接、握手与执行
+func runSSHHost(ctx context.Context, c *SSHConfig, addr string, auth []ssh.AuthMethod, hostKey ssh.HostKeyCallback) SSHHostResult {
+	start := time.Now()
+	res := SSHHostResult{Host: addr, ExitCode: -1}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(c.Timeout)*time.Second)
+	defer cancel()
+	fail := func(err error) SSHHostResult {
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			res.Error = fmt.Sprintf("执行超时（%d秒）", c.Timeout)
+		case ctx.Err() != nil:
+			res.Error = "执行被取消"
+		default:
+			res.Error = err.Error()
+		}
+		res.DurationMs = time.Since(start).Milliseconds()
+		return res
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fail(fmt.Errorf("连接失败: %v", err))
+	}
+	// 超时或取消时关闭连接，使握手与会话立即返回
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	defer conn.Close()
+
+	var fingerprint string
+	config := &ssh.ClientConfig{
+		User: c.User,
+		Auth: auth,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			fingerprint = key.Type() + " " + ssh.FingerprintSHA256(key)
+			return hostKey(hostname, remote, key)
+		},
+	}
+	sc, chans, reqs, err := ssh.NewClientConn(conn, addr, config)
+	if err != nil {
+		if msg := describeSSHHostKeyError(err, fingerprint); msg != "" {
+			err = errors.New(msg)
+		}
+		return fail(fmt.Errorf("SSH握手失败: %v", err))
+	}
+	client := ssh.NewClient(sc, chans, reqs)
+	defer client.Close()
+	session, err := client.NewSession()
+	if err != nil {
+		return fail(fmt.Errorf("创建会话失败: %v", err))
+	}
+	defer session.Close()
+
+	var stdout, stderr bytes.Buffer
+	session.Stdout = &stdout
+	session.Stderr = &stderr
+	err = session.Run(c.Command)
+	res.Stdout, res.Stderr = stdout.String(), stderr.String()
+	if ctx.Err() != nil {
+		return fail(ctx.Err())
+	}
+	var exitErr *ssh.ExitError
+	switch {
+	case err == nil:
+		res.ExitCode, res.Success = 0, true
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitStatus()
+		res.Error = fmt.Sprintf("退出码 %d", res.ExitCode)
+		if exitErr.Signal() != "" {
+			res.Error = fmt.Sprintf("被信号 %s 终止", exitErr.Signal())
+		}
+	default:
+		return fail(fmt.Errorf("执行失败: %v", err))
+	}
+	res.DurationMs = time.Since(start).Milliseconds()
+	return res
+}
+
+// executeSSHJobForSummary 执行SSH任务
+func executeSSHJobForSummary(ctx context.Context, job *Jobs) (*sshExecResult, error) {
+	config, err := loadSSHConfig(job)
+	if err != nil {
+		return &sshExecResult{}, fmt.Errorf("解析SSH配置失败: %v", err)
+	}
+	res, err := executeSSHConfig(ctx, config)
+	// 聚合日志中只保留每台主机输出的开头，完整输出在 stdout 中（过大时转为产物）
+	for i := range res.Hosts {
+		res.Hosts[i].Stdout = previewText(res.Hosts[i].Stdout, sshHostOutputPreview)
+		res.Hosts[i].Stderr = previewText(res.Hosts[i].Stderr, sshHostOutputPreview)
+	}
+	return res, err
+}
+
+// executeSSHConfig 按并发数在各主机上执行命令，按策略汇总结果
+func executeSSHConfig(ctx context.Context, c *SSHConfig) (*sshExecResult, error) {
+	res := &sshExecResult{Command: c.Command}
+	addrs := make([]string, 0, len(c.Hosts))
+	for _, h := range c.Hosts {
+		addr, err := sshHostAddr(h, c.Port)
+		if err != nil {
+			return res, err
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return res, fmt.Errorf("缺少【hosts】目标主机")
+	}
+	auth, err := sshAuthMethods(c)
+	if err != nil {
+		return res, err
+	}
+	path := sshKnownHostsPath(c)
+	if path == "" {
+		return res, fmt.Errorf("无法确定 known_hosts 文件，请配置【known_hosts】")
+	}
+	hostKey, err := knownhosts.New(path)
+	if err != nil {
+		return res, fmt.Errorf("读取 known_hosts 失败: %v", err)
+	}
+
+	concurrency := c.Concurrency
+	if concurrency <= 0 {
+		concurrency = GetJobsConfigInt("jobs.ssh_concurrency", 5)
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	res.Hosts = make([]SSHHostResult, len(addrs))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				res.Hosts[i] = SSHHostResult{Host: addr, ExitCode: -1, Error: "执行被取消"}
+				return
+			}
+			defer func() { <-sem }()
+			res.Hosts[i] = runSSHHost(ctx, c, addr, auth, hostKey)
+		}()
+	}
+	wg.Wait()
+
+	var b strings.Builder
+	var failed []string
+	for i := range res.Hosts {
+		h := res.Hosts[i]
+		b.WriteString(fmt.Sprintf("\n=== %s 退出码: %d 耗时: %dms ===\n", h.Host, h.ExitCode, h.DurationMs))
+		b.WriteString(h.Stdout)
+		if h.Stderr != "" {
+			b.WriteString("\n[stderr]\n" + h.Stderr)
+		}
+		if h.Error != "" {
+			b.WriteString("\n[错误] " + h.Error)
+		}
+		if !h.Success {
+			if len(failed) == 0 {
+				res.ExitCode = h.ExitCode
+			}
+			failed = append(failed, fmt.Sprintf("%s: %s", h.Host, h.Error))
+		}
+	}
+	res.Summary = strings.TrimPrefix(b.String(), "\n")
+
+	succeeded := len(addrs) - len(failed)
+	if c.Policy == SSHPolicyAny {
+		res.Success = succeeded > 0
+	} else {
+		res.Success = len(failed) == 0
+	}
+	if !res.Success {
+		return res, fmt.Errorf("%d/%d 台主机失败（策略 %s）: %s", len(failed), len(addrs), c.Policy, strings.Join(failed, "；"))
+	}
+	return res, nil
+}
+
+// validateSSHConfig 校验SSH任务的必填项与组合（单个标签的取值已单独校验）
+func validateSSHConfig(report *JobConfigReport, c *SSHConfig) {
+	if len(c.Hosts) == 0 {
+		report.errorf(0, "hosts", "缺少目标主机")
+	}
+	if strings.TrimSpace(c.User) == "" {
+		report.errorf(0, "user", "缺少登录用户")
+	}
+	if strings.TrimSpace(c.Command) == "" {
+		report.errorf(0, "command", "缺少远端命令")
+	}
+	if c.Key == "" && c.Password == "" {
+		report.errorf(0, "key", "需配置【key】私钥或【password】密码")
+	}
+	if c.Passphrase != "" && c.Key == "" {
+		report.warnf(0, "passphrase", "未配置 key，passphrase 不生效")
+	}
+	if c.Concurrency > len(c.Hosts) && len(c.Hosts) > 0 {
+		report.warnf(0, "concurrency", "并发数 %d 大于主机数 %d", c.Concurrency, len(c.Hosts))
+	}
+	if c.KnownHosts == "" {
+		if path := sshKnownHostsPath(c); path == "" {
+			report.warnf(0, "known_hosts", "无法确定默认 known_hosts 文件，请配置【known_hosts】")
+		} else if _, err := os.Stat(path); err != nil {
+			report.warnf(0, "known_hosts", "默认 known_hosts 文件在当前主机不存在: %s", path)
+		}
+	}
+}
+
+// validateSSHTagValue 校验SSH相关标签的取值（port、concurrency 按整数标签校验），不是这些标签时返回 false
+func validateSSHTagValue(report *JobConfigReport, line int, tag, value string) bool {
+	switch tag {
+	case "hosts":
+		hosts := splitSSHHosts(value)
+		if len(hosts) == 0 {
+			report.errorf(line, tag, "主机列表不能为空")
+		}
+		for _, h := range hosts {
+			if _, err := sshHostAddr(h, 22); err != nil {
+				report.errorf(line, tag, "%v", err)
+			}
+		}
+	case "user":
+		if value == "" {
+			report.errorf(line, tag, "登录用户不能为空")
+		}
+	case "password":
+		checkSecretRef(report, line, tag, "密码", value)
+	case "passphrase":
+		checkSecretRef(report, line, tag, "私钥口令", value)
+	case "key":
+		if !IsSecretRef(value) {
+			report.errorf(line, tag, "%v", errSSHKeyNotRef)
+			return true
+		}
+		checkSecretRef(report, line, tag, "私钥", value)
+	case "known_hosts":
+		if value == "" {
+			report.errorf(line, tag, "known_hosts 路径不能为空")
+		} else if info, err := os.Stat(value); err != nil {
+			report.warnf(line, tag, "known_hosts 文件在当前主机不存在: %s", value)
+		} else if info.IsDir() {
+			report.errorf(line, tag, "不是文件: %s", value)
+		}
+	case "policy":
+		if p := strings.ToLower(value); p != SSHPolicyAll && p != SSHPolicyAny {
+			report.errorf(line, tag, "策略应为 all 或 any，当前为 %q", value)
+		}
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,376 @@
+package global
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+const testSSHPassword = "s3cret"
+
+// testSSHServer 本地回环上的最小 SSH 服务，只支持 exec 请求：
+//
+//	echo 文本   输出文本，退出码 0
+//	exit N      退出码 N
+//	sleep 毫秒  等待后退出码 0
+type testSSHServer struct {
+	addr    string
+	hostKey ssh.Signer
+	clients []ssh.PublicKey // 允许登录的客户端公钥
+	gauge   *sessionGauge
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// sessionGauge 正在执行的会话数及其峰值，可由多个服务端共享
+type sessionGauge struct {
+	active, peak atomic.Int64
+}
+
+func (g *sessionGauge) enter() {
+	n := g.active.Add(1)
+	for {
+		p := g.peak.Load()
+		if n <= p || g.peak.CompareAndSwap(p, n) {
+			return
+		}
+	}
+}
+
+func (g *sessionGauge) leave() { g.active.Add(-1) }
+
+// newTestSSHServer 启动测试服务，gauge 为 nil 时单独统计
+func newTestSSHServer(t *testing.T, gauge *sessionGauge, clients ...ssh.PublicKey) *testSSHServer {
+	t.Helper()
+	if gauge == nil {
+		gauge = &sessionGauge{}
+	}
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testSSHServer{addr: ln.Addr().String(), hostKey: signer, clients: clients, gauge: gauge, done: make(chan struct{})}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(_ ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if string(password) == testSSHPassword {
+				return nil, nil
+			}
+			return nil, errors.New("密码错误")
+		},
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			for _, k := range s.clients {
+				if bytes.Equal(k.Marshal(), key.Marshal()) {
+					return nil, nil
+				}
+			}
+			return nil, errors.New("未授权的公钥")
+		},
+	}
+	config.AddHostKey(signer)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serveConn(conn, config)
+			}()
+		}
+	}()
+	t.Cleanup(func() {
+		close(s.done)
+		ln.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *testSSHServer) serveConn(conn net.Conn, config *ssh.ServerConfig) {
+	defer conn.Close()
+	_, chans, reqs, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for nc := range chans {
+		if nc.ChannelType() != "session" {
+			nc.Reject(ssh.UnknownChannelType, "仅支持 session")
+			continue
+		}
+		ch, requests, err := nc.Accept()
+		if err != nil {
+			return
+		}
+		go s.serveSession(ch, requests)
+	}
+}
+
+func (s *testSSHServer) serveSession(ch ssh.Channel, requests <-chan *ssh.Request) {
+	defer ch.Close()
+	for req := range requests {
+		if req.Type != "exec" {
+			req.Reply(false, nil)
+			continue
+		}
+		var payload struct{ Command string }
+		if err := ssh.Unmarshal(req.Payload, &payload); err != nil {
+			req.Reply(false, nil)
+			return
+		}
+		req.Reply(true, nil)
+		code := s.exec(ch, payload.Command)
+		status := make([]byte, 4)
+		binary.BigEndian.PutUint32(status, uint32(code))
+		ch.SendRequest("exit-status", false, status)
+		return
+	}
+}
+
+func (s *testSSHServer) exec(ch ssh.Channel, command string) int {
+	s.gauge.enter()
+	defer s.gauge.leave()
+	name, arg, _ := strings.Cut(command, " ")
+	switch name {
+	case "echo":
+		fmt.Fprintln(ch, arg)
+		return 0
+	case "exit":
+		code, _ := strconv.Atoi(arg)
+		fmt.Fprintf(ch.Stderr(), "exit %d\n", code)
+		return code
+	case "sleep":
+		ms, _ := strconv.Atoi(arg)
+		select {
+		case <-time.After(time.Duration(ms) * time.Millisecond):
+		case <-s.done:
+		}
+		return 0
+	}
+	fmt.Fprintf(ch.Stderr(), "未知命令: %s\n", command)
+	return 127
+}
+
+// writeKnownHosts 写入 known_hosts，登记各地址对应的主机公钥
+func writeKnownHosts(t *testing.T, entries map[string]ssh.PublicKey) string {
+	t.Helper()
+	var b strings.Builder
+	for addr, key := range entries {
+		b.WriteString(knownhosts.Line([]string{knownhosts.Normalize(addr)}, key) + "\n")
+	}
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(path, []byte(b.String()), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// testSSHConfig 信任所有给定服务端的配置
+func testSSHConfig(t *testing.T, command string, servers ...*testSSHServer) *SSHConfig {
+	t.Helper()
+	entries := make(map[string]ssh.PublicKey, len(servers))
+	c := &SSHConfig{User: "deploy", Password: testSSHPassword, Command: command, Timeout: 5}
+	for _, s := range servers {
+		c.Hosts = append(c.Hosts, s.addr)
+		entries[s.addr] = s.hostKey.PublicKey()
+	}
+	c.KnownHosts = writeKnownHosts(t, entries)
+	applySSHConfigDefaults(c)
+	return c
+}
+
+func TestSSHExecuteAllHosts(t *testing.T) {
+	a, b := newTestSSHServer(t, nil), newTestSSHServer(t, nil)
+	res, err := executeSSHConfig(context.Background(), testSSHConfig(t, "echo hello", a, b))
+	if err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if !res.Success || len(res.Hosts) != 2 {
+		t.Fatalf("结果不符: %+v", res)
+	}
+	for i, s := range []*testSSHServer{a, b} {
+		h := res.Hosts[i]
+		if h.Host != s.addr || !h.Success || h.ExitCode != 0 || h.Stdout != "hello\n" {
+			t.Fatalf("主机 %d 结果不符: %+v", i, h)
+		}
+	}
+}
+
+func TestSSHHostKeyMismatch(t *testing.T) {
+	s := newTestSSHServer(t, nil)
+	c := testSSHConfig(t, "echo hello", s)
+
+	// 登记的是另一把密钥
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _ := ssh.NewSignerFromKey(other)
+	c.KnownHosts = writeKnownHosts(t, map[string]ssh.PublicKey{s.addr: otherKey.PublicKey()})
+	res, err := executeSSHConfig(context.Background(), c)
+	if err == nil || res.Success {
+		t.Fatal("主机密钥不一致时应失败")
+	}
+	if h := res.Hosts[0]; !strings.Contains(h.Error, "不一致") || !strings.Contains(h.Error, ssh.FingerprintSHA256(s.hostKey.PublicKey())) {
+		t.Fatalf("错误信息应说明密钥不一致并附上指纹: %s", h.Error)
+	}
+
+	// 未登记的主机同样拒绝
+	c.KnownHosts = writeKnownHosts(t, nil)
+	res, _ = executeSSHConfig(context.Background(), c)
+	if h := res.Hosts[0]; h.Success || !strings.Contains(h.Error, "未登记") {
+		t.Fatalf("未登记的主机应被拒绝: %+v", h)
+	}
+}
+
+func TestSSHPolicy(t *testing.T) {
+	ok := newTestSSHServer(t, nil)
+	unreachable := "127.0.0.1:1" // 无服务监听，连接失败
+	c := testSSHConfig(t, "echo hi", ok)
+	c.Hosts = []string{unreachable, ok.addr}
+
+	// any：一台成功即成功
+	c.Policy = SSHPolicyAny
+	res, err := executeSSHConfig(context.Background(), c)
+	if err != nil || !res.Success {
+		t.Fatalf("any 策略下一台成功应整体成功: %+v, %v", res, err)
+	}
+	if res.Hosts[0].Success || !res.Hosts[1].Success {
+		t.Fatalf("各主机结果不符: %+v", res.Hosts)
+	}
+
+	// all：任一主机失败即失败
+	c.Policy = SSHPolicyAll
+	res, err = executeSSHConfig(context.Background(), c)
+	if err == nil || res.Success {
+		t.Fatal("all 策略下有主机失败应整体失败")
+	}
+	if !strings.Contains(err.Error(), "1/2 台主机失败（策略 all）") {
+		t.Fatalf("错误信息不符: %v", err)
+	}
+
+	// any：全部失败时失败，退出码取第一台失败主机
+	c.Hosts = []string{ok.addr}
+	c.Command = "exit 3"
+	c.Policy = SSHPolicyAny
+	res, err = executeSSHConfig(context.Background(), c)
+	if err == nil || res.Success || res.ExitCode != 3 {
+		t.Fatalf("any 策略下全部失败应整体失败: %+v, %v", res, err)
+	}
+	if h := res.Hosts[0]; h.Error != "退出码 3" || h.Stderr != "exit 3\n" {
+		t.Fatalf("主机结果不符: %+v", h)
+	}
+}
+
+func TestSSHPerHostTimeout(t *testing.T) {
+	a, b := newTestSSHServer(t, nil), newTestSSHServer(t, nil)
+	c := testSSHConfig(t, "sleep 5000", a, b)
+	c.Timeout = 1
+	c.Policy = SSHPolicyAny
+	start := time.Now()
+	res, err := executeSSHConfig(context.Background(), c)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Fatalf("超时后应立即返回，实际耗时 %s", elapsed)
+	}
+	if err == nil || res.Success {
+		t.Fatal("全部超时应失败")
+	}
+	for _, h := range res.Hosts {
+		if h.Error != "执行超时（1秒）" || h.ExitCode != -1 {
+			t.Fatalf("超时结果不符: %+v", h)
+		}
+	}
+
+	// 超时按主机计算：串行执行时第二台仍有完整的超时时间
+	c.Command = "sleep 700"
+	c.Concurrency = 1
+	res, err = executeSSHConfig(context.Background(), c)
+	if err != nil || !res.Success {
+		t.Fatalf("每台主机都在超时内完成，应成功: %+v, %v", res, err)
+	}
+}
+
+func TestSSHConcurrencyCap(t *testing.T) {
+	gauge := &sessionGauge{}
+	servers := make([]*testSSHServer, 5)
+	for i := range servers {
+		servers[i] = newTestSSHServer(t, gauge)
+	}
+	c := testSSHConfig(t, "sleep 200", servers...)
+	c.Concurrency = 2
+	res, err := executeSSHConfig(context.Background(), c)
+	if err != nil || !res.Success {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if p := gauge.peak.Load(); p != 2 {
+		t.Fatalf("同时执行的主机数应为 2，实际 %d", p)
+	}
+
+	// 未配置时使用全局并发数
+	gauge.peak.Store(0)
+	c.Concurrency = 0
+	Viper.Set("jobs.ssh_concurrency", 3)
+	defer Viper.Set("jobs.ssh_concurrency", 5)
+	if _, err := executeSSHConfig(context.Background(), c); err != nil {
+		t.Fatalf("执行失败: %v", err)
+	}
+	if p := gauge.peak.Load(); p != 3 {
+		t.Fatalf("同时执行的主机数应为 3，实际 %d", p)
+	}
+}
+
+func TestSSHKeyAuth(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(t.TempDir(), "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	signer, _ := ssh.NewSignerFromKey(priv)
+	s := newTestSSHServer(t, nil, signer.PublicKey())
+
+	c := testSSHConfig(t, "echo key", s)
+	c.Password = ""
+	c.Key = "file:" + keyPath
+	res, err := executeSSHConfig(context.Background(), c)
+	if err != nil || res.Hosts[0].Stdout != "key\n" {
+		t.Fatalf("私钥登录失败: %+v, %v", res, err)
+	}
+
+	// 私钥必须使用引用
+	c.Key = keyPath
+	if _, err := executeSSHConfig(context.Background(), c); !errors.Is(err, errSSHKeyNotRef) {
+		t.Fatalf("非引用的私钥应被拒绝: %v", err)
+	}
+}
@@ -0,0 +1,390 @@
+package global
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WorkflowConfig 工作流任务配置（mode=workflow，command 为 JSON）
+// 示例：{"vars":{"user":"admin"},"steps":[{"name":"login","type":"http","config":"【url】https://x/login\n【mode】POST","outputs":{"token":"json:$.data.token"}},{"name":"sync","type":"http","config":"【url】https://x/sync\n【headers】Authorization:Bearer {{token}}"}]}
+type WorkflowConfig struct {
+	Vars    map[string]string `json:"vars"`    // 初始共享变量
+	Timeout int               `json:"timeout"` // 整体超时（秒），0 表示不限制
+	Steps   []WorkflowStep    `json:"steps"`   // 按顺序执行的步骤
+}
+
+// WorkflowStep 工作流步骤
+type WorkflowStep struct {
+	Name            string            `json:"name"`              // 步骤名（唯一），用于条件与变量引用
+	Type            string            `json:"type"`              // http/command/script/ssh/function
+	Config          string            `json:"config"`            // 与对应模式相同的【】配置，支持 {{变量}} 替换
+	Timeout         int               `json:"timeout"`           // 步骤超时（秒），0 表示沿用步骤配置自身的超时
+	If              string            `json:"if"`                // 执行条件，默认 success()
+	ContinueOnError bool              `json:"continue_on_error"` // 失败时不影响后续步骤与整体结果
+	Outputs         map[string]string `json:"outputs"`           // 输出提取：变量名 -> json:路径 / regex:表达式 / header:名称 / status / output
+}
+
+// WorkflowStepLog 步骤执行记录（写入聚合日志）
+type WorkflowStepLog struct {
+	Name       string            `json:"name"`
+	Type       string            `json:"type"`
+	Status     string            `json:"status"` // 成功/失败/跳过
+	Code       int               `json:"code,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+	Outputs    map[string]string `json:"outputs,omitempty"`
+	Error      string            `json:"error,omitempty"`
+
+	Assertions []HTTPAssertionResult `json:"assertions,omitempty"` // HTTP步骤的断言结果
+}
+
+// workflowStepResult 单个步骤的原始执行结果
+type workflowStepResult struct {
+	success bool
+	code    int         // HTTP状态码或退出码
+	output  string      // 原始输出：HTTP响应体/标准输出/函数返回值
+	summary string      // 写入日志的步骤摘要
+	header  http.Header // 仅HTTP步骤
+	err     error
+
+	assertions []HTTPAssertionResult // 仅HTTP步骤
+}
+
+var workflowVarPattern = regexp.MustCompile(`\{\{\s*([\w.\-]+)\s*\}\}`)
+
+// parseWorkflowConfig 解析工作流配置
+func parseWorkflowConfig(command string) (*WorkflowConfig, error) {
+	var config WorkflowConfig
+	if err := json.Unmarshal([]byte(strings.TrimSpace(command)), &config); err != nil {
+		return nil, fmt.Errorf("工作流配置不是合法JSON: %v", err)
+	}
+	if len(config.Steps) == 0 {
+		return nil, fmt.Errorf("工作流至少需要一个步骤")
+	}
+	seen := make(map[string]bool)
+	for i := range config.Steps {
+		step := &config.Steps[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step%d", i+1)
+		}
+		if seen[step.Name] {
+			return nil, fmt.Errorf("步骤名重复: %s", step.Name)
+		}
+		seen[step.Name] = true
+		step.Type = strings.ToLower(strings.TrimSpace(step.Type))
+		switch step.Type {
+		case "http", "command", "script", "ssh":
+		case "function", "func":
+			step.Type = "function"
+		default:
+			return nil, fmt.Errorf("步骤 %s 的类型不支持: %s", step.Name, step.Type)
+		}
+		if strings.TrimSpace(step.Config) == "" {
+			return nil, fmt.Errorf("步骤 %s 的配置不能为空", step.Name)
+		}
+	}
+	return &config, nil
+}
+
+// renderWorkflowVars 替换 {{变量}}，未定义的变量保持原样
+func renderWorkflowVars(text string, vars map[string]string) string {
+	return workflowVarPattern.ReplaceAllStringFunc(text, func(m string) string {
+		name := workflowVarPattern.FindStringSubmatch(m)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// evalWorkflowCondition 计算步骤执行条件
+// 支持：always()/success()/failure()、steps.名称.success/failure/skipped、A == B、A != B，以及前缀 ! 取反
+func evalWorkflowCondition(expr string, failed bool, vars map[string]string) (bool, error) {
+	expr = strings.TrimSpace(renderWorkflowVars(expr, vars))
+	if expr == "" {
+		return !failed, nil
+	}
+	if strings.HasPrefix(expr, "!") && !strings.HasPrefix(expr, "!=") {
+		v, err := evalWorkflowCondition(expr[1:], failed, vars)
+		return !v, err
+	}
+	for _, op := range []string{"==", "!="} {
+		if idx := strings.Index(expr, op); idx > 0 {
+			left := strings.Trim(strings.TrimSpace(expr[:idx]), `"'`)
+			right := strings.Trim(strings.TrimSpace(expr[idx+len(op):]), `"'`)
+			if op == "==" {
+				return left == right, nil
+			}
+			return left != right, nil
+		}
+	}
+	switch strings.TrimSuffix(expr, "()") {
+	case "always":
+		return true, nil
+	case "success":
+		return !failed, nil
+	case "failure":
+		return failed, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if strings.HasPrefix(expr, "steps.") {
+		parts := strings.Split(expr, ".")
+		if len(parts) == 3 {
+			status, ok := vars["steps."+parts[1]+".status"]
+			if !ok {
+				return false, fmt.Errorf("条件引用了未执行的步骤: %s", parts[1])
+			}
+			switch parts[2] {
+			case "success":
+				return status == "success", nil
+			case "failure", "failed":
+				return status == "failure", nil
+			case "skipped":
+				return status == "skipped", nil
+			}
+		}
+	}
+	return false, fmt.Errorf("无法识别的条件: %s", expr)
+}
+
+// extractWorkflowOutput 按表达式从步骤结果中提取输出
+func extractWorkflowOutput(expr string, res *workflowStepResult) (string, error) {
+	return extractResponseValue(expr, res.output, res.header, res.code)
+}
+
+// runStepWithRetry 执行命令/脚本步骤，遇到可重试退出码时按【retry_codes】规则重试，notes 为重试说明
+func runStepWithRetry(ctx context.Context, policy ExitPolicy, interval int, run func() (bool, string, int, string, string, error)) (ok bool, command string, code int, stdout string, stderr string, notes string, err error) {
+	for retry := 0; ; retry++ {
+		ok, command, code, stdout, stderr, err = run()
+		again, note := policy.waitRetry(ctx, retry, interval, code, stderr, err)
+		notes += note
+		if !again {
+			return ok, command, code, stdout, stderr, notes, err
+		}
+	}
+}
+
+// runWorkflowStep 执行单个步骤
+func runWorkflowStep(ctx context.Context, job *Jobs, step WorkflowStep, config string, sink *artifactSink) *workflowStepResult {
+	res := &workflowStepResult{}
+	switch step.Type {
+	case "http":
+		cfg, err := parseHTTPConfig(config)
+		if err != nil {
+			res.err = fmt.Errorf("解析HTTP配置失败: %v", err)
+			return res
+		}
+		if step.Timeout > 0 {
+			cfg.Timeout = step.Timeout
+		}
+		cfg.CookieJar = cookieJarName(cfg.CookieJar, job.ID)
+		hr, err := executeHTTPConfig(ctx, cfg, sink)
+		res.success, res.code, res.output, res.summary, res.header, res.err = hr.Success, hr.StatusCode, hr.Body, hr.Summary, hr.Header, err
+		res.assertions = hr.Assertions
+		if !res.success && res.err == nil {
+			res.err = failedAssertionsError(hr.Assertions)
+		}
+		if !res.success && res.err == nil {
+			res.err = fmt.Errorf("HTTP请求未通过成功判断（状态码 %d）", hr.StatusCode)
+		}
+	case "command":
+		stepJob := &Jobs{ID: job.ID, Name: job.Name, Mode: "command", Command: config}
+		var policy ExitPolicy
+		var interval int
+		if cfg, perr := parseCommandConfig(config); perr == nil {
+			policy, interval = cfg.ExitPolicy, cfg.Interval
+		}
+		ok, command, code, stdout, stderr, notes, err := runStepWithRetry(ctx, policy, interval, func() (bool, string, int, string, string, error) {
+			return executeCommandJobV2(ctx, stepJob, true, sink)
+		})
+		res.success, res.code, res.output, res.err = ok, code, stdout, err
+		res.summary = fmt.Sprintf("命令: %s\n退出码: %d\n%s%s", command, code, notes, stdout)
+		if stderr != "" {
+			res.summary += "\n[stderr]\n" + stderr
+		}
+		if !ok && err == nil {
+			res.err = fmt.Errorf("命令退出码 %d", code)
+		}
+	case "script":
+		stepJob := &Jobs{ID: job.ID, Name: job.Name, Mode: "script", Command: config}
+		var policy ExitPolicy
+		var interval int
+		if cfg, perr := parseScriptConfig(config); perr == nil {
+			policy, interval = cfg.ExitPolicy, cfg.Interval
+		}
+		ok, command, code, stdout, stderr, notes, err := runStepWithRetry(ctx, policy, interval, func() (bool, string, int, string, string, error) {
+			return executeScriptJob(ctx, stepJob, sink)
+		})
+		res.success, res.code, res.output, res.err = ok, code, stdout, err
+		res.summary = fmt.Sprintf("脚本: %s\n退出码: %d\n%s%s", command, code, notes, stdout)
+		if stderr != "" {
+			res.summary += "\n[stderr]\n" + stderr
+		}
+		if !ok && err == nil {
+			res.err = fmt.Errorf("脚本退出码 %d", code)
+		}
+	case "ssh":
+		cfg, err := parseSSHConfig(config)
+		if err != nil {
+			res.err = fmt.Errorf("解析SSH配置失败: %v", err)
+			return res
+		}
+		if step.Timeout > 0 {
+			cfg.Timeout = step.Timeout
+		}
+		sr, err := executeSSHConfig(ctx, cfg)
+		res.success, res.code, res.summary, res.err = sr.Success, sr.ExitCode, sr.Summary, err
+		// 单台主机时输出即该主机的 stdout，便于提取变量
+		res.output = sr.Summary
+		if len(sr.Hosts) == 1 {
+			res.output = sr.Hosts[0].Stdout
+		}
+	case "function":
+		cfg, err := parseFunctionConfig(config)
+		if err != nil {
+			res.err = fmt.Errorf("解析函数配置失败: %v", err)
+			return res
+		}
+		fn, exists := GetFunction(cfg.Name)
+		if !exists {
+			res.err = fmt.Errorf("未找到函数: %s", cfg.Name)
+			return res
+		}
+		timeout := cfg.Timeout
+		if step.Timeout > 0 {
+			timeout = step.Timeout
+		}
+		fctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			res.output, res.err = fn(cfg.Args)
+		}()
+		select {
+		case <-done:
+			res.success = res.err == nil
+		case <-fctx.Done():
+			return &workflowStepResult{err: fmt.Errorf("函数执行超时（%d秒）", timeout)}
+		}
+		res.summary = fmt.Sprintf("函数: %s 参数: %v\n%s", cfg.Name, cfg.Args, res.output)
+	}
+	return res
+}
+
+// executeWorkflowJobForSummary 执行工作流任务，返回整体结果、步骤摘要与逐步记录
+func executeWorkflowJobForSummary(ctx context.Context, job *Jobs, sink *artifactSink) (success bool, stdout string, steps []WorkflowStepLog, err error) {
+	config, err := parseWorkflowConfig(job.Command)
+	if err != nil {
+		return false, "", nil, fmt.Errorf("解析工作流配置失败: %v", err)
+	}
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(config.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	vars := make(map[string]string, len(config.Vars))
+	for k, v := range config.Vars {
+		vars[k] = v
+	}
+	vars["job.id"] = strconv.Itoa(int(job.ID))
+	vars["job.name"] = job.Name
+	// 运行期产生的变量，替换到后续步骤配置时按上下文转义
+	runtimeVars := make(map[string]bool)
+
+	var b strings.Builder
+	failed := false
+	var firstErr error
+	for i, step := range config.Steps {
+		b.WriteString(fmt.Sprintf("\n=== 步骤 %d/%d: %s (%s) ===\n", i+1, len(config.Steps), step.Name, step.Type))
+		entry := WorkflowStepLog{Name: step.Name, Type: step.Type}
+
+		run, cerr := evalWorkflowCondition(step.If, failed, vars)
+		if cerr != nil {
+			run = false
+			entry.Error = cerr.Error()
+		}
+		if ctx.Err() != nil {
+			run = false
+			entry.Error = fmt.Sprintf("工作流已超时或被取消: %v", ctx.Err())
+		}
+		if !run {
+			entry.Status = "跳过"
+			vars["steps."+step.Name+".status"] = "skipped"
+			b.WriteString("已跳过")
+			if entry.Error != "" {
+				b.WriteString("：" + entry.Error)
+			}
+			b.WriteString("\n")
+			steps = append(steps, entry)
+			continue
+		}
+
+		stepCtx := ctx
+		var cancel context.CancelFunc = func() {}
+		if step.Timeout > 0 {
+			stepCtx, cancel = context.WithTimeout(ctx, time.Duration(step.Timeout)*time.Second)
+		}
+		start := time.Now()
+		res := runWorkflowStep(stepCtx, job, step, renderWorkflowStepConfig(step.Type, step.Config, vars, runtimeVars), sink)
+		cancel()
+		entry.DurationMs = time.Since(start).Milliseconds()
+		entry.Code = res.code
+		entry.Assertions = res.assertions
+		b.WriteString(res.summary)
+		b.WriteString("\n")
+
+		vars["steps."+step.Name+".code"] = strconv.Itoa(res.code)
+		vars["steps."+step.Name+".output"] = res.output
+		runtimeVars["steps."+step.Name+".output"] = true
+		if res.success {
+			entry.Status = "成功"
+			vars["steps."+step.Name+".status"] = "success"
+		} else {
+			entry.Status = "失败"
+			vars["steps."+step.Name+".status"] = "failure"
+			if res.err != nil {
+				entry.Error = res.err.Error()
+			}
+			if !step.ContinueOnError {
+				failed = true
+				if firstErr == nil {
+					firstErr = fmt.Errorf("步骤 %s 失败: %s", step.Name, entry.Error)
+				}
+			}
+		}
+
+		// 提取输出到共享变量（仅步骤成功或允许失败继续时）
+		if len(step.Outputs) > 0 && (res.success || step.ContinueOnError) {
+			entry.Outputs = make(map[string]string, len(step.Outputs))
+			for name, expr := range step.Outputs {
+				v, xerr := extractWorkflowOutput(expr, res)
+				if xerr != nil {
+					b.WriteString(fmt.Sprintf("输出提取失败 %s: %v\n", name, xerr))
+					continue
+				}
+				vars[name] = v
+				vars["steps."+step.Name+".outputs."+name] = v
+				runtimeVars[name] = true
+				runtimeVars["steps."+step.Name+".outputs."+name] = true
+				entry.Outputs[name] = previewText(v, 200)
+			}
+		}
+		b.WriteString(fmt.Sprintf("步骤结果: %s（%dms）\n", entry.Status, entry.DurationMs))
+		steps = append(steps, entry)
+	}
+
+	if failed {
+		return false, b.String(), steps, firstErr
+	}
+	return true, b.String(), steps, nil
+}
@@ -0,0 +1,210 @@
+package global
+
+import (
+	"encoding/json"
+	"net/url"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// 工作流变量替换
+//
+// 工作流 vars 中定义的变量由任务作者给出，步骤的 code/status 为固定格式，均按原文替换；步骤的 output 与
+// outputs 提取结果可能来自远端响应，按所在步骤类型与标签转义后再替换，避免拼接出额外的命令或标签：
+//
+//	command  bash 引用（'...'，含换行等控制字符时为 $'...'），Windows 下为去掉 " 与 % 的 "..."
+//	script   sh/bash 脚本为 shell 引用，python3/node 脚本为 JSON 字符串字面量（含引号），其他解释器不替换
+//	ssh      【command】中为单引号引用，换行替换为空格
+//	http     【url】中按URL组件编码，【data】为JSON时按JSON字符串内容转义、为表单时按URL编码
+//	function 【arg】中转义 \ " ,
+//
+// 其余标签中去掉换行与 |||，防止拼出新的标签行或额外的键值对。引用后的值已带引号，模板中不要再加引号。
+
+// renderWorkflowStepConfig 替换步骤配置中的 {{变量}}，runtimeVars 中的变量按上下文转义
+func renderWorkflowStepConfig(stepType, config string, vars map[string]string, runtimeVars map[string]bool) string {
+	lines := strings.Split(config, "\n")
+	rc := newWorkflowRenderContext(stepType, config)
+	inScript := false
+	for i, line := range lines {
+		tag := ""
+		if !inScript {
+			if m := jobTagLineRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil {
+				tag = strings.TrimSpace(m[1])
+			}
+		}
+		if stepType == "script" && tag == "script" {
+			inScript = true
+		}
+		escape := rc.escaper(tag, inScript)
+		lines[i] = workflowVarPattern.ReplaceAllStringFunc(line, func(m string) string {
+			name := workflowVarPattern.FindStringSubmatch(m)[1]
+			v, ok := vars[name]
+			if !ok {
+				return m
+			}
+			if !runtimeVars[name] {
+				return v
+			}
+			if escape == nil {
+				return m
+			}
+			return escape(v)
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// workflowRenderContext 步骤配置中决定转义方式的信息
+type workflowRenderContext struct {
+	stepType string
+	bodyType string // http 请求体类型：json/form/raw
+	scriptQ  func(string) string
+}
+
+func newWorkflowRenderContext(stepType, config string) *workflowRenderContext {
+	rc := &workflowRenderContext{stepType: stepType}
+	switch stepType {
+	case "http":
+		var bodyType, data string
+		for _, line := range strings.Split(config, "\n") {
+			m := jobTagLineRe.FindStringSubmatch(strings.TrimSpace(line))
+			if m == nil {
+				continue
+			}
+			switch strings.TrimSpace(m[1]) {
+			case "body_type":
+				bodyType = strings.ToLower(strings.TrimSpace(m[2]))
+			case "data":
+				data = strings.TrimSpace(m[2])
+			}
+		}
+		switch {
+		case bodyType == HTTPBodyJSON, bodyType == "" && (strings.HasPrefix(data, "{") || strings.HasPrefix(data, "[")):
+			rc.bodyType = HTTPBodyJSON
+		case bodyType == HTTPBodyForm:
+			rc.bodyType = HTTPBodyForm
+		default:
+			rc.bodyType = HTTPBodyRaw
+		}
+	case "script":
+		rc.scriptQ = scriptValueQuoter(config)
+	}
+	return rc
+}
+
+// escaper 返回某一行中运行期变量的转义函数，返回 nil 表示不替换
+func (rc *workflowRenderContext) escaper(tag string, inScript bool) func(string) string {
+	switch rc.stepType {
+	case "command":
+		if tag == "" || tag == "command" {
+			if runtime.GOOS == "windows" {
+				return cmdQuote
+			}
+			return bashQuote
+		}
+	case "script":
+		if inScript {
+			return rc.scriptQ
+		}
+	case "ssh":
+		if tag == "command" {
+			return func(v string) string {
+				return shellQuote(strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(v))
+			}
+		}
+	case "http":
+		switch tag {
+		case "url":
+			return urlComponentEscape
+		case "data":
+			switch rc.bodyType {
+			case HTTPBodyJSON:
+				return jsonStringContent
+			case HTTPBodyForm:
+				return urlComponentEscape
+			}
+		}
+	case "function":
+		if tag == "arg" {
+			return funcArgEscape
+		}
+	}
+	return tagValueEscape
+}
+
+// scriptValueQuoter 按脚本解释器选择引用方式，无法安全引用时返回 nil
+func scriptValueQuoter(config string) func(string) string {
+	header, body, _ := splitScriptCommand(config)
+	c := &ScriptConfig{Script: body}
+	for _, line := range strings.Split(header, "\n") {
+		if m := jobTagLineRe.FindStringSubmatch(strings.TrimSpace(line)); m != nil && strings.TrimSpace(m[1]) == "interpreter" {
+			c.Interpreter = strings.TrimSpace(m[2])
+		}
+	}
+	name, args, _, err := scriptCommand(c)
+	if err != nil {
+		return nil
+	}
+	// #!/usr/bin/env python3 之类的 shebang 按实际解释器判断
+	interp := filepath.Base(name)
+	if interp == "env" && len(args) > 0 {
+		interp = filepath.Base(args[len(args)-1])
+	}
+	switch {
+	case interp == "sh", interp == "bash", interp == "dash", interp == "zsh":
+		return shellQuote
+	case strings.HasPrefix(interp, "python"), interp == "node", interp == "nodejs":
+		return jsonStringLiteral
+	}
+	return nil
+}
+
+// shellQuote POSIX shell 单引号引用
+func shellQuote(v string) string {
+	return "'" + strings.ReplaceAll(v, "'", `'\''`) + "'"
+}
+
+// bashQuote bash 引用，含控制字符时使用 $'...' 以保持单行
+func bashQuote(v string) string {
+	if !strings.ContainsAny(v, "\r\n\t") {
+		return shellQuote(v)
+	}
+	r := strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	return "$'" + r.Replace(v) + "'"
+}
+
+// cmdQuote Windows cmd 双引号引用（去掉无法在引号内转义的 " 与 %）
+func cmdQuote(v string) string {
+	r := strings.NewReplacer(`"`, "", "%", "", "\r", " ", "\n", " ")
+	return `"` + r.Replace(v) + `"`
+}
+
+// jsonStringLiteral JSON 字符串字面量（含引号），同时是合法的 Python/JavaScript 字符串
+func jsonStringLiteral(v string) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// jsonStringContent JSON 字符串内容（不含引号），用于模板中 "{{变量}}" 的位置
+func jsonStringContent(v string) string {
+	s := jsonStringLiteral(v)
+	return s[1 : len(s)-1]
+}
+
+// urlComponentEscape 按URL组件编码（空格为 %20）
+func urlComponentEscape(v string) string {
+	return strings.ReplaceAll(url.QueryEscape(v), "+", "%20")
+}
+
+// funcArgEscape 函数参数中转义分隔符与引号
+func funcArgEscape(v string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, ",", `\,`, "\r", " ", "\n", " ")
+	return r.Replace(v)
+}
+
+// tagValueEscape 去掉换行与 |||，避免拼出新的标签行或键值对
+func tagValueEscape(v string) string {
+	r := strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "|||", "")
+	return r.Replace(v)
+}
@@ -73,6 +73,8 @@ func main() {
 		}
 	case "daemon":
 		core.DaemonLoop()
+	case "import-cron":
+		os.Exit(core.RunImportLegacy(args[1:]))
 	case "help", "-h", "--help":
 		printHelp()
 	default:
@@ -92,6 +94,7 @@ func printHelp() {
 	fmt.Println("  stop -f         - 停止守护模式(后台进程和守护进程都退出)")
 	fmt.Println("  status          - 查看系统运行状态")
 	fmt.Println("  daemon          - 进入守护模式")
+	fmt.Println("  import-cron     - 导入 crontab / systemd timer（import-cron -h 查看参数）")
 	fmt.Println("  help            - 显示帮助信息")
 	fmt.Println("")
 	fmt.Println("示例:")
@@ -102,4 +105,5 @@ func printHelp() {
 	fmt.Println("  ./jobs stop -f            # 停止所有相关进程")
 	fmt.Println("  ./jobs status             # 查看运行状态")
 	fmt.Println("  ./jobs daemon             # 进入守护模式")
+	fmt.Println("  ./jobs import-cron -dry-run /etc/crontab /etc/cron.d   # 预览导入系统crontab")
 }
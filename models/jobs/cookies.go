@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"time"
+)
+
+// CookieJarEntry HTTP任务持久化Cookie（Cookie罐）
+// 同一罐内以 域名+路径+名称 唯一；Expires 为空表示会话Cookie，跨执行保留直到被服务端删除或清空
+type CookieJarEntry struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement:true" json:"id"`
+	Jar       string     `gorm:"size:100;not null;uniqueIndex:idx_cookie_key;comment:Cookie罐名称" json:"jar"` // 共享罐为自定义名称，任务独立罐为 job:任务ID
+	Domain    string     `gorm:"size:200;not null;uniqueIndex:idx_cookie_key;comment:域名" json:"domain"`
+	Path      string     `gorm:"size:200;not null;uniqueIndex:idx_cookie_key;comment:路径" json:"path"`
+	Name      string     `gorm:"size:200;not null;uniqueIndex:idx_cookie_key;comment:名称" json:"name"`
+	Value     string     `gorm:"type:text;comment:值" json:"value"`
+	HostOnly  bool       `gorm:"default:false;comment:仅限设置的主机" json:"host_only"`
+	Secure    bool       `gorm:"default:false;comment:仅HTTPS发送" json:"secure"`
+	HttpOnly  bool       `gorm:"default:false;comment:HttpOnly" json:"http_only"`
+	Expires   *time.Time `gorm:"index;comment:到期时间" json:"expires"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (CookieJarEntry) TableName() string {
+	return "xiaohus_cookie_jars"
+}
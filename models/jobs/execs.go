@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"time"
+)
+
+// JobExec 任务执行记录（执行登记表）
+// 每次执行开始时写入 running，结束时更新为 success/warning/failed；进程异常退出遗留的 running 记录在启动时标记为 interrupted
+type JobExec struct {
+	ID         uint       `gorm:"primaryKey;autoIncrement:true" json:"id"`
+	ExecID     string     `gorm:"size:64;not null;uniqueIndex;comment:执行ID" json:"exec_id"`
+	JobID      uint       `gorm:"not null;index;comment:任务ID" json:"job_id"`
+	JobName    string     `gorm:"size:100;comment:任务名称" json:"job_name"`
+	Mode       string     `gorm:"size:20;comment:执行模式" json:"mode"`
+	Source     string     `gorm:"size:20;comment:触发来源" json:"source"`       // cron/manual/probe/requeue
+	Status     string     `gorm:"size:20;index;comment:执行状态" json:"status"` // running/success/warning/failed/interrupted
+	Pid        int        `gorm:"default:0;comment:命令进程ID" json:"pid"`      // 仅命令模式
+	Attempt    int        `gorm:"default:0;comment:当前尝试次数" json:"attempt"`
+	StartedAt  time.Time  `gorm:"comment:开始时间" json:"started_at"`
+	FinishedAt *time.Time `gorm:"comment:结束时间" json:"finished_at"`
+	Requeued   bool       `gorm:"default:false;comment:中断后是否已补跑" json:"requeued"`
+	Outputs    string     `gorm:"type:text;comment:提取的输出" json:"outputs"` // JSON对象，HTTP任务【extract】的结果
+}
+
+// TableName 指定表名
+func (JobExec) TableName() string {
+	return "xiaohus_job_execs"
+}
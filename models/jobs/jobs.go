@@ -8,18 +8,28 @@ import (
 // swagger:model Jobs
 // 示例：{"id":1,"name":"测试任务","desc":"描述","cron_expr":"* * * * * *","mode":"command","command":"echo hello","state":0,"allow_mode":0,"max_run_count":0,"run_count":2,"created_at":"2024-06-25T12:00:00Z","updated_at":"2024-06-25T12:00:00Z"}
 type Jobs struct {
-	ID          uint      `gorm:"primaryKey;autoIncrement:true" json:"id"` // 主键ID
-	Name        string    `gorm:"size:100;not null;comment:任务名称" json:"name"`
-	Desc        string    `gorm:"size:500;comment:任务描述" json:"desc"`
-	CronExpr    string    `gorm:"size:100;not null;comment:cron表达式" json:"cron_expr"`
-	Mode        string    `gorm:"size:20;not null;default:'http';comment:执行模式" json:"mode"` // http/command/func
-	Command     string    `gorm:"type:text;not null;comment:执行命令或URL" json:"command"`
-	State       int       `gorm:"type:tinyint;default:0;comment:任务状态" json:"state"`      // 0等待 1执行中 2停止
-	AllowMode   int       `gorm:"type:tinyint;default:0;comment:执行模式" json:"allow_mode"` // 0默认并行 1串行 2立即执行
-	MaxRunCount uint      `gorm:"default:0;comment:最大执行次数" json:"max_run_count"`         // 0=无限制
-	RunCount    uint      `gorm:"default:0;comment:已执行次数" json:"run_count"`
-	CreatedAt   time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
-	UpdatedAt   time.Time `gorm:"type:timestamp;default:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	ID            uint       `gorm:"primaryKey;autoIncrement:true" json:"id"` // 主键ID
+	Name          string     `gorm:"size:100;not null;comment:任务名称" json:"name"`
+	Desc          string     `gorm:"size:500;comment:任务描述" json:"desc"`
+	Tags          string     `gorm:"size:255;comment:标签" json:"tags"` // 逗号分隔，如 backup,db
+	CronExpr      string     `gorm:"size:100;not null;comment:cron表达式" json:"cron_expr"`
+	Mode          string     `gorm:"size:20;not null;default:'http';comment:执行模式" json:"mode"` // http/command/script/ssh/func/workflow/heartbeat
+	Command       string     `gorm:"type:text;not null;comment:执行命令或URL" json:"command"`
+	Config        string     `gorm:"type:text;comment:结构化执行配置" json:"config"`               // http/command/script/ssh/func 模式的JSON配置，非空时优先于 command；workflow 与 heartbeat 不使用
+	State         int        `gorm:"type:tinyint;default:0;comment:任务状态" json:"state"`      // 0启用 1执行中（旧版本，等同启用） 2停止 3熔断暂停；运行状态见 running
+	AllowMode     int        `gorm:"type:tinyint;default:0;comment:执行模式" json:"allow_mode"` // 0默认并行 1串行 2立即执行
+	MaxRunCount   uint       `gorm:"default:0;comment:最大执行次数" json:"max_run_count"`         // 0=无限制
+	RunCount      uint       `gorm:"default:0;comment:已执行次数" json:"run_count"`
+	Notify        string     `gorm:"type:text;comment:通知订阅规则" json:"notify"` // JSON数组，如 [{"channel":"ops","on":["failure","recovery"]}]
+	Breaker       string     `gorm:"type:text;comment:熔断配置" json:"breaker"`  // JSON，如 {"failures":5,"probe_seconds":600}
+	SuspendReason string     `gorm:"size:500;comment:熔断原因" json:"suspend_reason"`
+	SuspendedAt   *time.Time `gorm:"comment:熔断时间" json:"suspended_at"`
+	PingToken     string     `gorm:"size:64;index;comment:心跳token" json:"ping_token"` // 仅 heartbeat 模式使用
+	LastPingAt    *time.Time `gorm:"comment:最近心跳时间" json:"last_ping_at"`
+	ManagedBy     string     `gorm:"size:255;comment:声明式定义文件" json:"managed_by"` // 非空表示由YAML定义文件管理，API中只读
+	CreatedAt     time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP;comment:创建时间" json:"created_at"`
+	UpdatedAt     time.Time  `gorm:"type:timestamp;default:CURRENT_TIMESTAMP;comment:更新时间" json:"updated_at"`
+	Running       int        `gorm:"-" json:"running"` // 当前正在执行的实例数（来自执行登记，不入库）
 }
 
 // TableName 指定表名
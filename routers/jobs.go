@@ -13,6 +13,7 @@ func InitGlobal(r *gin.Engine) {
 	AdminsInit(r)
 	IndexInit(r)
 	JobsInit(r)
+	PingInit(r)
 }
 
 // JobsInit 注册 jobs 相关路由及中间件
@@ -35,6 +36,12 @@ func JobsInit(r *gin.Engine) {
 		JobsRouters.POST("/runAll", JobsController.JobRunAll)
 		JobsRouters.POST("/restart", JobsController.JobRestart)
 		JobsRouters.GET("/read", JobsController.JobInfo)
+		JobsRouters.GET("/running", JobsController.JobRunning)
+		JobsRouters.POST("/sync", JobsController.JobSync)
+		JobsRouters.GET("/export", JobsController.JobExport)
+		JobsRouters.POST("/import", JobsController.JobImport)
+		JobsRouters.POST("/import/legacy", JobsController.JobImportLegacy)
+		JobsRouters.POST("/validate", JobsController.JobValidate)
 		JobsRouters.POST("/checkJob", JobsController.CalibrateJobList)
 		JobsRouters.GET("/scheduler", JobsController.GetSchedulerTasks)
 		JobsRouters.GET("/functions", JobsController.GetFunctions)
@@ -46,8 +53,24 @@ func JobsInit(r *gin.Engine) {
 		JobsRouters.GET("/jobState", JobsController.JobState)
 		JobsRouters.POST("/logs", JobsController.JobLogs)
 		JobsRouters.GET("/execs", JobsController.GetExecByID)
+		JobsRouters.GET("/outputs", JobsController.JobOutputs)
 		JobsRouters.POST("/logs/clear", JobsController.ClearLogs)
 
+		// 执行产物接口
+		JobsRouters.GET("/artifacts", JobsController.JobArtifacts)
+		JobsRouters.GET("/artifacts/download", JobsController.DownloadArtifact)
+
+		// HTTP任务连接池
+		JobsRouters.GET("/http/pool", JobsController.HTTPPoolStats)
+
+		// Cookie罐接口
+		JobsRouters.GET("/cookies", JobsController.CookieJars)
+		JobsRouters.POST("/cookies/clear", JobsController.ClearCookieJar)
+
+		// 通知接口
+		JobsRouters.GET("/notify/channels", JobsController.NotifyChannels)
+		JobsRouters.POST("/notify/test", JobsController.NotifyTest)
+
 		// IP控制管理接口
 		JobsRouters.GET("/ip-control/status", JobsController.GetIPControlStatus)
 		JobsRouters.POST("/ip-control/whitelist/add", JobsController.AddToWhitelist)
@@ -70,6 +93,16 @@ func JobsInit(r *gin.Engine) {
 	}
 }
 
+// PingInit 注册心跳上报路由
+// 供其他服务器上的外部任务调用，不经过 jobs 分组的 IP 控制，以 token 作为凭证
+func PingInit(r *gin.Engine) {
+	JobsController := JobsApp.Index{}
+	r.POST("/ping/:token", JobsController.Ping)
+	r.GET("/ping/:token", JobsController.Ping)
+	r.POST("/ping/:token/:kind", JobsController.Ping)
+	r.GET("/ping/:token/:kind", JobsController.Ping)
+}
+
 // RegisterJobRoutes 预留任务相关路由注册（如需拆分可实现）
 func RegisterJobRoutes(r *gin.Engine) {
 	// TODO: 注册任务相关路由